@@ -0,0 +1,37 @@
+// Command openapigen rewrites the components.schemas section of the OpenAPI
+// document from the Go types of the model package:
+//
+//	go generate ./internal/model
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/isavita/tictactoe_api/internal/openapi"
+)
+
+func main() {
+	modelDir := flag.String("model", "internal/model", "directory of the model package")
+	specPath := flag.String("spec", ".well-known/openapi.yaml", "OpenAPI document to update")
+	types := flag.String("types", strings.Join(openapi.ModelSchemas, ","), "comma-separated root types to generate")
+	flag.Parse()
+
+	schemas, err := openapi.GenerateSchemas(*modelDir, strings.Split(*types, ",")...)
+	if err != nil {
+		log.Fatal(err)
+	}
+	doc, err := os.ReadFile(*specPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	doc, err = openapi.ReplaceSchemas(doc, schemas)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*specPath, doc, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -1,95 +1,386 @@
 package main
 
 import (
-	"io/ioutil"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
 	"log"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/template"
+	"time"
 
+	tictactoeapi "github.com/isavita/tictactoe_api"
 	"github.com/isavita/tictactoe_api/internal/api"
+	"github.com/isavita/tictactoe_api/internal/config"
 	"github.com/isavita/tictactoe_api/internal/game"
+	"github.com/isavita/tictactoe_api/internal/middleware"
+	"github.com/isavita/tictactoe_api/internal/router"
+	"github.com/isavita/tictactoe_api/internal/session"
 )
 
 func main() {
-	ticTacToeGame := game.NewTicTacToeGame()
-	ticTacToeAPI := api.NewTicTacToeAPI(ticTacToeGame)
+	cfg, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	http.HandleFunc("/v1/tictactoe", ticTacToeAPI.TicTacToeHandler)
+	options, err := authOptions(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	auth, err := middleware.NewAuthenticator(options)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	// Handle ai-plugin.json request for OpenAI Plugins.
-	http.HandleFunc("/.well-known/ai-plugin.json", openAIPluginHandler)
+	// The manifest tells OpenAI to send its bearer token once keys are needed
+	pluginAuth := noPluginAuth
+	if auth != nil {
+		pluginAuth = servicePluginAuth(cfg.OpenAIVerificationToken)
+	}
+	assets, err := newPluginAssets(wellKnownFiles(cfg.WellKnownDir), cfg.PublicURL, pluginAuth)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	// Handle openapi.yaml request.
-	http.HandleFunc("/openapi.yaml", openapiHandler)
+	server := newServer(cfg)
+	server.Handler = middleware.CORS(corsOptions(cfg), newRouter(assets, routerOptions{
+		auth:           auth,
+		limiter:        middleware.NewRateLimiter(rateLimitOptions(cfg)),
+		lenientV1:      cfg.LenientV1,
+		maxComputeTime: cfg.MaxComputeTime,
+		limits:         cfg.Game,
+		games:          cfg.Games,
+		onShutdown:     server.RegisterOnShutdown,
+	}))
 
-	// Handle logo.png request.
-	http.HandleFunc("/logo.png", logoHandler)
+	listener, err := net.Listen("tcp", ":"+cfg.Port)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("listening on %s", listener.Addr())
 
-	// Handle all other requests with a 404 Not Found.
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		log.Printf("404 - %s", r.URL.Path)
-		http.NotFound(w, r)
-	})
+	// The first signal starts the shutdown. Restoring the default handling
+	// then lets a second one kill the server without waiting.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	if err := serve(ctx, server, listener, cfg.ShutdownTimeout); err != nil {
+		log.Fatal(err)
+	}
+}
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
-		log.Printf("default to port %s", port)
+// newServer returns the HTTP server without its handler. The write timeout
+// is cfg.WriteTimeout on top of the compute time of a move, and there is none
+// when the compute time is unlimited, as a slow move would otherwise be cut
+// off. Event streams and WebSockets lift the timeouts for themselves.
+func newServer(cfg config.Config) *http.Server {
+	writeTimeout := cfg.MaxComputeTime + cfg.WriteTimeout
+	if cfg.MaxComputeTime == 0 {
+		writeTimeout = 0
 	}
+	return &http.Server{
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      writeTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+	}
+}
 
-	http.ListenAndServe(":"+port, nil)
+// serve serves on the listener until ctx is done, then shuts the server down
+// gracefully: it stops accepting connections and waits up to shutdownTimeout
+// for the in-flight requests, after which it closes the connections left. It
+// returns nil after a clean shutdown.
+func serve(ctx context.Context, server *http.Server, listener net.Listener, shutdownTimeout time.Duration) error {
+	served := make(chan error, 1)
+	go func() {
+		served <- server.Serve(listener)
+	}()
+
+	select {
+	case err := <-served:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Printf("shutting down, waiting up to %v for in-flight requests", shutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		// Closing the connections also stops the searches still running
+		server.Close()
+		return fmt.Errorf("shutdown: %w", err)
+	}
+	return nil
 }
 
-func openAIPluginHandler(w http.ResponseWriter, r *http.Request) {
-	jsonFile, err := os.Open("./.well-known/ai-plugin.json")
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+// corsOptions configures CORS, which is disabled unless there are allowed
+// origins.
+func corsOptions(cfg config.Config) middleware.CORSOptions {
+	return middleware.CORSOptions{
+		AllowedOrigins: cfg.CORSAllowedOrigins,
+		AllowedMethods: cfg.CORSAllowedMethods,
+		AllowedHeaders: cfg.CORSAllowedHeaders,
+		ExposedHeaders: []string{"Location", "WWW-Authenticate", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After"},
+		MaxAge:         cfg.CORSMaxAge,
 	}
-	defer jsonFile.Close()
+}
 
-	jsonData, err := ioutil.ReadAll(jsonFile)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+// rateLimitOptions configures the rate limit. A rate of 0 disables rate
+// limiting. Clients are told apart by their API key, with its own quota if it
+// has one, or else by their IP, which is read from X-Forwarded-For when the
+// server trusts its proxy.
+func rateLimitOptions(cfg config.Config) middleware.RateLimitOptions {
+	clientIP := middleware.ClientIP
+	if cfg.TrustProxy {
+		clientIP = middleware.ForwardedClientIP
+	}
+	return middleware.RateLimitOptions{
+		Rate:  cfg.RateLimit,
+		Burst: cfg.RateLimitBurst,
+		Key: func(r *http.Request) string {
+			if key := middleware.AuthenticatedKey(r); key != nil {
+				return "key:" + key.Name
+			}
+			return "ip:" + clientIP(r)
+		},
+		Quota: func(r *http.Request) (float64, int, bool) {
+			if key := middleware.AuthenticatedKey(r); key != nil {
+				return key.RateLimit, key.Burst, true
+			}
+			return 0, 0, false
+		},
+		Limited: http.HandlerFunc(api.RateLimited),
 	}
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.Write(jsonData)
+// authOptions reads the API keys from the API keys file and the
+// comma-separated API_KEYS environment variable. Authentication is disabled
+// when there are none.
+func authOptions(cfg config.Config) (middleware.AuthOptions, error) {
+	var keys []middleware.APIKey
+	if cfg.APIKeysFile != "" {
+		fileKeys, err := middleware.LoadAPIKeys(cfg.APIKeysFile)
+		if err != nil {
+			return middleware.AuthOptions{}, err
+		}
+		keys = append(keys, fileKeys...)
+	}
+	keys = append(keys, middleware.ParseAPIKeys(cfg.APIKeys)...)
+	return middleware.AuthOptions{
+		Keys:         keys,
+		Unauthorized: http.HandlerFunc(api.Unauthorized),
+		Forbidden:    http.HandlerFunc(api.Forbidden),
+	}, nil
 }
 
-func logoHandler(w http.ResponseWriter, r *http.Request) {
-	logoFile, err := os.Open("./.well-known/logo.png")
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+// The features of the API that API keys may be limited to.
+const (
+	FEATURE_MOVES  = "moves"
+	FEATURE_RENDER = "render"
+	FEATURE_GAMES  = "games"
+)
+
+// routerOptions configure the middleware of the routes. The zero value
+// serves every route to everyone without limits.
+type routerOptions struct {
+	// auth checks the API keys of the API routes; the plugin files are always
+	// public. A nil auth lets everyone in.
+	auth *middleware.Authenticator
+	// limiter rate limits every route, with AI moves costing by board size
+	// and difficulty. A nil limiter disables rate limiting.
+	limiter *middleware.RateLimiter
+	// lenientV1 decodes the request bodies of v1 as the first version of the
+	// API did, ignoring unknown fields.
+	lenientV1 bool
+	// maxComputeTime stops the AI's search for a move after the duration,
+	// when it is not zero. The AI plays the best move found so far, if any.
+	// GIF replays that take longer are given up.
+	maxComputeTime time.Duration
+	// limits bound the boards and the AI's search of the moves and games.
+	// The zero value keeps game.DefaultLimits.
+	limits game.Limits
+	// games bound the server-held games. Zero values keep the defaults.
+	games session.StoreOptions
+	// onShutdown registers a function to call when the server shuts down, to
+	// end the event streams and WebSockets of the games. It may be nil.
+	onShutdown func(func())
+}
+
+// newRouter routes the endpoints of the server. Paths without a route get a
+// 404 and methods without a route a 405, both as API errors.
+func newRouter(assets *pluginAssets, options routerOptions) *router.Router {
+	rt := api.NewRouter()
+	handle := func(method string, pattern string, feature string, handlerFunc http.HandlerFunc, cost func(*http.Request) int) {
+		var handler http.Handler = handlerFunc
+		if options.lenientV1 && strings.HasPrefix(pattern, "/v1/") {
+			handler = api.Lenient(handler)
+		}
+		handler = options.limiter.Limit(cost, handler)
+		if feature != "" {
+			handler = options.auth.Require(feature, handler)
+		}
+		rt.Handle(method, pattern, handler)
 	}
-	defer logoFile.Close()
 
-	logoData, err := ioutil.ReadAll(logoFile)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+	// search bounds the requests in which the AI searches for a move, or a
+	// replay is drawn, by the compute time. Event streams and WebSockets are
+	// left without a deadline.
+	search := func(handlerFunc http.HandlerFunc) http.HandlerFunc {
+		return middleware.Deadline(options.maxComputeTime, handlerFunc).ServeHTTP
 	}
 
-	w.Header().Set("Content-Type", "image/png")
-	w.Write(logoData)
+	limits := options.limits
+	if limits == (game.Limits{}) {
+		limits = game.DefaultLimits
+	}
+	ticTacToeGame := game.NewTicTacToeGame(limits)
+	ticTacToeAPI := api.NewTicTacToeAPI(ticTacToeGame, limits)
+
+	handle(http.MethodPost, "/v1/tictactoe", FEATURE_MOVES, search(ticTacToeAPI.TicTacToeHandler), ticTacToeAPI.MoveRequestCost)
+	handle(http.MethodPost, "/v2/moves", FEATURE_MOVES, search(ticTacToeAPI.MovesHandlerV2), ticTacToeAPI.MoveRequestCostV2)
+
+	// Handle board images.
+	handle(http.MethodGet, "/v1/render", FEATURE_RENDER, api.RenderHandler, nil)
+	handle(http.MethodPost, "/v1/render", FEATURE_RENDER, api.RenderHandler, nil)
+	handle(http.MethodGet, "/v1/render/replay", FEATURE_RENDER, search(api.ReplayHandler), api.ReplayRequestCost)
+	handle(http.MethodPost, "/v1/render/replay", FEATURE_RENDER, search(api.ReplayHandler), api.ReplayRequestCost)
+
+	// Handle server-held games, played over WebSocket or against the AI.
+	gamesAPI := api.NewGamesAPI(session.NewStore(options.games), limits)
+	if options.onShutdown != nil {
+		options.onShutdown(gamesAPI.Close)
+	}
+	handle(http.MethodPost, "/v1/games", FEATURE_GAMES, search(gamesAPI.GamesHandler), gamesAPI.CreateCost)
+	handle(http.MethodPost, "/v1/games/import", FEATURE_GAMES, search(gamesAPI.ImportHandler), gamesAPI.ImportCost)
+	handle(http.MethodGet, "/v1/games/{id}", FEATURE_GAMES, gamesAPI.GameHandler, nil)
+	handle(http.MethodGet, "/v1/games/{id}/ws", FEATURE_GAMES, gamesAPI.JoinHandler, nil)
+	handle(http.MethodGet, "/v1/games/{id}/events", FEATURE_GAMES, gamesAPI.EventsHandler, nil)
+	handle(http.MethodGet, "/v1/games/{id}/record", FEATURE_GAMES, gamesAPI.RecordHandler, nil)
+	handle(http.MethodGet, "/v1/games/{id}/replay", FEATURE_GAMES, search(gamesAPI.GameReplayHandler), gamesAPI.ReplayCost)
+	handle(http.MethodPost, "/v1/games/{id}/moves", FEATURE_GAMES, search(gamesAPI.MovesHandler), gamesAPI.MoveCost)
+	handle(http.MethodPost, "/v1/games/{id}/undo", FEATURE_GAMES, gamesAPI.UndoHandler, nil)
+
+	// Handle ai-plugin.json request for OpenAI Plugins.
+	handle(http.MethodGet, "/.well-known/ai-plugin.json", "", assets.openAIPluginHandler, nil)
+
+	// Handle openapi.yaml request.
+	handle(http.MethodGet, "/openapi.yaml", "", assets.openapiHandler, nil)
+
+	// Handle logo.png request.
+	handle(http.MethodGet, "/logo.png", "", assets.logoHandler, nil)
+
+	return rt
 }
 
-func openapiHandler(w http.ResponseWriter, r *http.Request) {
-	openapiFile, err := os.Open("./.well-known/openapi.yaml")
+// pluginAuth is the auth section of the plugin manifest.
+type pluginAuth struct {
+	Type               string            `json:"type"`
+	AuthorizationType  string            `json:"authorization_type,omitempty"`
+	VerificationTokens map[string]string `json:"verification_tokens,omitempty"`
+}
+
+var noPluginAuth = pluginAuth{Type: "none"}
+
+// servicePluginAuth is the auth of a server that needs an API key. OpenAI
+// sends the key that was registered with the plugin as a bearer token and
+// hands out the verification token on registration, so it may be missing.
+func servicePluginAuth(verificationToken string) pluginAuth {
+	auth := pluginAuth{Type: "service_http", AuthorizationType: "bearer"}
+	if verificationToken != "" {
+		auth.VerificationTokens = map[string]string{"openai": verificationToken}
+	}
+	return auth
+}
+
+// wellKnownFiles returns the files of the directory, or the embedded files of
+// .well-known when dir is empty.
+func wellKnownFiles(dir string) fs.FS {
+	if dir != "" {
+		return os.DirFS(dir)
+	}
+	files, err := fs.Sub(tictactoeapi.WellKnown, ".well-known")
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		panic(err)
 	}
-	defer openapiFile.Close()
+	return files
+}
+
+// pluginAssets are the files of .well-known with the public URL of the server
+// filled in.
+type pluginAssets struct {
+	aiPlugin []byte
+	openapi  []byte
+	logo     []byte
+}
 
-	openapiData, err := ioutil.ReadAll(openapiFile)
+// newPluginAssets reads ai-plugin.json, openapi.yaml and logo.png from files.
+func newPluginAssets(files fs.FS, publicURL string, auth pluginAuth) (*pluginAssets, error) {
+	// The URL is written into JSON and YAML strings unescaped
+	u, err := url.Parse(publicURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.RawQuery != "" || u.Fragment != "" || strings.ContainsAny(publicURL, "\"'\\ ") {
+		return nil, fmt.Errorf("invalid public URL %q: use an http or https URL such as %s", publicURL, config.DEFAULT_PUBLIC_URL)
+	}
+	authJSON, err := json.MarshalIndent(auth, "    ", "    ")
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		return nil, err
+	}
+	data := struct {
+		PublicURL string
+		Auth      string
+	}{strings.TrimSuffix(publicURL, "/"), string(authJSON)}
+
+	assets := &pluginAssets{}
+	for _, asset := range []struct {
+		name string
+		dst  *[]byte
+	}{
+		{"ai-plugin.json", &assets.aiPlugin},
+		{"openapi.yaml", &assets.openapi},
+	} {
+		tmpl, err := template.ParseFS(files, asset.name)
+		if err != nil {
+			return nil, err
+		}
+		var b bytes.Buffer
+		if err := tmpl.Execute(&b, data); err != nil {
+			return nil, err
+		}
+		*asset.dst = b.Bytes()
 	}
 
+	if assets.logo, err = fs.ReadFile(files, "logo.png"); err != nil {
+		return nil, err
+	}
+	return assets, nil
+}
+
+func (a *pluginAssets) openAIPluginHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(a.aiPlugin)
+}
+
+func (a *pluginAssets) logoHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "image/png")
+	w.Write(a.logo)
+}
+
+func (a *pluginAssets) openapiHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "text/yaml")
-	w.Write(openapiData)
+	w.Write(a.openapi)
 }
@@ -1,36 +1,38 @@
 package main
 
 import (
+	"bufio"
+	"context"
 	"encoding/json"
+	"errors"
+	"image/gif"
+	"image/png"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"reflect"
+	"strconv"
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/isavita/tictactoe_api/internal/api"
+	"github.com/isavita/tictactoe_api/internal/config"
 	"github.com/isavita/tictactoe_api/internal/game"
+	"github.com/isavita/tictactoe_api/internal/middleware"
 	"github.com/isavita/tictactoe_api/internal/model"
+	"github.com/isavita/tictactoe_api/internal/websocket"
 )
 
-func TestMain(m *testing.M) {
-	// path setup to the root directory
-	// we need this because the main file is in `/cmd/server`
-	prevPath, _ := os.Getwd()
-	os.Chdir("../..")
-	defer os.Chdir(prevPath)
-
-	m.Run()
-}
-
 func TestTicTacToeHandler(t *testing.T) {
 	t.Run("first move with empty payload", func(t *testing.T) {
 		// setup the tic-tac-toe api
-		ticTacToeGame := game.NewTicTacToeGame()
-		ticTacToeAPI := api.NewTicTacToeAPI(ticTacToeGame)
+		ticTacToeGame := game.NewTicTacToeGame(game.DefaultLimits)
+		ticTacToeAPI := api.NewTicTacToeAPI(ticTacToeGame, game.DefaultLimits)
 
 		s := httptest.NewServer(http.HandlerFunc(ticTacToeAPI.TicTacToeHandler))
 		url := s.URL + "/v1/tictactoe"
@@ -52,12 +54,20 @@ func TestTicTacToeHandler(t *testing.T) {
 
 		want := model.MoveResponse{
 			Success:      true,
-			Message:      "Player 1 has placed 'X' in position 1. Please note: If the user is playing with 'X', disregard this move. Instead, ask the user where they would like to place their first move, then present the game board reflecting their choice.",
+			Message:      "Player 1 has placed 'X' in position 1.",
 			Board:        []int{1, 0, 0, 0, 0, 0, 0, 0, 0},
 			BoardSize:    3,
 			BoardDisplay: " X | 2 | 3 \n --------- \n 4 | 5 | 6 \n --------- \n 7 | 8 | 9 ",
 			GameStatus:   "ongoing",
+			StatusText:   "O to move.",
 			NextPlayer:   game.OPlayer,
+			Position:     "x../.../... o",
+			AssistantHints: []string{
+				"If the user is playing with 'X', disregard this move. Instead, ask the user where they would like to place their first move, then present the game board reflecting their choice.",
+				"Show boardDisplay to the user in a code block so that the columns line up.",
+				"Empty cells in boardDisplay are numbered from 1 to 9. To play cell n, set board[n-1] to the user's player number (1 for X, 2 for O) and send the whole board.",
+				"Ask the user which numbered cell they want to play their 'O' in.",
+			},
 		}
 
 		if !reflect.DeepEqual(got, want) {
@@ -65,6 +75,248 @@ func TestTicTacToeHandler(t *testing.T) {
 		}
 	})
 
+	t.Run("accepts a position string instead of the board", func(t *testing.T) {
+		s := newTestServer()
+		defer s.Close()
+
+		resp, err := http.Post(s.URL+"/v1/tictactoe", "application/json", strings.NewReader(`{"position": "oo./.x./.xx o"}`))
+		assertNoError(t, err)
+		defer resp.Body.Close()
+		assertStatusCode(t, resp, http.StatusOK)
+
+		got := model.MoveResponse{}
+		assertNoError(t, json.NewDecoder(resp.Body).Decode(&got))
+		if got.Position != "oo./.x./oxx x" || got.GameStatus != model.GameStatusOngoing {
+			t.Errorf("got position %q and status %s", got.Position, got.GameStatus)
+		}
+
+		for _, payload := range []string{
+			`{"position": "oo./.x./.xx x"}`,
+			`{"position": "oo./.x./.xx o", "board": [2, 2, 0, 0, 1, 0, 0, 1, 1]}`,
+			`{"position": "oo./.x./.xx o", "boardSize": 4}`,
+			`{"position": "` + strings.Repeat("/", 60000) + `"}`,
+		} {
+			resp, err := http.Post(s.URL+"/v1/tictactoe", "application/json", strings.NewReader(payload))
+			assertNoError(t, err)
+			defer resp.Body.Close()
+			assertStatusCode(t, resp, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("draws the board in the requested display style", func(t *testing.T) {
+		s := newTestServer()
+		defer s.Close()
+
+		resp, err := http.Post(s.URL+"/v1/tictactoe", "application/json", strings.NewReader(`{"position": "x.../.o../..../.... x", "displayStyle": "compact"}`))
+		assertNoError(t, err)
+		defer resp.Body.Close()
+		assertStatusCode(t, resp, http.StatusOK)
+
+		got := model.MoveResponse{}
+		assertNoError(t, json.NewDecoder(resp.Body).Decode(&got))
+		if strings.Contains(got.BoardDisplay, "\n") || !strings.HasPrefix(got.BoardDisplay, "X ") || strings.Count(got.BoardDisplay, " / ") != 3 {
+			t.Errorf("got boardDisplay %q", got.BoardDisplay)
+		}
+	})
+
+	t.Run("plays the requested side", func(t *testing.T) {
+		s := newTestServer()
+		defer s.Close()
+
+		cases := []struct {
+			name        string
+			payload     string
+			wantBoard   []int
+			wantNext    int
+			wantMessage string
+		}{
+			{"AI opens as X", `{"aiPlayer": 1}`, []int{1, 0, 0, 0, 0, 0, 0, 0, 0}, game.OPlayer, "Player 1 has placed 'X' in position 1."},
+			{"AI opens as O when O moves first", `{"aiPlayer": 2, "firstPlayer": 2}`, []int{2, 0, 0, 0, 0, 0, 0, 0, 0}, game.XPlayer, "Player 2 has placed 'O' in position 1."},
+			{"AI replies as X when O moved first", `{"board": [0, 0, 0, 0, 2, 0, 0, 0, 0], "aiPlayer": 1, "firstPlayer": 2}`, nil, game.OPlayer, ""},
+		}
+
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				resp, err := http.Post(s.URL+"/v1/tictactoe", "application/json", strings.NewReader(tc.payload))
+				assertNoError(t, err)
+				defer resp.Body.Close()
+				assertStatusCode(t, resp, http.StatusOK)
+
+				got := model.MoveResponse{}
+				assertNoError(t, json.NewDecoder(resp.Body).Decode(&got))
+				if (tc.wantBoard != nil && !reflect.DeepEqual(got.Board, tc.wantBoard)) || got.NextPlayer != tc.wantNext || (tc.wantMessage != "" && got.Message != tc.wantMessage) {
+					t.Errorf("got board %v, next player %d and message %q", got.Board, got.NextPlayer, got.Message)
+				}
+				for _, hint := range got.AssistantHints {
+					if strings.Contains(hint, "disregard this move") {
+						t.Errorf("got the first-move hint although aiPlayer was sent")
+					}
+				}
+			})
+		}
+	})
+
+	t.Run("leaves out assistant hints for apps", func(t *testing.T) {
+		s := newTestServer()
+		defer s.Close()
+
+		resp, err := http.Post(s.URL+"/v1/tictactoe", "application/json", strings.NewReader(`{"clientType": "app"}`))
+		assertNoError(t, err)
+		defer resp.Body.Close()
+		assertStatusCode(t, resp, http.StatusOK)
+
+		body, err := io.ReadAll(resp.Body)
+		assertNoError(t, err)
+		if strings.Contains(string(body), "assistantHints") {
+			t.Errorf("got %s", body)
+		}
+	})
+
+	t.Run("answers with a coloured text board for terminals", func(t *testing.T) {
+		s := newTestServer()
+		defer s.Close()
+
+		for _, tc := range []struct {
+			query  string
+			accept string
+		}{
+			{"?format=ansi", ""},
+			{"", "text/plain"},
+		} {
+			req, _ := http.NewRequest(http.MethodPost, s.URL+"/v1/tictactoe"+tc.query, strings.NewReader(`{"position": "xx./.o./... o"}`))
+			req.Header.Set("Accept", tc.accept)
+			resp, err := http.DefaultClient.Do(req)
+			assertNoError(t, err)
+			defer resp.Body.Close()
+			assertStatusCode(t, resp, http.StatusOK)
+
+			body, err := io.ReadAll(resp.Body)
+			assertNoError(t, err)
+			if resp.Header.Get("Content-Type") != "text/plain; charset=utf-8" || !strings.Contains(string(body), "\x1b[7m") || !strings.HasSuffix(string(body), "X to move.\n") {
+				t.Errorf("got %s: %q", resp.Header.Get("Content-Type"), body)
+			}
+		}
+
+		req, _ := http.NewRequest(http.MethodPost, s.URL+"/v1/tictactoe", strings.NewReader(`{}`))
+		req.Header.Set("Accept", "application/json, text/plain, */*")
+		resp, err := http.DefaultClient.Do(req)
+		assertNoError(t, err)
+		defer resp.Body.Close()
+		if resp.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("got %s want JSON when both are accepted", resp.Header.Get("Content-Type"))
+		}
+	})
+
+	t.Run("answers in the requested language", func(t *testing.T) {
+		s := newTestServer()
+		defer s.Close()
+
+		cases := []struct {
+			name           string
+			payload        string
+			acceptLanguage string
+			wantLanguage   string
+			wantMessage    string
+			wantStatus     string
+		}{
+			{"from Accept-Language", `{"position": "xx./.o./... o"}`, "de-AT, en;q=0.5", "de", "Spieler 2 hat 'O' auf Feld 3 gesetzt.", "X ist am Zug."},
+			{"from the locale field", `{"position": "xx./.o./... o", "locale": "bg"}`, "es", "bg", "Играч 2 постави 'O' на позиция 3.", "X е на ход."},
+			{"falls back to English", `{"position": "xx./.o./... o"}`, "fr-FR", "en", "Player 2 has placed 'O' in position 3.", "X to move."},
+		}
+
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				req, _ := http.NewRequest(http.MethodPost, s.URL+"/v1/tictactoe", strings.NewReader(tc.payload))
+				req.Header.Set("Accept-Language", tc.acceptLanguage)
+				resp, err := http.DefaultClient.Do(req)
+				assertNoError(t, err)
+				defer resp.Body.Close()
+				assertStatusCode(t, resp, http.StatusOK)
+
+				got := model.MoveResponse{}
+				assertNoError(t, json.NewDecoder(resp.Body).Decode(&got))
+				if resp.Header.Get("Content-Language") != tc.wantLanguage || got.Message != tc.wantMessage || got.StatusText != tc.wantStatus {
+					t.Errorf("got %s %q %q want %s %q %q", resp.Header.Get("Content-Language"), got.Message, got.StatusText, tc.wantLanguage, tc.wantMessage, tc.wantStatus)
+				}
+			})
+		}
+
+		t.Run("errors", func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPost, s.URL+"/v1/tictactoe", strings.NewReader(`{"boardSize": 7, "locale": "es"}`))
+			req.Header.Set("Accept", "application/json")
+			resp, err := http.DefaultClient.Do(req)
+			assertNoError(t, err)
+			defer resp.Body.Close()
+			assertStatusCode(t, resp, http.StatusBadRequest)
+
+			got := model.ErrorResponse{}
+			assertNoError(t, json.NewDecoder(resp.Body).Decode(&got))
+			if got.Error.Code != "invalid_board_size" || got.Error.Message != "Los valores admitidos de boardSize son 3, 4, 5 y 6." {
+				t.Errorf("got %+v", got.Error)
+			}
+		})
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		s := newTestServer()
+		defer s.Close()
+
+		cases := []struct {
+			name       string
+			method     string
+			payload    string
+			wantStatus int
+			wantCode   string
+			wantField  string
+			wantText   string
+		}{
+			{"unsupported board size", http.MethodPost, `{"boardSize": 7}`, http.StatusBadRequest, "invalid_board_size", "boardSize", api.INVALID_BOARD_SIZE},
+			{"illegal piece count", http.MethodPost, `{"board": [1, 1, 0, 0, 0, 0, 0, 0, 0]}`, http.StatusBadRequest, "illegal_piece_count", "board", api.INVALID_BOARD},
+			{"board not matching the size", http.MethodPost, `{"board": [0, 0, 0], "boardSize": 3}`, http.StatusBadRequest, "invalid_board", "board", api.INVALID_BOARD},
+			{"invalid difficulty", http.MethodPost, `{"difficulty": 4}`, http.StatusBadRequest, "invalid_difficulty", "difficulty", api.INVALID_DIFFICULTY},
+			{"not the AI's turn", http.MethodPost, `{"board": [1, 0, 0, 0, 0, 0, 0, 0, 0], "aiPlayer": 1}`, http.StatusBadRequest, "not_ai_turn", "aiPlayer", api.NOT_AI_TURN},
+			{"invalid AI player", http.MethodPost, `{"aiPlayer": 3}`, http.StatusBadRequest, "invalid_ai_player", "aiPlayer", api.INVALID_AI_PLAYER},
+			{"invalid first player", http.MethodPost, `{"firstPlayer": 3}`, http.StatusBadRequest, "invalid_first_player", "firstPlayer", api.INVALID_FIRST},
+			{"piece count for O moving first", http.MethodPost, `{"board": [1, 0, 0, 0, 0, 0, 0, 0, 0], "firstPlayer": 2}`, http.StatusBadRequest, "illegal_piece_count", "board", api.INVALID_BOARD},
+			{"unknown client type", http.MethodPost, `{"clientType": "robot"}`, http.StatusBadRequest, "invalid_client_type", "clientType", api.INVALID_CLIENT},
+			{"unknown display style", http.MethodPost, `{"displayStyle": "fancy"}`, http.StatusBadRequest, "invalid_display_style", "displayStyle", api.INVALID_DISPLAY},
+			{"malformed body", http.MethodPost, `{`, http.StatusBadRequest, "invalid_request_body", "", "Invalid request body"},
+			{"wrong method", http.MethodGet, ``, http.StatusMethodNotAllowed, "method_not_allowed", "", "Method not allowed."},
+		}
+
+		for _, tc := range cases {
+			t.Run(tc.name+" as json", func(t *testing.T) {
+				req, _ := http.NewRequest(tc.method, s.URL+"/v1/tictactoe", strings.NewReader(tc.payload))
+				req.Header.Set("Accept", "application/json")
+				resp, err := http.DefaultClient.Do(req)
+				assertNoError(t, err)
+				defer resp.Body.Close()
+				assertStatusCode(t, resp, tc.wantStatus)
+
+				got := model.ErrorResponse{}
+				assertNoError(t, json.NewDecoder(resp.Body).Decode(&got))
+				want := model.ErrorDetail{Code: tc.wantCode, Message: tc.wantText, Field: tc.wantField, Docs: api.ERROR_DOCS_URL}
+				if got.Error != want {
+					t.Errorf("got %+v want %+v", got.Error, want)
+				}
+			})
+
+			t.Run(tc.name+" as text", func(t *testing.T) {
+				req, _ := http.NewRequest(tc.method, s.URL+"/v1/tictactoe", strings.NewReader(tc.payload))
+				resp, err := http.DefaultClient.Do(req)
+				assertNoError(t, err)
+				defer resp.Body.Close()
+				assertStatusCode(t, resp, tc.wantStatus)
+
+				body, err := io.ReadAll(resp.Body)
+				assertNoError(t, err)
+				if string(body) != tc.wantText+"\n" {
+					t.Errorf("got %q want %q", body, tc.wantText+"\n")
+				}
+			})
+		}
+	})
+
 	t.Run("handles concurrent requests", func(t *testing.T) {
 		s := newTestServer()
 		url := s.URL + "/v1/tictactoe"
@@ -85,42 +337,1190 @@ func TestTicTacToeHandler(t *testing.T) {
 
 }
 
+func TestRenderHandler(t *testing.T) {
+	s := newTestServer()
+	defer s.Close()
+
+	t.Run("renders a board from the query", func(t *testing.T) {
+		resp, err := http.Get(s.URL + "/v1/render?position=xxx/oo./...&lastMove=3")
+		assertNoError(t, err)
+		defer resp.Body.Close()
+		assertStatusCode(t, resp, http.StatusOK)
+
+		body, err := io.ReadAll(resp.Body)
+		assertNoError(t, err)
+		if resp.Header.Get("Content-Type") != "image/svg+xml" || !strings.Contains(string(body), "winning-line") {
+			t.Errorf("got %s: %s", resp.Header.Get("Content-Type"), body)
+		}
+	})
+
+	t.Run("renders a board from the body", func(t *testing.T) {
+		resp, err := http.Post(s.URL+"/v1/render", "application/json", strings.NewReader(`{"board": [1, 0, 0, 0, 2, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0], "boardSize": 4, "lastMove": 5}`))
+		assertNoError(t, err)
+		defer resp.Body.Close()
+		assertStatusCode(t, resp, http.StatusOK)
+	})
+
+	t.Run("renders a PNG with a theme and cell size", func(t *testing.T) {
+		resp, err := http.Get(s.URL + "/v1/render?position=x../.o./...&format=png&cellSize=20&theme=dark")
+		assertNoError(t, err)
+		defer resp.Body.Close()
+		assertStatusCode(t, resp, http.StatusOK)
+
+		img, err := png.Decode(resp.Body)
+		assertNoError(t, err)
+		if resp.Header.Get("Content-Type") != "image/png" || img.Bounds().Dx() != 64 {
+			t.Errorf("got %s with width %d", resp.Header.Get("Content-Type"), img.Bounds().Dx())
+		}
+	})
+
+	t.Run("picks PNG from the Accept header", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodPost, s.URL+"/v1/render", strings.NewReader(`{"position": "x../.../..."}`))
+		req.Header.Set("Accept", "image/png")
+		resp, err := http.DefaultClient.Do(req)
+		assertNoError(t, err)
+		defer resp.Body.Close()
+		assertStatusCode(t, resp, http.StatusOK)
+		if resp.Header.Get("Content-Type") != "image/png" {
+			t.Errorf("got %s", resp.Header.Get("Content-Type"))
+		}
+	})
+
+	t.Run("rejects invalid boards", func(t *testing.T) {
+		for _, query := range []string{"?boardSize=8", "?board=1,2,3", "?lastMove=10", "?board=a", "?format=gif", "?cellSize=8", "?cellSize=512", "?theme=sepia"} {
+			resp, err := http.Get(s.URL + "/v1/render" + query)
+			assertNoError(t, err)
+			defer resp.Body.Close()
+			assertStatusCode(t, resp, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("move endpoint answers with SVG when asked", func(t *testing.T) {
+		ts := newTestServer()
+		defer ts.Close()
+
+		req, _ := http.NewRequest(http.MethodPost, ts.URL+"/v1/tictactoe", strings.NewReader(`{}`))
+		req.Header.Set("Accept", "image/svg+xml")
+		resp, err := http.DefaultClient.Do(req)
+		assertNoError(t, err)
+		defer resp.Body.Close()
+		assertStatusCode(t, resp, http.StatusOK)
+
+		body, err := io.ReadAll(resp.Body)
+		assertNoError(t, err)
+		if resp.Header.Get("Content-Type") != "image/svg+xml" || !strings.Contains(string(body), `class="last-move"`) {
+			t.Errorf("got %s: %s", resp.Header.Get("Content-Type"), body)
+		}
+	})
+
+	t.Run("replays a move list as a GIF", func(t *testing.T) {
+		replay := newTestServer()
+		defer replay.Close()
+
+		resp, err := http.Get(replay.URL + "/v1/render/replay?moves=1,4,2,5,3&delay=200&cellSize=20")
+		assertNoError(t, err)
+		defer resp.Body.Close()
+		assertStatusCode(t, resp, http.StatusOK)
+
+		animation, err := gif.DecodeAll(resp.Body)
+		assertNoError(t, err)
+		if resp.Header.Get("Content-Type") != "image/gif" || len(animation.Image) != 6 || animation.Delay[0] != 20 {
+			t.Errorf("got %s with %d frames and delay %v", resp.Header.Get("Content-Type"), len(animation.Image), animation.Delay)
+		}
+
+		for _, body := range []string{`{"moves": [1, 1]}`, `{"moves": [1, 4, 2, 5, 3, 6]}`, `{"moves": [17], "boardSize": 4}`, `{"moves": [1], "delay": 10}`, `{"moves": [1], "boardSize": 7}`} {
+			resp, err := http.Post(replay.URL+"/v1/render/replay", "application/json", strings.NewReader(body))
+			assertNoError(t, err)
+			defer resp.Body.Close()
+			assertStatusCode(t, resp, http.StatusBadRequest)
+		}
+
+		resp, err = http.Post(replay.URL+"/v1/render/replay", "application/json", strings.NewReader(`{"moves": [1, 2, 3, 4, 5, 6, 7], "boardSize": 6, "cellSize": 256}`))
+		assertNoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		assertNoError(t, err)
+		assertStatusCode(t, resp, http.StatusBadRequest)
+		if !strings.HasPrefix(string(body), "The replay is too large") {
+			t.Errorf("got body %q for a replay with too many pixels", body)
+		}
+	})
+
+	t.Run("move endpoint checks the format and theme before the move", func(t *testing.T) {
+		ts := newTestServer()
+		defer ts.Close()
+
+		for query, wantBody := range map[string]string{
+			"?format=gif":            "Invalid format: Use \"svg\", \"png\" or \"ansi\".\n",
+			"?format=svg&theme=neon": api.INVALID_THEME + "\n",
+		} {
+			// The 6x6 hard move would take a while to search
+			resp, err := http.Post(ts.URL+"/v1/tictactoe"+query, "application/json", strings.NewReader(`{"boardSize": 6}`))
+			assertNoError(t, err)
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			assertNoError(t, err)
+
+			assertStatusCode(t, resp, http.StatusBadRequest)
+			if string(body) != wantBody {
+				t.Errorf("got body %q for %s want %q", body, query, wantBody)
+			}
+		}
+	})
+
+	t.Run("names the query parameter that is not a number", func(t *testing.T) {
+		ts := newTestServer()
+		defer ts.Close()
+
+		for path, wantField := range map[string]string{
+			"/v1/render?board=0,0,x,0,0,0,0,0,0":     "board",
+			"/v1/render/replay?moves=1,4&delay=fast": "delay",
+		} {
+			req, _ := http.NewRequest(http.MethodGet, ts.URL+path, nil)
+			req.Header.Set("Accept", "application/json")
+			resp, err := http.DefaultClient.Do(req)
+			assertNoError(t, err)
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			assertNoError(t, err)
+
+			assertStatusCode(t, resp, http.StatusBadRequest)
+			if !strings.Contains(string(body), `"code":"invalid_query"`) || !strings.Contains(string(body), `"field":"`+wantField+`"`) {
+				t.Errorf("got body %s for %s", body, path)
+			}
+		}
+	})
+
+	t.Run("move endpoint answers with PNG when asked", func(t *testing.T) {
+		ts := newTestServer()
+		defer ts.Close()
+
+		resp, err := http.Post(ts.URL+"/v1/tictactoe?format=png&theme=high-contrast", "application/json", strings.NewReader(`{}`))
+		assertNoError(t, err)
+		defer resp.Body.Close()
+		assertStatusCode(t, resp, http.StatusOK)
+
+		_, err = png.Decode(resp.Body)
+		assertNoError(t, err)
+		if resp.Header.Get("Content-Type") != "image/png" {
+			t.Errorf("got %s", resp.Header.Get("Content-Type"))
+		}
+	})
+}
+
+func TestMovesHandlerV2(t *testing.T) {
+	s := newTestServer()
+	defer s.Close()
+
+	t.Run("plays the winning move", func(t *testing.T) {
+		payload := `{"board": {"size": 3, "cells": ["x", "x", "", "o", "", "", "", "", "o"]}, "aiPlayer": "x"}`
+		resp, err := http.Post(s.URL+"/v2/moves", "application/json", strings.NewReader(payload))
+		assertNoError(t, err)
+		defer resp.Body.Close()
+		assertStatusCode(t, resp, http.StatusOK)
+
+		got := model.MoveResponseV2{}
+		assertNoError(t, json.NewDecoder(resp.Body).Decode(&got))
+		want := model.MoveResponseV2{
+			Move:        model.MoveV2{Player: "x", Index: 2, Row: 0, Column: 2},
+			Board:       model.BoardV2{Size: 3, Cells: []string{"x", "x", "x", "o", "", "", "", "", "o"}},
+			Position:    "xxx/o../..o -",
+			Status:      model.StatusV2Won,
+			Winner:      "x",
+			WinningLine: []int{0, 1, 2},
+			Message:     "The AI placed X in cell 2.",
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %+v want %+v", got, want)
+		}
+	})
+
+	t.Run("plays O from a position string", func(t *testing.T) {
+		payload := `{"position": "x../.../... o", "aiPlayer": "o", "difficulty": "hard"}`
+		resp, err := http.Post(s.URL+"/v2/moves", "application/json", strings.NewReader(payload))
+		assertNoError(t, err)
+		defer resp.Body.Close()
+		assertStatusCode(t, resp, http.StatusOK)
+
+		got := model.MoveResponseV2{}
+		assertNoError(t, json.NewDecoder(resp.Body).Decode(&got))
+		if got.Move.Player != "o" || got.Status != model.StatusV2Ongoing || got.ToMove != "x" || got.Board.Cells[got.Move.Index] != "o" {
+			t.Errorf("got %+v", got)
+		}
+		if got.Move.Row*3+got.Move.Column != got.Move.Index {
+			t.Errorf("got row %d and column %d for cell %d", got.Move.Row, got.Move.Column, got.Move.Index)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		cases := []struct {
+			name       string
+			method     string
+			payload    string
+			wantStatus int
+			wantCode   string
+			wantField  string
+		}{
+			{"missing AI player", http.MethodPost, `{}`, http.StatusBadRequest, "invalid_ai_player", "aiPlayer"},
+			{"unknown cell", http.MethodPost, `{"board": {"size": 3, "cells": ["x", "", "", "", "", "", "", "", "1"]}, "aiPlayer": "o"}`, http.StatusBadRequest, "invalid_cells", "board.cells"},
+			{"cells not matching the size", http.MethodPost, `{"board": {"size": 4, "cells": ["", "", ""]}, "aiPlayer": "x"}`, http.StatusBadRequest, "invalid_cells", "board.cells"},
+			{"unsupported board size", http.MethodPost, `{"board": {"size": 7}, "aiPlayer": "x"}`, http.StatusBadRequest, "invalid_board_size", "board.size"},
+			{"illegal piece count", http.MethodPost, `{"board": {"size": 3, "cells": ["x", "x", "", "", "", "", "", "", ""]}, "aiPlayer": "o"}`, http.StatusBadRequest, "illegal_piece_count", "board.cells"},
+			{"board and position", http.MethodPost, `{"board": {"size": 3}, "position": ".../.../... x", "aiPlayer": "x"}`, http.StatusBadRequest, "ambiguous_board", "position"},
+			{"not the AI's turn", http.MethodPost, `{"aiPlayer": "o"}`, http.StatusBadRequest, "not_ai_turn", "aiPlayer"},
+			{"game over", http.MethodPost, `{"position": "xxx/oo./...", "aiPlayer": "o"}`, http.StatusConflict, "game_over", ""},
+			{"invalid difficulty", http.MethodPost, `{"aiPlayer": "x", "difficulty": "3"}`, http.StatusBadRequest, "invalid_difficulty", "difficulty"},
+			{"invalid first player", http.MethodPost, `{"aiPlayer": "x", "firstPlayer": "1"}`, http.StatusBadRequest, "invalid_first_player", "firstPlayer"},
+			{"malformed body", http.MethodPost, `{`, http.StatusBadRequest, "invalid_request_body", ""},
+			{"wrong method", http.MethodGet, ``, http.StatusMethodNotAllowed, "method_not_allowed", ""},
+		}
+
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				// v2 answers errors with JSON even when the client does not ask for it
+				req, _ := http.NewRequest(tc.method, s.URL+"/v2/moves", strings.NewReader(tc.payload))
+				resp, err := http.DefaultClient.Do(req)
+				assertNoError(t, err)
+				defer resp.Body.Close()
+				assertStatusCode(t, resp, tc.wantStatus)
+
+				got := model.ErrorResponse{}
+				assertNoError(t, json.NewDecoder(resp.Body).Decode(&got))
+				if resp.Header.Get("Content-Type") != "application/json" || got.Error.Code != tc.wantCode || got.Error.Field != tc.wantField {
+					t.Errorf("got %s %+v want code %s for field %q", resp.Header.Get("Content-Type"), got.Error, tc.wantCode, tc.wantField)
+				}
+			})
+		}
+	})
+}
+
+func TestGamesHandler(t *testing.T) {
+	s := newTestServer()
+	defer s.Close()
+
+	t.Run("rejects unsupported board size", func(t *testing.T) {
+		resp, err := http.Post(s.URL+"/v1/games", "application/json", strings.NewReader(`{"boardSize": 7}`))
+		assertNoError(t, err)
+		defer resp.Body.Close()
+
+		assertStatusCode(t, resp, http.StatusBadRequest)
+	})
+
+	t.Run("unknown game", func(t *testing.T) {
+		resp, err := http.Get(s.URL + "/v1/games/missing")
+		assertNoError(t, err)
+		defer resp.Body.Close()
+
+		assertStatusCode(t, resp, http.StatusNotFound)
+	})
+
+	t.Run("two players play over websocket", func(t *testing.T) {
+		record := createGame(t, s, `{}`)
+		wsURL := "ws" + strings.TrimPrefix(s.URL, "http") + "/v1/games/" + record.ID + "/ws"
+
+		x, _, err := websocket.Dial(wsURL, nil)
+		assertNoError(t, err)
+		defer x.Close()
+		assertGameMessage(t, x, model.MessageTypeJoined, game.XPlayer)
+
+		o, _, err := websocket.Dial(wsURL, nil)
+		assertNoError(t, err)
+		defer o.Close()
+		assertGameMessage(t, o, model.MessageTypeJoined, game.OPlayer)
+		assertGameMessage(t, x, model.MessageTypePlayerJoined, game.OPlayer)
+
+		_, resp, err := websocket.Dial(wsURL, nil)
+		if err == nil || resp.StatusCode != http.StatusConflict {
+			t.Errorf("got %v want third player to be rejected", err)
+		}
+
+		o.WriteJSON(model.GameMessage{Type: model.MessageTypeMove, Position: 1})
+		assertGameMessage(t, o, model.MessageTypeError, 0)
+
+		moves := []struct {
+			conn   *websocket.Conn
+			player int
+			pos    int
+		}{{x, game.XPlayer, 1}, {o, game.OPlayer, 4}, {x, game.XPlayer, 2}, {o, game.OPlayer, 5}, {x, game.XPlayer, 3}}
+		var last model.GameMessage
+		for _, move := range moves {
+			assertNoError(t, move.conn.WriteJSON(model.GameMessage{Type: model.MessageTypeMove, Position: move.pos}))
+			assertGameMessage(t, x, model.MessageTypeMove, move.player)
+			last = assertGameMessage(t, o, model.MessageTypeMove, move.player)
+		}
+
+		if last.Game.GameStatus != model.GameStatusPlayer1Wins || last.Game.NextPlayer != -1 {
+			t.Errorf("got status %s next player %d", last.Game.GameStatus, last.Game.NextPlayer)
+		}
+
+		x.WriteClose(websocket.CloseNormalClosure, "")
+		assertGameMessage(t, o, model.MessageTypePlayerLeft, game.XPlayer)
+	})
+
+	t.Run("keeps the display style of the game", func(t *testing.T) {
+		record := createGame(t, s, `{"mode": "ai", "aiPlayer": 1, "displayStyle": "emoji"}`)
+		if record.DisplayStyle != game.DisplayStyleEmoji || !strings.Contains(record.BoardDisplay, "❌") {
+			t.Errorf("got display style %q and boardDisplay %q", record.DisplayStyle, record.BoardDisplay)
+		}
+
+		resp, err := http.Post(s.URL+"/v1/games", "application/json", strings.NewReader(`{"displayStyle": "fancy"}`))
+		assertNoError(t, err)
+		defer resp.Body.Close()
+		assertStatusCode(t, resp, http.StatusBadRequest)
+	})
+
+	t.Run("replays a stored game as a GIF", func(t *testing.T) {
+		record := createGame(t, s, `{"mode": "ai", "aiPlayer": 1}`)
+
+		resp, err := http.Get(s.URL + "/v1/games/" + record.ID + "/replay?theme=dark")
+		assertNoError(t, err)
+		defer resp.Body.Close()
+		assertStatusCode(t, resp, http.StatusOK)
+
+		animation, err := gif.DecodeAll(resp.Body)
+		assertNoError(t, err)
+		if len(animation.Image) != 2 {
+			t.Errorf("got %d frames want the empty board and the AI's first move", len(animation.Image))
+		}
+	})
+
+	t.Run("plays against the AI and takes a move back", func(t *testing.T) {
+		record := createGame(t, s, `{"mode": "ai", "allowTakebacks": true, "maxTakebacks": 1}`)
+		gameURL := s.URL + "/v1/games/" + record.ID
+
+		resp, err := http.Post(gameURL+"/moves", "application/json", strings.NewReader(`{"position": 5}`))
+		assertNoError(t, err)
+		defer resp.Body.Close()
+		assertStatusCode(t, resp, http.StatusOK)
+		got := model.GameRecord{}
+		assertNoError(t, json.NewDecoder(resp.Body).Decode(&got))
+		if len(got.Moves) != 2 || got.Moves[1].Player != game.OPlayer || got.NextPlayer != game.XPlayer {
+			t.Errorf("got moves %v and next player %d", got.Moves, got.NextPlayer)
+		}
+
+		resp, err = http.Post(gameURL+"/moves", "application/json", strings.NewReader(`{"position": 5}`))
+		assertNoError(t, err)
+		defer resp.Body.Close()
+		assertStatusCode(t, resp, http.StatusBadRequest)
+
+		resp, err = http.Post(gameURL+"/undo", "application/json", nil)
+		assertNoError(t, err)
+		defer resp.Body.Close()
+		assertStatusCode(t, resp, http.StatusOK)
+		got = model.GameRecord{}
+		assertNoError(t, json.NewDecoder(resp.Body).Decode(&got))
+		if len(got.Moves) != 0 || got.Takebacks != 1 {
+			t.Errorf("got moves %v and %d takebacks", got.Moves, got.Takebacks)
+		}
+
+		resp, err = http.Post(gameURL+"/undo", "application/json", nil)
+		assertNoError(t, err)
+		defer resp.Body.Close()
+		assertStatusCode(t, resp, http.StatusConflict)
+	})
+
+	t.Run("exports and imports game records", func(t *testing.T) {
+		record := createGame(t, s, `{"mode": "ai", "difficulty": 1}`)
+
+		resp, err := http.Get(s.URL + "/v1/games/" + record.ID + "/record")
+		assertNoError(t, err)
+		defer resp.Body.Close()
+		assertStatusCode(t, resp, http.StatusOK)
+		body, err := io.ReadAll(resp.Body)
+		assertNoError(t, err)
+		if !strings.Contains(string(body), `[O "AI"]`) || !strings.Contains(string(body), `[Difficulty "1"]`) {
+			t.Errorf("got record %s", body)
+		}
+
+		valid := "[X \"Alice\"]\n[O \"Bob\"]\n[Result \"1-0\"]\n\n1. 1 4 2. 2 5 3. 3 1-0\n"
+		resp, err = http.Post(s.URL+"/v1/games/import", "text/plain", strings.NewReader(valid))
+		assertNoError(t, err)
+		defer resp.Body.Close()
+		assertStatusCode(t, resp, http.StatusCreated)
+		imported := model.GameRecord{}
+		assertNoError(t, json.NewDecoder(resp.Body).Decode(&imported))
+		if imported.Mode != model.GameModeHuman || imported.GameStatus != model.GameStatusPlayer1Wins || len(imported.Moves) != 5 {
+			t.Errorf("got imported game %+v", imported)
+		}
+
+		for _, invalid := range []string{
+			"1. 5 5\n",
+			"[Result \"0-1\"]\n1. 1 4 2. 2 5 3. 3 0-1\n",
+			"1. 1 4 2. 2 5 3. 3 6\n",
+			"[WinLength \"4\"]\n1. 5\n",
+		} {
+			resp, err = http.Post(s.URL+"/v1/games/import", "text/plain", strings.NewReader(invalid))
+			assertNoError(t, err)
+			defer resp.Body.Close()
+			assertStatusCode(t, resp, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("spectators follow the game over server-sent events", func(t *testing.T) {
+		record := createGame(t, s, `{}`)
+		wsURL := "ws" + strings.TrimPrefix(s.URL, "http") + "/v1/games/" + record.ID + "/ws"
+		x, _, err := websocket.Dial(wsURL, nil)
+		assertNoError(t, err)
+		defer x.Close()
+		o, _, err := websocket.Dial(wsURL, nil)
+		assertNoError(t, err)
+		defer o.Close()
+		assertGameMessage(t, x, model.MessageTypeJoined, game.XPlayer)
+		assertGameMessage(t, x, model.MessageTypePlayerJoined, game.OPlayer)
+
+		x.WriteJSON(model.GameMessage{Type: model.MessageTypeMove, Position: 5})
+		assertGameMessage(t, x, model.MessageTypeMove, game.XPlayer)
+
+		resp, err := http.Get(s.URL + "/v1/games/" + record.ID + "/events")
+		assertNoError(t, err)
+		defer resp.Body.Close()
+		assertStatusCode(t, resp, http.StatusOK)
+		events := bufio.NewReader(resp.Body)
+
+		assertSSE(t, events, "1", "move", model.GameStatusOngoing)
+
+		moves := []struct {
+			conn *websocket.Conn
+			pos  int
+		}{{o, 1}, {x, 3}, {o, 2}, {x, 7}}
+		for i, move := range moves {
+			move.conn.WriteJSON(model.GameMessage{Type: model.MessageTypeMove, Position: move.pos})
+			got := assertSSE(t, events, strconv.Itoa(i+2), "move", "")
+			if got.Position != move.pos {
+				t.Errorf("got position %d want %d", got.Position, move.pos)
+			}
+		}
+		assertSSE(t, events, "5", "status", model.GameStatusPlayer1Wins)
+
+		t.Run("replays missed moves after Last-Event-ID", func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, s.URL+"/v1/games/"+record.ID+"/events", nil)
+			req.Header.Set("Last-Event-ID", "3")
+			resp, err := http.DefaultClient.Do(req)
+			assertNoError(t, err)
+			defer resp.Body.Close()
+			events := bufio.NewReader(resp.Body)
+
+			assertSSE(t, events, "4", "move", model.GameStatusOngoing)
+			got := assertSSE(t, events, "5", "move", model.GameStatusPlayer1Wins)
+			if got.BoardDisplay != " O | O | X \n --------- \n 4 | X | 6 \n --------- \n X | 8 | 9 " {
+				t.Errorf("got board display %q", got.BoardDisplay)
+			}
+			assertSSE(t, events, "5", "status", model.GameStatusPlayer1Wins)
+		})
+	})
+
+	t.Run("spectators replay takebacks after Last-Event-ID", func(t *testing.T) {
+		record := createGame(t, s, `{"mode": "ai", "difficulty": 1, "allowTakebacks": true}`)
+		gameURL := s.URL + "/v1/games/" + record.ID
+		post := func(path string, payload string) model.GameRecord {
+			t.Helper()
+			resp, err := http.Post(gameURL+path, "application/json", strings.NewReader(payload))
+			assertNoError(t, err)
+			defer resp.Body.Close()
+			assertStatusCode(t, resp, http.StatusOK)
+			assertNoError(t, json.NewDecoder(resp.Body).Decode(&record))
+			return record
+		}
+		// playEmpty plays the first empty cell, against a random AI.
+		playEmpty := func() {
+			t.Helper()
+			for i, cell := range record.Board {
+				if cell == 0 {
+					post("/moves", `{"position": `+strconv.Itoa(i+1)+`}`)
+					return
+				}
+			}
+		}
+
+		playEmpty()
+		playEmpty()
+		// The spectator has seen events 1 to 4 when it drops
+		post("/undo", "")
+		playEmpty()
+
+		req, _ := http.NewRequest(http.MethodGet, gameURL+"/events", nil)
+		req.Header.Set("Last-Event-ID", "4")
+		resp, err := http.DefaultClient.Do(req)
+		assertNoError(t, err)
+		defer resp.Body.Close()
+		events := bufio.NewReader(resp.Body)
+
+		undo := assertSSE(t, events, "5", "undo", model.GameStatusOngoing)
+		if undo.Move != 2 {
+			t.Errorf("got %d moves after the takeback want 2", undo.Move)
+		}
+		assertSSE(t, events, "6", "move", "")
+		got := assertSSE(t, events, "7", "move", "")
+		if !reflect.DeepEqual(got.Board, record.Board) || got.Move != 4 {
+			t.Errorf("got move %d and board %v want move 4 and %v", got.Move, got.Board, record.Board)
+		}
+	})
+}
+
+func TestRouting(t *testing.T) {
+	s := newTestServer()
+	defer s.Close()
+
+	cases := []struct {
+		name       string
+		method     string
+		path       string
+		accept     string
+		wantStatus int
+		wantAllow  string
+		wantCode   string
+	}{
+		{"unknown path", http.MethodGet, "/unknown", "", http.StatusNotFound, "", "not_found"},
+		{"unknown v2 path", http.MethodGet, "/v2/unknown", "", http.StatusNotFound, "", "not_found"},
+		{"unknown v1 path as json", http.MethodGet, "/v1/unknown", "application/json", http.StatusNotFound, "", "not_found"},
+		{"unknown game resource", http.MethodGet, "/v1/games/abc/unknown", "application/json", http.StatusNotFound, "", "not_found"},
+		{"v2 method not allowed", http.MethodGet, "/v2/moves", "", http.StatusMethodNotAllowed, "OPTIONS, POST", "method_not_allowed"},
+		{"v1 method not allowed as json", http.MethodPut, "/v1/render", "application/json", http.StatusMethodNotAllowed, "GET, HEAD, OPTIONS, POST", "method_not_allowed"},
+		{"import is not a game id", http.MethodGet, "/v1/games/import", "application/json", http.StatusMethodNotAllowed, "OPTIONS, POST", "method_not_allowed"},
+		{"game not found", http.MethodGet, "/v1/games/abc", "application/json", http.StatusNotFound, "", "game_not_found"},
+		{"options", http.MethodOptions, "/v1/tictactoe", "", http.StatusNoContent, "OPTIONS, POST", ""},
+		{"head", http.MethodHead, "/openapi.yaml", "", http.StatusOK, "", ""},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tc.method, s.URL+tc.path, nil)
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			assertNoError(t, err)
+			defer resp.Body.Close()
+			assertStatusCode(t, resp, tc.wantStatus)
+			if resp.Header.Get("Allow") != tc.wantAllow {
+				t.Errorf("got Allow %q want %q", resp.Header.Get("Allow"), tc.wantAllow)
+			}
+
+			if tc.wantCode != "" {
+				got := model.ErrorResponse{}
+				assertNoError(t, json.NewDecoder(resp.Body).Decode(&got))
+				if got.Error.Code != tc.wantCode {
+					t.Errorf("got code %s want %s", got.Error.Code, tc.wantCode)
+				}
+			}
+		})
+	}
+
+	t.Run("unknown v1 path as text", func(t *testing.T) {
+		resp, err := http.Get(s.URL + "/v1/unknown")
+		assertNoError(t, err)
+		defer resp.Body.Close()
+		assertStatusCode(t, resp, http.StatusNotFound)
+		body, err := io.ReadAll(resp.Body)
+		assertNoError(t, err)
+		if string(body) != "Not found.\n" {
+			t.Errorf("got %q want %q", body, "Not found.\n")
+		}
+	})
+}
+
+func TestCORS(t *testing.T) {
+	t.Setenv("CORS_ALLOWED_ORIGINS", "https://app.example.com, http://localhost:3000")
+	t.Setenv("CORS_MAX_AGE", "1h")
+	cfg, err := config.Load(nil)
+	assertNoError(t, err)
+	assets, err := newPluginAssets(wellKnownFiles(""), config.DEFAULT_PUBLIC_URL, noPluginAuth)
+	assertNoError(t, err)
+	s := httptest.NewServer(middleware.CORS(corsOptions(cfg), newRouter(assets, routerOptions{})))
+	defer s.Close()
+
+	for _, path := range []string{"/v1/tictactoe", "/v2/moves", "/.well-known/ai-plugin.json", "/openapi.yaml"} {
+		t.Run("preflight for "+path, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodOptions, s.URL+path, nil)
+			req.Header.Set("Origin", "http://localhost:3000")
+			req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+			req.Header.Set("Access-Control-Request-Headers", "Content-Type")
+			resp, err := http.DefaultClient.Do(req)
+			assertNoError(t, err)
+			defer resp.Body.Close()
+			assertStatusCode(t, resp, http.StatusNoContent)
+			if resp.Header.Get("Access-Control-Allow-Origin") != "http://localhost:3000" || resp.Header.Get("Access-Control-Max-Age") != "3600" {
+				t.Errorf("got headers %v", resp.Header)
+			}
+		})
+	}
+
+	t.Run("move from the front-end", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodPost, s.URL+"/v1/tictactoe", strings.NewReader(`{}`))
+		req.Header.Set("Origin", "https://app.example.com")
+		resp, err := http.DefaultClient.Do(req)
+		assertNoError(t, err)
+		defer resp.Body.Close()
+		assertStatusCode(t, resp, http.StatusOK)
+		if resp.Header.Get("Access-Control-Allow-Origin") != "https://app.example.com" {
+			t.Errorf("got headers %v", resp.Header)
+		}
+	})
+
+	t.Run("WebSocket games from allowed origins only", func(t *testing.T) {
+		record := createGame(t, s, `{}`)
+		wsURL := "ws" + strings.TrimPrefix(s.URL, "http") + "/v1/games/" + record.ID + "/ws"
+
+		player, _, err := websocket.Dial(wsURL, http.Header{"Origin": {"https://app.example.com"}})
+		assertNoError(t, err)
+		defer player.Close()
+		assertGameMessage(t, player, model.MessageTypeJoined, game.XPlayer)
+
+		_, resp, err := websocket.Dial(wsURL, http.Header{"Origin": {"https://evil.example.com"}})
+		if err == nil || resp == nil || resp.StatusCode != http.StatusForbidden {
+			t.Errorf("got error %v and response %v want a 403", err, resp)
+		}
+	})
+
+	t.Run("invalid max age", func(t *testing.T) {
+		t.Setenv("CORS_MAX_AGE", "soon")
+		if _, err := config.Load(nil); err == nil {
+			t.Errorf("got no error for an invalid CORS_MAX_AGE")
+		}
+	})
+}
+
+func TestRateLimit(t *testing.T) {
+	t.Setenv("RATE_LIMIT", "0.001")
+	t.Setenv("RATE_LIMIT_BURST", "12")
+	cfg, err := config.Load(nil)
+	assertNoError(t, err)
+	auth, err := middleware.NewAuthenticator(middleware.AuthOptions{Keys: []middleware.APIKey{
+		{Name: "player", Key: "player-key"},
+		{Name: "busy", Key: "busy-key"},
+		{Name: "idle", Key: "idle-key", Burst: 30},
+		{Name: "artist", Key: "artist-key"},
+	}})
+	assertNoError(t, err)
+	assets, err := newPluginAssets(wellKnownFiles(""), config.DEFAULT_PUBLIC_URL, noPluginAuth)
+	assertNoError(t, err)
+	s := httptest.NewServer(newRouter(assets, routerOptions{auth: auth, limiter: middleware.NewRateLimiter(rateLimitOptions(cfg))}))
+	defer s.Close()
+
+	post := func(path string, body string, apiKey string) *http.Response {
+		req, _ := http.NewRequest(http.MethodPost, s.URL+path, strings.NewReader(body))
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+		resp, err := http.DefaultClient.Do(req)
+		assertNoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	t.Run("moves cost by board size and difficulty", func(t *testing.T) {
+		resp := post("/v2/moves", `{"position": "x5/6/6/6/6/6 o", "aiPlayer": "o", "difficulty": "easy"}`, "player-key")
+		assertStatusCode(t, resp, http.StatusOK)
+		if got := resp.Header.Get("RateLimit-Remaining"); got != "11" {
+			t.Errorf("got RateLimit-Remaining %s after an easy move, want 11", got)
+		}
+
+		resp = post("/v1/tictactoe", `{"position": "xoox/oxxo/xoox/.... x"}`, "player-key")
+		assertStatusCode(t, resp, http.StatusOK)
+		if got := resp.Header.Get("RateLimit-Remaining"); got != "9" {
+			t.Errorf("got RateLimit-Remaining %s after a hard move on 4x4, want 9", got)
+		}
+	})
+
+	t.Run("replays cost by their pixels and imports by the AI move", func(t *testing.T) {
+		resp := post("/v1/render/replay", `{"moves": [5, 1, 9, 3, 2, 8, 7, 4, 6]}`, "artist-key")
+		assertStatusCode(t, resp, http.StatusOK)
+		if got := resp.Header.Get("RateLimit-Remaining"); got != "11" {
+			t.Errorf("got RateLimit-Remaining %s after a 3x3 replay, want 11", got)
+		}
+
+		resp = post("/v1/render/replay", `{"boardSize": 4, "cellSize": 256, "moves": [1, 2, 3, 4, 5]}`, "artist-key")
+		assertStatusCode(t, resp, http.StatusOK)
+		if got := resp.Header.Get("RateLimit-Remaining"); got != "4" {
+			t.Errorf("got RateLimit-Remaining %s after a 4x4 replay at the largest cells, want 4", got)
+		}
+
+		resp = post("/v1/games/import", "[X \"AI\"]\n[BoardSize \"4\"]\n[WinLength \"4\"]\n[Difficulty \"3\"]\n\n1. 1 2 *\n", "artist-key")
+		assertStatusCode(t, resp, http.StatusCreated)
+		if got := resp.Header.Get("RateLimit-Remaining"); got != "2" {
+			t.Errorf("got RateLimit-Remaining %s after importing a 4x4 game on hard, want 2", got)
+		}
+	})
+
+	t.Run("rejects clients without tokens", func(t *testing.T) {
+		for i := 0; i < 12; i++ {
+			post("/v1/games", `{"mode": "human"}`, "busy-key")
+		}
+		resp := post("/v1/games", `{"mode": "human"}`, "busy-key")
+		assertStatusCode(t, resp, http.StatusTooManyRequests)
+		if resp.Header.Get("Retry-After") == "" || resp.Header.Get("RateLimit-Limit") != "12" || resp.Header.Get("RateLimit-Remaining") != "0" {
+			t.Errorf("got headers %v", resp.Header)
+		}
+		if resp.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("got Content-Type %s, want the JSON error envelope", resp.Header.Get("Content-Type"))
+		}
+
+		// Other keys have their own tokens and quotas
+		resp = post("/v1/games", `{"mode": "human"}`, "idle-key")
+		assertStatusCode(t, resp, http.StatusCreated)
+		if resp.Header.Get("RateLimit-Limit") != "30" {
+			t.Errorf("got RateLimit-Limit %s, want the quota of the key", resp.Header.Get("RateLimit-Limit"))
+		}
+	})
+
+	t.Run("invalid configuration", func(t *testing.T) {
+		for _, args := range [][]string{{"-rate-limit", "-1"}, {"-rate-limit-burst", "0"}, {"-trust-proxy=maybe"}} {
+			if _, err := config.Load(args); err == nil {
+				t.Errorf("got no error for %v", args)
+			}
+		}
+		t.Setenv("RATE_LIMIT", "fast")
+		if _, err := config.Load(nil); err == nil {
+			t.Errorf("got no error for an invalid RATE_LIMIT")
+		}
+	})
+}
+
+func TestAuth(t *testing.T) {
+	keysFile := filepath.Join(t.TempDir(), "keys.json")
+	assertNoError(t, os.WriteFile(keysFile, []byte(`[{"name": "renderer", "key": "render-key", "features": ["render"]}]`), 0o600))
+	t.Setenv("API_KEYS", "secret-key")
+	cfg, err := config.Load([]string{"-api-keys-file", keysFile})
+	assertNoError(t, err)
+	options, err := authOptions(cfg)
+	assertNoError(t, err)
+	auth, err := middleware.NewAuthenticator(options)
+	assertNoError(t, err)
+	assets, err := newPluginAssets(wellKnownFiles(""), config.DEFAULT_PUBLIC_URL, servicePluginAuth("abc123"))
+	assertNoError(t, err)
+	s := httptest.NewServer(newRouter(assets, routerOptions{auth: auth}))
+	defer s.Close()
+
+	do := func(method string, path string, headers map[string]string) (*http.Response, string) {
+		req, _ := http.NewRequest(method, s.URL+path, strings.NewReader(`{}`))
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		assertNoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		assertNoError(t, err)
+		return resp, string(body)
+	}
+
+	cases := []struct {
+		name       string
+		method     string
+		path       string
+		headers    map[string]string
+		wantStatus int
+		wantBody   string
+	}{
+		{"no key", http.MethodPost, "/v1/tictactoe", nil, http.StatusUnauthorized, "Missing or invalid API key. Send it in the Authorization header as a bearer token.\n"},
+		{"wrong key", http.MethodPost, "/v1/tictactoe", map[string]string{"Authorization": "Bearer guess"}, http.StatusUnauthorized, ""},
+		{"key without the bearer scheme", http.MethodPost, "/v1/tictactoe", map[string]string{"Authorization": "secret-key"}, http.StatusUnauthorized, ""},
+		{"key from the environment", http.MethodPost, "/v1/tictactoe", map[string]string{"Authorization": "Bearer secret-key"}, http.StatusOK, ""},
+		{"key from the file", http.MethodGet, "/v1/render?position=x../.../...", map[string]string{"Authorization": "bearer render-key"}, http.StatusOK, ""},
+		{"feature the key does not allow", http.MethodPost, "/v2/moves", map[string]string{"Authorization": "Bearer render-key"}, http.StatusForbidden, `"code":"feature_not_allowed"`},
+		{"plugin manifest", http.MethodGet, "/.well-known/ai-plugin.json", nil, http.StatusOK, `"auth": {`},
+		{"unknown path", http.MethodGet, "/v1/unknown", nil, http.StatusNotFound, ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, body := do(tc.method, tc.path, tc.headers)
+			assertStatusCode(t, resp, tc.wantStatus)
+			if !strings.Contains(body, tc.wantBody) {
+				t.Errorf("got body %q want it to contain %q", body, tc.wantBody)
+			}
+			if resp.StatusCode == http.StatusUnauthorized && resp.Header.Get("WWW-Authenticate") == "" {
+				t.Errorf("got no WWW-Authenticate header")
+			}
+		})
+	}
+
+	t.Run("manifest advertises service_http", func(t *testing.T) {
+		_, body := do(http.MethodGet, "/.well-known/ai-plugin.json", nil)
+		manifest := struct {
+			Auth pluginAuth `json:"auth"`
+		}{}
+		assertNoError(t, json.Unmarshal([]byte(body), &manifest))
+		want := pluginAuth{Type: "service_http", AuthorizationType: "bearer", VerificationTokens: map[string]string{"openai": "abc123"}}
+		if !reflect.DeepEqual(manifest.Auth, want) {
+			t.Errorf("got auth %+v want %+v", manifest.Auth, want)
+		}
+	})
+
+	t.Run("invalid keys", func(t *testing.T) {
+		for _, keys := range [][]middleware.APIKey{
+			{{Name: "a", Key: ""}},
+			{{Name: "a", Key: "one"}, {Name: "a", Key: "two"}},
+			{{Name: "a", Key: "one"}, {Name: "b", Key: "one"}},
+		} {
+			if _, err := middleware.NewAuthenticator(middleware.AuthOptions{Keys: keys}); err == nil {
+				t.Errorf("got no error for %+v", keys)
+			}
+		}
+	})
+}
+
+func TestStrictDecoding(t *testing.T) {
+	assets, err := newPluginAssets(wellKnownFiles(""), config.DEFAULT_PUBLIC_URL, noPluginAuth)
+	assertNoError(t, err)
+	strict := httptest.NewServer(newRouter(assets, routerOptions{}))
+	defer strict.Close()
+	lenient := httptest.NewServer(newRouter(assets, routerOptions{lenientV1: true}))
+	defer lenient.Close()
+
+	cases := []struct {
+		name        string
+		server      *httptest.Server
+		path        string
+		contentType string
+		accept      string
+		body        string
+		wantStatus  int
+		wantBody    string
+	}{
+		{"misspelled field", strict, "/v1/tictactoe", "application/json", "", `{"board_size": 4}`, http.StatusBadRequest, "Unknown field \"board_size\". Did you mean \"boardSize\"?\n"},
+		{"misspelled field as JSON", strict, "/v1/tictactoe", "application/json", "application/json", `{"board_size": 4}`, http.StatusBadRequest, `"code":"unknown_field","message":"Unknown field \"board_size\". Did you mean \"boardSize\"?","field":"board_size"`},
+		{"mistyped field", strict, "/v1/tictactoe", "application/json", "application/json", `{"boardSize": "4"}`, http.StatusBadRequest, `"code":"invalid_field_type","message":"Invalid boardSize: got a string, want an integer.","field":"boardSize"`},
+		{"mistyped nested field", strict, "/v2/moves", "application/json", "", `{"board": {"size": 3, "cells": [1]}, "aiPlayer": "o"}`, http.StatusBadRequest, `"field":"board.cells.0"`},
+		{"unknown field in v2", strict, "/v2/moves", "application/json", "", `{"ai_player": "o"}`, http.StatusBadRequest, `"code":"unknown_field","message":"Unknown field \"ai_player\". Did you mean \"aiPlayer\"?"`},
+		{"trailing data", strict, "/v1/tictactoe", "application/json", "", `{} {}`, http.StatusBadRequest, "Invalid request body\n"},
+		{"form content type", strict, "/v1/tictactoe", "application/x-www-form-urlencoded", "", `{}`, http.StatusUnsupportedMediaType, "Unsupported content type: send the request body as application/json.\n"},
+		{"JSON content type with charset", strict, "/v1/tictactoe", "application/json; charset=utf-8", "", `{}`, http.StatusOK, `"success":true`},
+		{"no content type", strict, "/v1/tictactoe", "", "", `{}`, http.StatusOK, `"success":true`},
+		{"too large", strict, "/v1/tictactoe", "application/json", "application/json", `{"position": "` + strings.Repeat(".", 70000) + `"}`, http.StatusRequestEntityTooLarge, `"code":"request_too_large"`},
+		{"too large game record", strict, "/v1/games/import", "text/plain", "", strings.Repeat("[Event \"x\"]\n", 7000), http.StatusRequestEntityTooLarge, "The request body is too large.\n"},
+		{"lenient unknown field", lenient, "/v1/tictactoe", "text/plain", "", `{"board_size": 4}`, http.StatusOK, `"boardSize":3`},
+		{"lenient mistyped field", lenient, "/v1/tictactoe", "application/json", "", `{"boardSize": "4"}`, http.StatusBadRequest, "Invalid request body\n"},
+		{"lenient mode is only for v1", lenient, "/v2/moves", "application/json", "", `{"ai_player": "o"}`, http.StatusBadRequest, `"code":"unknown_field"`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPost, tc.server.URL+tc.path, strings.NewReader(tc.body))
+			if tc.contentType != "" {
+				req.Header.Set("Content-Type", tc.contentType)
+			}
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			assertNoError(t, err)
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			assertNoError(t, err)
+
+			assertStatusCode(t, resp, tc.wantStatus)
+			if !strings.Contains(string(body), tc.wantBody) {
+				t.Errorf("got body %q want it to contain %q", body, tc.wantBody)
+			}
+		})
+	}
+}
+
+func TestComputeTime(t *testing.T) {
+	assets, err := newPluginAssets(wellKnownFiles(""), config.DEFAULT_PUBLIC_URL, noPluginAuth)
+	assertNoError(t, err)
+	s := httptest.NewServer(newRouter(assets, routerOptions{maxComputeTime: time.Nanosecond}))
+	defer s.Close()
+
+	cases := []struct {
+		name       string
+		path       string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"hard move out of time", "/v1/tictactoe", `{"boardSize": 6}`, http.StatusServiceUnavailable, "The AI ran out of time to find a move. Try again, or with a lower difficulty or a smaller board.\n"},
+		{"hard move out of time in v2", "/v2/moves", `{"position": "x5/6/6/6/6/6 o", "aiPlayer": "o"}`, http.StatusServiceUnavailable, `"code":"search_timeout"`},
+		{"easy move needs no search", "/v1/tictactoe", `{"boardSize": 6, "difficulty": 1}`, http.StatusOK, `"success":true`},
+		{"AI opening out of time", "/v1/games", `{"boardSize": 6, "mode": "ai", "aiPlayer": 1}`, http.StatusServiceUnavailable, "The AI ran out of time to find a move."},
+		{"AI game without an opening", "/v1/games", `{"boardSize": 6, "mode": "ai"}`, http.StatusCreated, `"moves":[]`},
+		{"replay out of time", "/v1/render/replay", `{"moves": [1, 4, 2]}`, http.StatusServiceUnavailable, "The replay took too long to draw."},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := http.Post(s.URL+tc.path, "application/json", strings.NewReader(tc.body))
+			assertNoError(t, err)
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			assertNoError(t, err)
+
+			assertStatusCode(t, resp, tc.wantStatus)
+			if !strings.Contains(string(body), tc.wantBody) {
+				t.Errorf("got body %q want it to contain %q", body, tc.wantBody)
+			}
+		})
+	}
+
+	t.Run("AI reply out of time leaves the game as it was", func(t *testing.T) {
+		resp, err := http.Post(s.URL+"/v1/games", "application/json", strings.NewReader(`{"boardSize": 6, "mode": "ai"}`))
+		assertNoError(t, err)
+		resp.Body.Close()
+		gameURL := s.URL + resp.Header.Get("Location")
+
+		resp, err = http.Post(gameURL+"/moves", "application/json", strings.NewReader(`{"position": 1}`))
+		assertNoError(t, err)
+		resp.Body.Close()
+		assertStatusCode(t, resp, http.StatusServiceUnavailable)
+
+		resp, err = http.Get(gameURL)
+		assertNoError(t, err)
+		defer resp.Body.Close()
+		var record model.GameRecord
+		assertNoError(t, json.NewDecoder(resp.Body).Decode(&record))
+		if len(record.Moves) != 0 || record.NextPlayer != game.XPlayer {
+			t.Errorf("got moves %v and next player %d, want the game untouched", record.Moves, record.NextPlayer)
+		}
+	})
+}
+
+func TestGameLimits(t *testing.T) {
+	assets, err := newPluginAssets(wellKnownFiles(""), config.DEFAULT_PUBLIC_URL, noPluginAuth)
+	assertNoError(t, err)
+	limits := game.Limits{MinBoardSize: 3, MaxBoardSize: 4, DefaultBoardSize: 4, DefaultDifficulty: 1, SearchDepth: 4}
+	s := httptest.NewServer(newRouter(assets, routerOptions{limits: limits}))
+	defer s.Close()
+
+	cases := []struct {
+		name       string
+		path       string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"default board size", "/v1/tictactoe", `{}`, http.StatusOK, `"boardSize":4`},
+		{"board too large", "/v1/tictactoe", `{"boardSize": 5}`, http.StatusBadRequest, "The supported boardSize values are 3 and 4.\n"},
+		{"position too large", "/v2/moves", `{"position": "5/5/5/5/5 x", "aiPlayer": "x"}`, http.StatusBadRequest, `"code":"invalid_position"`},
+		{"board too large in v2", "/v2/moves", `{"board": {"size": 6}, "aiPlayer": "x"}`, http.StatusBadRequest, `"message":"The supported boardSize values are 3 and 4.","field":"board.size"`},
+		{"default difficulty", "/v1/tictactoe", `{"difficulty": 4}`, http.StatusBadRequest, "Default is 1 (Easy) if not provided.\n"},
+		{"default difficulty in v2", "/v2/moves", `{"aiPlayer": "x", "difficulty": "expert"}`, http.StatusBadRequest, `Default is \"easy\".`},
+		{"game too large", "/v1/games", `{"boardSize": 6}`, http.StatusBadRequest, "The supported boardSize values are 3 and 4.\n"},
+		{"images of every size", "/v1/render/replay", `{"boardSize": 6, "moves": [1]}`, http.StatusOK, "GIF"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := http.Post(s.URL+tc.path, "application/json", strings.NewReader(tc.body))
+			assertNoError(t, err)
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			assertNoError(t, err)
+
+			assertStatusCode(t, resp, tc.wantStatus)
+			if !strings.Contains(string(body), tc.wantBody) {
+				t.Errorf("got body %q want it to contain %q", body, tc.wantBody)
+			}
+		})
+	}
+}
+
+func TestServe(t *testing.T) {
+	// start serves handler with the server settings until the returned
+	// function is called, which returns the error of serve.
+	start := func(t *testing.T, handler func(*http.Server) http.Handler, shutdownTimeout time.Duration) (*httptest.Server, func() error) {
+		cfg, err := config.Load(nil)
+		assertNoError(t, err)
+		s := httptest.NewUnstartedServer(nil)
+		s.Config = newServer(cfg)
+		s.Config.Handler = handler(s.Config)
+		s.URL = "http://" + s.Listener.Addr().String()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		served := make(chan error, 1)
+		go func() {
+			served <- serve(ctx, s.Config, s.Listener, shutdownTimeout)
+		}()
+		return s, func() error {
+			cancel()
+			return <-served
+		}
+	}
+
+	t.Run("waits for in-flight requests", func(t *testing.T) {
+		started, release := make(chan struct{}), make(chan struct{})
+		s, shutdown := start(t, func(*http.Server) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				close(started)
+				<-release
+				w.Write([]byte("done"))
+			})
+		}, time.Minute)
+
+		responses := make(chan *http.Response, 1)
+		go func() {
+			resp, err := http.Get(s.URL)
+			assertNoError(t, err)
+			responses <- resp
+		}()
+		<-started
+
+		shutdownErr := make(chan error, 1)
+		go func() {
+			shutdownErr <- shutdown()
+		}()
+		time.Sleep(50 * time.Millisecond)
+		if _, err := net.Dial("tcp", s.Listener.Addr().String()); err == nil {
+			t.Error("the server accepted a connection while shutting down")
+		}
+		close(release)
+
+		resp := <-responses
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		assertNoError(t, err)
+		assertStatusCode(t, resp, http.StatusOK)
+		if string(body) != "done" {
+			t.Errorf("got body %q want %q", body, "done")
+		}
+		assertNoError(t, <-shutdownErr)
+	})
+
+	t.Run("closes the requests left after the timeout", func(t *testing.T) {
+		started := make(chan struct{})
+		s, shutdown := start(t, func(*http.Server) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				close(started)
+				<-r.Context().Done()
+			})
+		}, 50*time.Millisecond)
+
+		requestErr := make(chan error, 1)
+		go func() {
+			resp, err := http.Get(s.URL)
+			if err == nil {
+				resp.Body.Close()
+			}
+			requestErr <- err
+		}()
+		<-started
+
+		if err := shutdown(); err == nil {
+			t.Error("got no error from a shutdown that timed out")
+		}
+		if err := <-requestErr; err == nil {
+			t.Error("the request left after the timeout was answered")
+		}
+	})
+
+	t.Run("ends event streams and WebSockets", func(t *testing.T) {
+		assets, err := newPluginAssets(wellKnownFiles(""), config.DEFAULT_PUBLIC_URL, noPluginAuth)
+		assertNoError(t, err)
+		s, shutdown := start(t, func(server *http.Server) http.Handler {
+			// Streams have to outlast the timeouts of the server
+			server.ReadTimeout, server.WriteTimeout = 50*time.Millisecond, 50*time.Millisecond
+			return newRouter(assets, routerOptions{onShutdown: server.RegisterOnShutdown})
+		}, time.Minute)
+
+		record := createGame(t, s, `{"mode": "ai", "aiPlayer": 2, "difficulty": 1}`)
+		resp, err := http.Get(s.URL + "/v1/games/" + record.ID + "/events")
+		assertNoError(t, err)
+		defer resp.Body.Close()
+		events := bufio.NewReader(resp.Body)
+
+		time.Sleep(150 * time.Millisecond)
+		moveResp, err := http.Post(s.URL+"/v1/games/"+record.ID+"/moves", "application/json", strings.NewReader(`{"position": 5}`))
+		assertNoError(t, err)
+		moveResp.Body.Close()
+		assertStatusCode(t, moveResp, http.StatusOK)
+		assertSSE(t, events, "1", "move", model.GameStatusOngoing)
+
+		humanGame := createGame(t, s, `{}`)
+		player, _, err := websocket.Dial("ws"+strings.TrimPrefix(s.URL, "http")+"/v1/games/"+humanGame.ID+"/ws", nil)
+		assertNoError(t, err)
+		defer player.Close()
+		assertGameMessage(t, player, model.MessageTypeJoined, game.XPlayer)
+
+		// A connection the client dialled but never used counts as in flight
+		// for its first seconds, so the idle ones are closed first
+		http.DefaultClient.CloseIdleConnections()
+		start := time.Now()
+		assertNoError(t, shutdown())
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("the shutdown took %v", elapsed)
+		}
+		var closeErr *websocket.CloseError
+		if _, _, err := player.ReadMessage(); !errors.As(err, &closeErr) || closeErr.Code != websocket.CloseGoingAway {
+			t.Errorf("got error %v want a going-away close frame", err)
+		}
+	})
+
+	t.Run("returns listen errors", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		assertNoError(t, err)
+		listener.Close()
+
+		if err := serve(context.Background(), &http.Server{}, listener, time.Minute); err == nil {
+			t.Error("got no error serving on a closed listener")
+		}
+	})
+}
+
 func TestOpenAIPluginHandler(t *testing.T) {
 	t.Parallel()
 
+	assets, err := newPluginAssets(wellKnownFiles(""), "https://tictactoe.example.com/", noPluginAuth)
+	assertNoError(t, err)
 	req := httptest.NewRequest(http.MethodGet, "/.well-known/ai-plugin.json", nil)
 	recorder := httptest.NewRecorder()
-	openAIPluginHandler(recorder, req)
+	assets.openAIPluginHandler(recorder, req)
 
 	res := recorder.Result()
 	defer res.Body.Close()
 
 	assertStatusCode(t, res, http.StatusOK)
+	manifest := struct {
+		API struct {
+			URL string `json:"url"`
+		} `json:"api"`
+		LogoURL string `json:"logo_url"`
+	}{}
+	assertNoError(t, json.NewDecoder(res.Body).Decode(&manifest))
+	if manifest.API.URL != "https://tictactoe.example.com/openapi.yaml" || manifest.LogoURL != "https://tictactoe.example.com/logo.png" {
+		t.Errorf("got manifest %+v", manifest)
+	}
 }
 
 func TestOpenapiHandler(t *testing.T) {
 	t.Parallel()
 
+	assets, err := newPluginAssets(wellKnownFiles(""), "http://localhost:8080", noPluginAuth)
+	assertNoError(t, err)
 	req := httptest.NewRequest(http.MethodGet, "/openapi.yaml", nil)
 	recorder := httptest.NewRecorder()
-	openapiHandler(recorder, req)
+	assets.openapiHandler(recorder, req)
 
 	res := recorder.Result()
 	defer res.Body.Close()
 
 	assertStatusCode(t, res, http.StatusOK)
+	body, err := io.ReadAll(res.Body)
+	assertNoError(t, err)
+	if !strings.Contains(string(body), "  - url: 'http://localhost:8080/v1'\n") || strings.Contains(string(body), "{{") {
+		t.Errorf("got spec without the public URL:\n%s", body)
+	}
 }
 
 func TestLogoHandler(t *testing.T) {
 	t.Parallel()
 
+	assets, err := newPluginAssets(wellKnownFiles(""), config.DEFAULT_PUBLIC_URL, noPluginAuth)
+	assertNoError(t, err)
 	req := httptest.NewRequest(http.MethodGet, "/logo.png", nil)
 	recorder := httptest.NewRecorder()
-	openapiHandler(recorder, req)
+	assets.logoHandler(recorder, req)
 	res := recorder.Result()
 	defer res.Body.Close()
 
 	assertStatusCode(t, res, http.StatusOK)
+	if _, err := png.Decode(res.Body); err != nil {
+		t.Errorf("got error %v decoding the logo", err)
+	}
+}
+
+func TestPluginAssetsPublicURL(t *testing.T) {
+	t.Parallel()
+
+	for _, invalid := range []string{"", "api.example.com", "ftp://api.example.com", "https://", "https://api.example.com/?a=1", `https://api.example.com/"`} {
+		if _, err := newPluginAssets(wellKnownFiles(""), invalid, noPluginAuth); err == nil {
+			t.Errorf("got no error for public URL %q", invalid)
+		}
+	}
 }
 
 func BenchmarkTicTacToeHandler(b *testing.B) {
@@ -185,8 +1585,68 @@ func assertNoError(t testing.TB, err error) {
 	}
 }
 
+func createGame(t testing.TB, s *httptest.Server, payload string) model.GameRecord {
+	t.Helper()
+	resp, err := http.Post(s.URL+"/v1/games", "application/json", strings.NewReader(payload))
+	assertNoError(t, err)
+	defer resp.Body.Close()
+	assertStatusCode(t, resp, http.StatusCreated)
+
+	record := model.GameRecord{}
+	assertNoError(t, json.NewDecoder(resp.Body).Decode(&record))
+	return record
+}
+
+func assertGameMessage(t testing.TB, conn *websocket.Conn, wantType string, wantPlayer int) model.GameMessage {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	got := model.GameMessage{}
+	if err := conn.ReadJSON(&got); err != nil {
+		t.Fatalf("got error %v when no error was expected", err)
+	}
+	if got.Type != wantType || got.Player != wantPlayer {
+		t.Errorf("got message %+v want type %s for player %d", got, wantType, wantPlayer)
+	}
+	return got
+}
+
+// assertSSE reads the next server-sent event, skipping comments and retry hints.
+func assertSSE(t testing.TB, r *bufio.Reader, wantID string, wantEvent string, wantStatus string) model.GameEvent {
+	t.Helper()
+	fields := map[string]string{}
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("got error %v when no error was expected", err)
+		}
+		line = strings.TrimRight(line, "\n")
+		if line == "" {
+			if _, ok := fields["event"]; ok {
+				break
+			}
+			continue
+		}
+		name, value, _ := strings.Cut(line, ": ")
+		fields[name] = value
+	}
+
+	got := model.GameEvent{}
+	assertNoError(t, json.Unmarshal([]byte(fields["data"]), &got))
+	if fields["id"] != wantID || fields["event"] != wantEvent {
+		t.Errorf("got event %s with id %s want %s with id %s", fields["event"], fields["id"], wantEvent, wantID)
+	}
+	if wantStatus != "" && got.GameStatus != wantStatus {
+		t.Errorf("got status %s want %s", got.GameStatus, wantStatus)
+	}
+	return got
+}
+
+// newTestServer serves every route of the server, each time with fresh game
+// state.
 func newTestServer() *httptest.Server {
-	ticTacToeGame := game.NewTicTacToeGame()
-	ticTacToeAPI := api.NewTicTacToeAPI(ticTacToeGame)
-	return httptest.NewServer(http.HandlerFunc(ticTacToeAPI.TicTacToeHandler))
+	assets, err := newPluginAssets(wellKnownFiles(""), config.DEFAULT_PUBLIC_URL, noPluginAuth)
+	if err != nil {
+		panic(err)
+	}
+	return httptest.NewServer(newRouter(assets, routerOptions{}))
 }
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	tictactoeapi "github.com/isavita/tictactoe_api"
+	"github.com/isavita/tictactoe_api/internal/config"
+	"github.com/isavita/tictactoe_api/internal/openapi"
+)
+
+func TestOpenAPIContract(t *testing.T) {
+	assets, err := newPluginAssets(wellKnownFiles(""), config.DEFAULT_PUBLIC_URL, noPluginAuth)
+	assertNoError(t, err)
+	spec, err := openapi.Parse(assets.openapi)
+	if err != nil {
+		t.Fatalf("got error %v parsing the spec", err)
+	}
+
+	s := newTestServer()
+	defer s.Close()
+
+	// validRequest is false for requests that break the documented schema on
+	// purpose; their error responses are still checked.
+	cases := []struct {
+		name         string
+		method       string
+		query        string
+		accept       string
+		body         string
+		validRequest bool
+	}{
+		{"first move", http.MethodPost, "", "", `{}`, true},
+		{"user move", http.MethodPost, "", "", `{"board": [0, 0, 0, 0, 1, 0, 0, 0, 0], "difficulty": 2, "displayStyle": "markdown"}`, true},
+		{"position", http.MethodPost, "", "", `{"position": "x.o/.x./... o", "locale": "de", "clientType": "app"}`, true},
+		{"game over", http.MethodPost, "", "", `{"board": [1, 1, 1, 2, 2, 0, 0, 0, 0]}`, true},
+		{"AI plays O", http.MethodPost, "", "", `{"board": [0, 0, 0, 0, 1, 0, 0, 0, 0], "aiPlayer": 2, "firstPlayer": 1, "boardSize": 3}`, true},
+		{"svg", http.MethodPost, "?format=svg&theme=dark", "", `{}`, true},
+		{"png", http.MethodPost, "", "image/png", `{}`, true},
+		{"ansi", http.MethodPost, "?format=ansi", "", `{}`, true},
+		{"not the AI's turn as text", http.MethodPost, "", "", `{"board": [1, 0, 0, 0, 0, 0, 0, 0, 0], "aiPlayer": 1}`, true},
+		{"not the AI's turn as json", http.MethodPost, "", "application/json", `{"board": [1, 0, 0, 0, 0, 0, 0, 0, 0], "aiPlayer": 1}`, true},
+		{"unsupported board size", http.MethodPost, "", "application/json", `{"boardSize": 7}`, false},
+		{"malformed body", http.MethodPost, "", "application/json", `{`, false},
+		{"wrong method as text", http.MethodGet, "", "", ``, true},
+		{"wrong method as json", http.MethodGet, "", "application/json", ``, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.validRequest && tc.method == http.MethodPost {
+				assertNoError(t, spec.ValidateRequest(tc.method, "/v1/tictactoe", "application/json", []byte(tc.body)))
+			}
+
+			req, _ := http.NewRequest(tc.method, s.URL+"/v1/tictactoe"+tc.query, strings.NewReader(tc.body))
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			assertNoError(t, err)
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			assertNoError(t, err)
+
+			// 405 responses are documented on the operations of the path
+			method := tc.method
+			if resp.StatusCode == http.StatusMethodNotAllowed {
+				method = http.MethodPost
+			}
+			assertNoError(t, spec.ValidateResponse(method, "/v1/tictactoe", resp.StatusCode, resp.Header.Get("Content-Type"), body))
+		})
+	}
+}
+
+func TestOpenAPISchemasAreGenerated(t *testing.T) {
+	doc, err := tictactoeapi.WellKnown.ReadFile(".well-known/openapi.yaml")
+	assertNoError(t, err)
+
+	schemas, err := openapi.GenerateSchemas("../../internal/model", openapi.ModelSchemas...)
+	assertNoError(t, err)
+	want, err := openapi.ReplaceSchemas(doc, schemas)
+	assertNoError(t, err)
+
+	if !bytes.Equal(doc, want) {
+		t.Errorf("the schemas in .well-known/openapi.yaml are out of date, run go generate ./internal/model")
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+var updateV1 = flag.Bool("update-v1", false, "rewrite the frozen v1 responses in testdata/v1")
+
+// v1Cases are requests whose responses /v1/tictactoe must keep returning byte
+// for byte. Only deterministic requests belong here: difficulty 1 and the
+// random fallback of difficulty 2 are left out.
+var v1Cases = []struct {
+	name    string
+	method  string
+	query   string
+	headers map[string]string
+	body    string
+}{
+	{"first_move_empty_payload", http.MethodPost, "", nil, `{}`},
+	{"first_move_missing_board", http.MethodPost, "", nil, `{"boardSize": 3, "difficulty": 3}`},
+	{"reply_to_center", http.MethodPost, "", nil, `{"board": [0, 0, 0, 0, 1, 0, 0, 0, 0]}`},
+	{"block_as_o", http.MethodPost, "", nil, `{"board": [1, 1, 0, 0, 2, 0, 0, 0, 0], "difficulty": 2}`},
+	{"win_as_x", http.MethodPost, "", nil, `{"board": [1, 1, 0, 2, 2, 0, 0, 0, 0]}`},
+	{"draw", http.MethodPost, "", nil, `{"board": [1, 2, 1, 1, 2, 2, 2, 1, 0]}`},
+	{"game_over", http.MethodPost, "", nil, `{"board": [1, 1, 1, 2, 2, 0, 0, 0, 0]}`},
+	{"board_4x4", http.MethodPost, "", nil, `{"board": [1, 0, 0, 0, 0, 2, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0], "boardSize": 4}`},
+	{"position", http.MethodPost, "", nil, `{"position": "x.o/.x./... o"}`},
+	{"display_style_unicode", http.MethodPost, "", nil, `{"board": [1, 0, 0, 0, 2, 0, 0, 0, 1], "displayStyle": "unicode"}`},
+	{"locale_es", http.MethodPost, "", nil, `{"board": [1, 0, 0, 0, 0, 0, 0, 0, 0], "locale": "es"}`},
+	{"accept_language_de", http.MethodPost, "", map[string]string{"Accept-Language": "de"}, `{"board": [1, 0, 0, 0, 0, 0, 0, 0, 0]}`},
+	{"client_type_app", http.MethodPost, "", nil, `{"clientType": "app"}`},
+	{"ai_player", http.MethodPost, "", nil, `{"board": [0, 0, 0, 0, 2, 0, 0, 0, 0], "aiPlayer": 1, "firstPlayer": 2}`},
+	{"svg", http.MethodPost, "", map[string]string{"Accept": "image/svg+xml"}, `{"board": [1, 0, 0, 0, 0, 0, 0, 0, 0]}`},
+	{"ansi", http.MethodPost, "?format=ansi", nil, `{"board": [1, 0, 0, 0, 0, 0, 0, 0, 0]}`},
+	{"error_board_size_text", http.MethodPost, "", nil, `{"boardSize": 7}`},
+	{"error_board_size_json", http.MethodPost, "", map[string]string{"Accept": "application/json"}, `{"boardSize": 7}`},
+	{"error_piece_count", http.MethodPost, "", nil, `{"board": [1, 1, 0, 0, 0, 0, 0, 0, 0]}`},
+	{"error_difficulty", http.MethodPost, "", nil, `{"difficulty": 4}`},
+	{"error_malformed_body", http.MethodPost, "", nil, `{`},
+	{"error_not_ai_turn", http.MethodPost, "", nil, `{"board": [1, 0, 0, 0, 0, 0, 0, 0, 0], "aiPlayer": 1}`},
+	{"error_method", http.MethodGet, "", nil, ``},
+}
+
+func TestV1Compatibility(t *testing.T) {
+	s := newTestServer()
+	defer s.Close()
+
+	for _, tc := range v1Cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest(tc.method, s.URL+"/v1/tictactoe"+tc.query, strings.NewReader(tc.body))
+			assertNoError(t, err)
+			for name, value := range tc.headers {
+				req.Header.Set(name, value)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			assertNoError(t, err)
+			defer resp.Body.Close()
+			got := dumpV1Response(t, resp)
+
+			golden := filepath.Join("testdata", "v1", tc.name+".txt")
+			if *updateV1 {
+				assertNoError(t, os.WriteFile(golden, got, 0o644))
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("missing frozen response, run the tests with -update-v1: %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("the v1 response changed\ngot:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
+
+// dumpV1Response writes the status, the headers but Date and Content-Length,
+// and the body.
+func dumpV1Response(t testing.TB, resp *http.Response) []byte {
+	t.Helper()
+	var b bytes.Buffer
+
+	fmt.Fprintf(&b, "%d %s\n", resp.StatusCode, http.StatusText(resp.StatusCode))
+	names := make([]string, 0, len(resp.Header))
+	for name := range resp.Header {
+		if name != "Date" && name != "Content-Length" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(&b, "%s: %s\n", name, strings.Join(resp.Header[name], ", "))
+	}
+	b.WriteString("\n")
+
+	body, err := io.ReadAll(resp.Body)
+	assertNoError(t, err)
+	b.Write(body)
+
+	return b.Bytes()
+}
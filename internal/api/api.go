@@ -9,13 +9,16 @@ import (
 	"github.com/isavita/tictactoe_api/internal/model"
 )
 
+// TicTacToeAPI answers the moves of v1 and v2 on boards within its limits.
 type TicTacToeAPI struct {
-	game *game.TicTacToeGame
+	game   *game.TicTacToeGame
+	limits game.Limits
 }
 
-func NewTicTacToeAPI(game *game.TicTacToeGame) *TicTacToeAPI {
+func NewTicTacToeAPI(game *game.TicTacToeGame, limits game.Limits) *TicTacToeAPI {
 	return &TicTacToeAPI{
-		game: game,
+		game:   game,
+		limits: limits,
 	}
 }
 
@@ -24,78 +27,207 @@ const (
 	INVALID_DIFFICULTY = "Invalid difficulty: Use 1 (Easy), 2 (Medium), or 3 (Hard). Default is 3 (Hard) if not provided."
 	INVALID_BOARD_SIZE = "The supported boardSize values are 3, 4, 5 and 6."
 	INVALID_BOARD      = "Invalid board: Must have exactly 9, 16, 25, or 36 numbers (0, 1, or 2); 0 (empty), 1 (Player 1), 2 (Player 2); Player 1 moves >= Player 2 moves; max difference: 1."
+	INVALID_POSITION   = "Invalid position: Use rows of 'x', 'o' and '.' separated by '/' followed by the side to move, e.g. \"x.o/.x./..o o\"; the side to move must match the number of X and O."
+	AMBIGUOUS_BOARD    = "Send either board (with boardSize) or position, not both."
+	INVALID_LAST_MOVE  = "Invalid lastMove: Use a cell numbered from 1 to n as in boardDisplay, or 0 for none."
+	INVALID_FORMAT     = "Invalid format: Use \"svg\" or \"png\"."
+	INVALID_CELL_SIZE  = "Invalid cellSize: Use a size between 16 and 256 pixels. Default is 100."
+	INVALID_THEME      = "Invalid theme: Use \"light\", \"dark\" or \"high-contrast\". Default is \"light\"."
+	INVALID_MOVES      = "Invalid moves: Use empty cells numbered from 1 to n as in boardDisplay, X first, with no moves after the game is over."
+	INVALID_DELAY      = "Invalid delay: Use a frame delay between 100 and 5000 milliseconds. Default is 800."
+	INVALID_FORMAT_V1  = "Invalid format: Use \"svg\", \"png\" or \"ansi\"."
+	REPLAY_TOO_LARGE   = "The replay is too large: Use a smaller cellSize for a game with this many moves."
+	INVALID_FIRST      = "Invalid firstPlayer: Use 1 (X) or 2 (O). Default is 1 (X)."
+	NOT_AI_TURN        = "It's not the submitted player's turn. Please submit the correct player's move."
+	INVALID_CLIENT     = "Invalid clientType: Use \"assistant\" (an LLM assistant, receives assistantHints) or \"app\". Default is \"assistant\"."
+	INVALID_DISPLAY    = "Invalid displayStyle: Use \"classic\", \"unicode\", \"emoji\", \"compact\" or \"markdown\". Default is \"classic\"."
 )
 
 func (api *TicTacToeAPI) TicTacToeHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		w.WriteHeader(http.StatusMethodNotAllowed)
+	var moveRequest model.MoveRequest
+	if apiErr := decodeJSON(w, r, &moveRequest, false); apiErr != nil {
+		writeError(w, r, apiErr)
 		return
 	}
 
-	var moveRequest model.MoveRequest
-	err := json.NewDecoder(r.Body).Decode(&moveRequest)
+	r = withLocale(r, moveRequest.Locale)
+	moveRequest.Locale = requestLocale(r)
+	w.Header().Set("Content-Language", moveRequest.Locale)
+
+	sideToMove, apiErr := resolveBoard(api.limits, &moveRequest.Board, &moveRequest.BoardSize, moveRequest.Position)
+	if apiErr != nil {
+		writeError(w, r, apiErr)
+		return
+	}
+
+	// X moves first unless told otherwise
+	if moveRequest.FirstPlayer == 0 {
+		moveRequest.FirstPlayer = game.XPlayer
+	}
+	if moveRequest.FirstPlayer != game.XPlayer && moveRequest.FirstPlayer != game.OPlayer {
+		writeError(w, r, ErrInvalidFirstPlayer)
+		return
+	}
+
+	if moveRequest.AIPlayer != 0 && moveRequest.AIPlayer != game.XPlayer && moveRequest.AIPlayer != game.OPlayer {
+		writeError(w, r, ErrInvalidAIPlayer)
+		return
+	}
+
+	currentPlayer, err := getCurrentPlayer(moveRequest.Board, moveRequest.FirstPlayer)
 	if err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeError(w, r, ErrIllegalPieceCount)
 		return
 	}
 
-	// Sets default value to 3 for 3x3 board
-	if moveRequest.BoardSize == 0 {
-		moveRequest.BoardSize = 3
+	if sideToMove != 0 && sideToMove != currentPlayer {
+		writeError(w, r, ErrInvalidPosition)
+		return
 	}
 
-	// Check the board is not too big
-	if moveRequest.BoardSize > 6 || moveRequest.BoardSize < 3 {
-		http.Error(w, INVALID_BOARD_SIZE, http.StatusBadRequest)
+	if moveRequest.AIPlayer != 0 && moveRequest.AIPlayer != currentPlayer {
+		writeError(w, r, ErrNotAITurn)
 		return
 	}
 
-	// if the board is not initialized
-	if moveRequest.Board == nil {
-		moveRequest.Board = make([]int, moveRequest.BoardSize*moveRequest.BoardSize)
+	moveRequest.Difficulty, err = api.limits.ParseDifficulty(moveRequest.Difficulty)
+	if err != nil {
+		writeError(w, r, difficultyError(api.limits, ErrInvalidDifficulty))
+		return
+	}
+
+	if _, ok := game.LookupRenderer(moveRequest.DisplayStyle); !ok {
+		writeError(w, r, ErrInvalidDisplayStyle)
+		return
+	}
+
+	if !game.IsClientType(moveRequest.ClientType) {
+		writeError(w, r, ErrInvalidClientType)
+		return
 	}
 
-	currentPlayer, err := getCurrentPlayer(moveRequest.Board)
+	// The answer's format is checked before the AI searches for its move
+	switch r.URL.Query().Get("format") {
+	case "", FORMAT_SVG, FORMAT_PNG, FORMAT_ANSI:
+	default:
+		writeError(w, r, ErrInvalidFormat.WithMessage(INVALID_FORMAT_V1))
+		return
+	}
+	options, apiErr := renderOptions(0, r.URL.Query().Get("theme"))
+	if apiErr != nil {
+		writeError(w, r, apiErr)
+		return
+	}
+
+	previousBoard := append([]int(nil), moveRequest.Board...)
+	moveResponse, err := api.game.MakeMove(r.Context(), currentPlayer, moveRequest)
 	if err != nil {
-		http.Error(w, INVALID_BOARD, http.StatusBadRequest)
+		writeError(w, r, ErrSearchTimeout)
 		return
 	}
 
-	if moveRequest.Difficulty == 0 || moveRequest.Difficulty == 3 {
-		moveRequest.Difficulty = game.DifficultyHard
-	} else if moveRequest.Difficulty == 1 {
-		moveRequest.Difficulty = game.DifficultyEasy
-	} else if moveRequest.Difficulty == 2 {
-		moveRequest.Difficulty = game.DifficultyMedium
-	} else {
-		http.Error(w, INVALID_DIFFICULTY, http.StatusBadRequest)
+	if format := imageFormat(r); format != "" {
+		options.LastMove = changedCell(previousBoard, moveResponse.Board)
+		writeImage(w, r, format, moveResponse.Board, moveResponse.BoardSize, options)
+		return
+	}
+
+	if wantsANSI(r) {
+		writeANSI(w, moveResponse, changedCell(previousBoard, moveResponse.Board))
 		return
 	}
 
-	moveResponse := api.game.MakeMove(currentPlayer, moveRequest)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(moveResponse)
 }
 
-func getCurrentPlayer(board []int) (int, error) {
-	xCount := 0
-	oCount := 0
+// resolveBoard fills in the board and boardSize of a request, either from the
+// position string or from their defaults, and validates them. It returns the
+// side to move from the position string, or 0 if there is none.
+func resolveBoard(limits game.Limits, board *[]int, boardSize *int, position string) (int, *APIError) {
+	// The position string replaces board and boardSize
+	sideToMove := 0
+	if position != "" {
+		if *board != nil {
+			return 0, ErrAmbiguousBoard
+		}
+
+		parsedBoard, parsedSize, next, err := game.ParsePosition(position)
+		if err != nil || (*boardSize != 0 && *boardSize != parsedSize) {
+			return 0, ErrInvalidPosition
+		}
+		*board = parsedBoard
+		*boardSize = parsedSize
+		sideToMove = next
+	}
+
+	// Sets the default board size, 3 for 3x3 unless configured otherwise
+	if *boardSize == 0 {
+		*boardSize = limits.DefaultBoardSize
+	}
+
+	// Check the board is one of the configured sizes
+	if !limits.IsBoardSize(*boardSize) {
+		return 0, boardSizeError(limits, ErrInvalidBoardSize)
+	}
+
+	// if the board is not initialized
+	if *board == nil {
+		*board = make([]int, *boardSize**boardSize)
+	}
+
+	if !isValidBoard(*board, *boardSize) {
+		return 0, ErrInvalidBoard
+	}
+
+	return sideToMove, nil
+}
+
+// changedCell returns the first cell that differs between the boards, or -1.
+func changedCell(before []int, after []int) int {
+	for i := range after {
+		if i < len(before) && before[i] != after[i] {
+			return i
+		}
+	}
+	return -1
+}
+
+func isValidBoard(board []int, boardSize int) bool {
+	if len(board) != boardSize*boardSize {
+		return false
+	}
+
+	for _, val := range board {
+		if val != 0 && val != game.XPlayer && val != game.OPlayer {
+			return false
+		}
+	}
+
+	return true
+}
+
+// getCurrentPlayer returns the side to move from the number of X and O on the
+// board, given the side that moved first.
+func getCurrentPlayer(board []int, firstPlayer int) (int, error) {
+	firstCount := 0
+	secondCount := 0
 
 	for _, val := range board {
-		if val == game.XPlayer {
-			xCount++
-		} else if val == game.OPlayer {
-			oCount++
+		if val == firstPlayer {
+			firstCount++
+		} else if val != 0 {
+			secondCount++
 		}
 	}
 
-	if xCount < oCount || xCount > oCount+1 {
+	if firstCount < secondCount || firstCount > secondCount+1 {
 		return 0, errors.New("invalid board")
 	}
 
-	if xCount == oCount {
-		return game.XPlayer, nil
+	if firstCount == secondCount {
+		return firstPlayer, nil
 	}
 
-	return game.OPlayer, nil
+	return game.GetOponent(firstPlayer), nil
 }
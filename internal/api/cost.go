@@ -0,0 +1,199 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+
+	"github.com/isavita/tictactoe_api/internal/game"
+	"github.com/isavita/tictactoe_api/internal/model"
+	"github.com/isavita/tictactoe_api/internal/notation"
+	"github.com/isavita/tictactoe_api/internal/render"
+	"github.com/isavita/tictactoe_api/internal/router"
+)
+
+// The rate limit of a client is counted in tokens. A request costs one token
+// unless it asks the AI for a move or for a GIF replay; the AI's search on
+// hard grows quickly with the board and a replay with the pixels of its
+// frames, so those requests cost more.
+
+// hardMoveCosts are the tokens an AI move on hard costs by board size. Easy
+// and medium moves cost one token on every board.
+var hardMoveCosts = map[int]int{3: 1, 4: 2, 5: 5, 6: 10}
+
+// replayPixelsPerToken is how many pixels of GIF frames a token buys. A whole
+// 3x3 game at the default cell size costs one token.
+const replayPixelsPerToken = 1 << 20
+
+// maxPeekSize bounds how much of a body is read to find the cost of a
+// request. Larger bodies cost one token and are rejected by their handler.
+const maxPeekSize = MAX_BODY_SIZE + 1
+
+// MoveCost returns the tokens of an AI move at the difficulty, one of the
+// game.Difficulty constants.
+func MoveCost(boardSize int, difficulty int) int {
+	if cost, ok := hardMoveCosts[boardSize]; ok && difficulty == game.DifficultyHard {
+		return cost
+	}
+	return 1
+}
+
+// ReplayCost returns the tokens of a GIF replay of moves on the board, which
+// grow with the frames and the square of the cell size. A zero cellSize is
+// the default one.
+func ReplayCost(boardSize int, moves int, cellSize int) int {
+	// Replays the handler rejects cost one token
+	if boardSize < game.MinBoardSize || boardSize > game.MaxBoardSize || moves > boardSize*boardSize ||
+		(cellSize != 0 && (cellSize < render.MinCellSize || cellSize > render.MaxCellSize)) {
+		return 1
+	}
+	pixels := render.GIFPixels(moves, boardSize, render.Options{CellSize: cellSize})
+	if pixels > render.MaxGIFPixels {
+		return 1
+	}
+	return 1 + pixels/replayPixelsPerToken
+}
+
+// MoveRequestCost is the cost of POST /v1/tictactoe. Invalid requests cost
+// one token.
+func (api *TicTacToeAPI) MoveRequestCost(r *http.Request) int {
+	var moveRequest model.MoveRequest
+	if !peekJSON(r, &moveRequest) {
+		return 1
+	}
+	boardSize := moveRequest.BoardSize
+	if moveRequest.Position != "" {
+		_, boardSize, _, _ = game.ParsePosition(moveRequest.Position)
+	} else if boardSize == 0 && moveRequest.Board != nil {
+		boardSize = int(math.Sqrt(float64(len(moveRequest.Board))))
+	} else if boardSize == 0 {
+		boardSize = api.limits.DefaultBoardSize
+	}
+	difficulty, err := api.limits.ParseDifficulty(moveRequest.Difficulty)
+	if err != nil {
+		return 1
+	}
+	return MoveCost(boardSize, difficulty)
+}
+
+// MoveRequestCostV2 is the cost of POST /v2/moves.
+func (api *TicTacToeAPI) MoveRequestCostV2(r *http.Request) int {
+	var moveRequest model.MoveRequestV2
+	if !peekJSON(r, &moveRequest) {
+		return 1
+	}
+	_, boardSize, apiErr := boardFromRequestV2(api.limits, moveRequest)
+	difficulty, ok := difficultyV2(api.limits, moveRequest.Difficulty)
+	if apiErr != nil || !ok {
+		return 1
+	}
+	return MoveCost(boardSize, difficulty)
+}
+
+// ReplayRequestCost is the cost of GET and POST /v1/render/replay.
+func ReplayRequestCost(r *http.Request) int {
+	replayRequest, apiErr := replayRequestFromQuery(r)
+	ok := apiErr == nil
+	if r.Method == http.MethodPost {
+		replayRequest = model.ReplayRequest{}
+		ok = peekJSON(r, &replayRequest)
+	}
+	if !ok {
+		return 1
+	}
+	if replayRequest.BoardSize == 0 {
+		replayRequest.BoardSize = 3
+	}
+	return ReplayCost(replayRequest.BoardSize, len(replayRequest.Moves), replayRequest.CellSize)
+}
+
+// CreateCost is the cost of POST /v1/games. Games against the AI cost a move,
+// as the AI moves first when it plays X.
+func (api *GamesAPI) CreateCost(r *http.Request) int {
+	var createRequest model.CreateGameRequest
+	if !peekJSON(r, &createRequest) || createRequest.Mode != model.GameModeAI {
+		return 1
+	}
+	if createRequest.BoardSize == 0 {
+		createRequest.BoardSize = api.limits.DefaultBoardSize
+	}
+	difficulty, err := api.limits.ParseDifficulty(createRequest.Difficulty)
+	if err != nil {
+		return 1
+	}
+	return MoveCost(createRequest.BoardSize, difficulty)
+}
+
+// MoveCost is the cost of POST /v1/games/{id}/moves, whose AI replies at the
+// difficulty of the game.
+func (api *GamesAPI) MoveCost(r *http.Request) int {
+	g, ok := api.store.Get(router.Param(r, "id"))
+	if !ok {
+		return 1
+	}
+	record := g.Record()
+	if record.Mode != model.GameModeAI {
+		return 1
+	}
+	difficulty, err := api.limits.ParseDifficulty(record.Difficulty)
+	if err != nil {
+		return 1
+	}
+	return MoveCost(record.BoardSize, difficulty)
+}
+
+// ImportCost is the cost of POST /v1/games/import. Games against the AI cost
+// a move, as the AI moves when the record leaves it to move.
+func (api *GamesAPI) ImportCost(r *http.Request) int {
+	data, ok := peekBody(r)
+	if !ok {
+		return 1
+	}
+	record, err := notation.Parse(bytes.NewReader(data))
+	if err != nil || (record.X != notation.AIName && record.O != notation.AIName) {
+		return 1
+	}
+	difficulty, err := api.limits.ParseDifficulty(record.Difficulty)
+	if err != nil {
+		return 1
+	}
+	return MoveCost(record.BoardSize, difficulty)
+}
+
+// ReplayCost is the cost of GET /v1/games/{id}/replay, a GIF of the moves of
+// the game so far.
+func (api *GamesAPI) ReplayCost(r *http.Request) int {
+	g, ok := api.store.Get(router.Param(r, "id"))
+	if !ok {
+		return 1
+	}
+	replayRequest, apiErr := replayRequestFromQuery(r)
+	if apiErr != nil {
+		return 1
+	}
+	record := g.Record()
+	return ReplayCost(record.BoardSize, len(record.Moves), replayRequest.CellSize)
+}
+
+// peekJSON decodes the start of the request body into v and puts the body
+// back for the handler. It reports whether the body was decoded.
+func peekJSON(r *http.Request, v any) bool {
+	data, ok := peekBody(r)
+	return ok && json.Unmarshal(data, v) == nil
+}
+
+// peekBody reads the start of the request body and puts the body back for
+// the handler. It reports whether the whole body was read.
+func peekBody(r *http.Request) ([]byte, bool) {
+	if r.Body == nil {
+		return nil, false
+	}
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxPeekSize))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(data), r.Body), r.Body}
+	return data, err == nil && len(data) < maxPeekSize
+}
@@ -0,0 +1,169 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// MAX_BODY_SIZE is the largest request body the API reads, in bytes.
+const MAX_BODY_SIZE = 64 << 10
+
+type lenientKey struct{}
+
+// Lenient decodes the request bodies of next the way the first version of the
+// API did: unknown fields and trailing data are ignored and any content type
+// is read as JSON. The size limit still applies.
+func Lenient(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), lenientKey{}, true)))
+	})
+}
+
+func isLenient(r *http.Request) bool {
+	lenient, _ := r.Context().Value(lenientKey{}).(bool)
+	return lenient
+}
+
+// limitBody caps the request body at MAX_BODY_SIZE.
+func limitBody(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, MAX_BODY_SIZE)
+}
+
+// decodeJSON decodes the JSON request body into v. The body must be
+// application/json, or have no content type, and may only hold the fields of
+// v. An empty body leaves v unchanged when allowEmpty is set. The errors name
+// the offending field where there is one.
+func decodeJSON(w http.ResponseWriter, r *http.Request, v any, allowEmpty bool) *APIError {
+	lenient := isLenient(r)
+	if contentType := r.Header.Get("Content-Type"); contentType != "" && !lenient {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || (mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json")) {
+			return ErrUnsupportedMedia
+		}
+	}
+
+	limitBody(w, r)
+	decoder := json.NewDecoder(r.Body)
+	if !lenient {
+		decoder.DisallowUnknownFields()
+	}
+	err := decoder.Decode(v)
+	if err == nil && !lenient {
+		// Anything after the value is a mistake, such as two bodies in one
+		if _, err = decoder.Token(); err == io.EOF {
+			err = nil
+		} else if err == nil {
+			err = errTrailingData
+		}
+	}
+	if errors.Is(err, io.EOF) && allowEmpty {
+		return nil
+	}
+	apiErr := decodeError(err, v)
+	if lenient && apiErr != nil && apiErr != ErrRequestTooLarge {
+		return ErrInvalidRequestBody
+	}
+	return apiErr
+}
+
+var errTrailingData = errors.New("trailing data after the JSON value")
+
+// decodeError maps an error of decoding a request body into v to an API
+// error, or returns nil for no error.
+func decodeError(err error, v any) *APIError {
+	var maxBytesErr *http.MaxBytesError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &maxBytesErr):
+		return ErrRequestTooLarge
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		apiErr := ErrInvalidFieldType.WithMessage(fmt.Sprintf("Invalid %s: got %s, want %s.", typeErr.Field, jsonTypeName(typeErr.Value), goTypeName(typeErr.Type)))
+		apiErr.Field = typeErr.Field
+		return apiErr
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		// encoding/json has no error type for unknown fields
+		name, unquoteErr := strconv.Unquote(strings.TrimPrefix(err.Error(), "json: unknown field "))
+		if unquoteErr != nil {
+			return ErrUnknownField
+		}
+		message := fmt.Sprintf("Unknown field %q.", name)
+		if suggestion := similarField(v, name); suggestion != "" {
+			message += fmt.Sprintf(" Did you mean %q?", suggestion)
+		}
+		apiErr := ErrUnknownField.WithMessage(message)
+		apiErr.Field = name
+		return apiErr
+	default:
+		return ErrInvalidRequestBody
+	}
+}
+
+// similarField returns the JSON name of the field of the struct v points to
+// that matches name but for case, underscores and dashes, e.g. boardSize for
+// board_size.
+func similarField(v any, name string) string {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return ""
+	}
+	normalize := strings.NewReplacer("_", "", "-", "")
+	want := strings.ToLower(normalize.Replace(name))
+	for i := 0; i < t.NumField(); i++ {
+		jsonName, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if jsonName == "" || jsonName == "-" {
+			continue
+		}
+		if strings.ToLower(normalize.Replace(jsonName)) == want {
+			return jsonName
+		}
+	}
+	return ""
+}
+
+// jsonTypeName names the JSON type that encoding/json reports in
+// UnmarshalTypeError.Value, which for numbers is their text.
+func jsonTypeName(value string) string {
+	switch value {
+	case "string":
+		return "a string"
+	case "object", "array":
+		return "an " + value
+	case "bool":
+		return "a boolean"
+	}
+	if strings.HasPrefix(value, "number") {
+		return "a number"
+	}
+	return value
+}
+
+func goTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	default:
+		return "an object"
+	}
+}
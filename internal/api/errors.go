@@ -0,0 +1,216 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/isavita/tictactoe_api/internal/model"
+	"github.com/isavita/tictactoe_api/internal/session"
+)
+
+// ERROR_DOCS_URL documents every error code.
+const ERROR_DOCS_URL = "https://github.com/isavita/tictactoe_api#error-codes"
+
+// APIError is an error response with a stable machine-readable code. Field
+// names the offending request field, if any.
+type APIError struct {
+	Status  int
+	Code    string
+	Message string
+	Field   string
+}
+
+func (e *APIError) Error() string {
+	return e.Message
+}
+
+// WithMessage returns a copy of the error with a more specific message.
+func (e *APIError) WithMessage(message string) *APIError {
+	copy := *e
+	copy.Message = message
+	return &copy
+}
+
+var (
+	ErrInvalidRequestBody = &APIError{http.StatusBadRequest, "invalid_request_body", "Invalid request body", ""}
+	ErrUnknownField       = &APIError{http.StatusBadRequest, "unknown_field", "The request body has an unknown field.", ""}
+	ErrInvalidFieldType   = &APIError{http.StatusBadRequest, "invalid_field_type", "A field of the request body has the wrong type.", ""}
+	ErrInvalidQuery       = &APIError{http.StatusBadRequest, "invalid_query", "A query parameter has an invalid value.", ""}
+	ErrRequestTooLarge    = &APIError{http.StatusRequestEntityTooLarge, "request_too_large", "The request body is too large.", ""}
+	ErrUnsupportedMedia   = &APIError{http.StatusUnsupportedMediaType, "unsupported_media_type", "Unsupported content type: send the request body as application/json.", ""}
+	ErrMethodNotAllowed   = &APIError{http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed.", ""}
+	ErrNotFound           = &APIError{http.StatusNotFound, "not_found", "Not found.", ""}
+	ErrRateLimited        = &APIError{http.StatusTooManyRequests, "rate_limited", "Too many requests. Please retry later.", ""}
+	ErrUnauthorized       = &APIError{http.StatusUnauthorized, "unauthorized", "Missing or invalid API key. Send it in the Authorization header as a bearer token.", ""}
+	ErrFeatureNotAllowed  = &APIError{http.StatusForbidden, "feature_not_allowed", "The API key does not allow this endpoint.", ""}
+	ErrInternal           = &APIError{http.StatusInternalServerError, "internal_error", "Internal server error.", ""}
+	ErrSearchTimeout      = &APIError{http.StatusServiceUnavailable, "search_timeout", "The AI ran out of time to find a move. Try again, or with a lower difficulty or a smaller board.", ""}
+
+	ErrInvalidBoardSize  = &APIError{http.StatusBadRequest, "invalid_board_size", INVALID_BOARD_SIZE, "boardSize"}
+	ErrInvalidBoard      = &APIError{http.StatusBadRequest, "invalid_board", INVALID_BOARD, "board"}
+	ErrIllegalPieceCount = &APIError{http.StatusBadRequest, "illegal_piece_count", INVALID_BOARD, "board"}
+	ErrInvalidDifficulty = &APIError{http.StatusBadRequest, "invalid_difficulty", INVALID_DIFFICULTY, "difficulty"}
+	ErrInvalidPosition   = &APIError{http.StatusBadRequest, "invalid_position", INVALID_POSITION, "position"}
+	ErrAmbiguousBoard    = &APIError{http.StatusBadRequest, "ambiguous_board", AMBIGUOUS_BOARD, "position"}
+	ErrInvalidLastMove   = &APIError{http.StatusBadRequest, "invalid_last_move", INVALID_LAST_MOVE, "lastMove"}
+	ErrInvalidFormat     = &APIError{http.StatusBadRequest, "invalid_format", INVALID_FORMAT, "format"}
+	ErrInvalidCellSize   = &APIError{http.StatusBadRequest, "invalid_cell_size", INVALID_CELL_SIZE, "cellSize"}
+	ErrInvalidTheme      = &APIError{http.StatusBadRequest, "invalid_theme", INVALID_THEME, "theme"}
+	ErrInvalidMoves      = &APIError{http.StatusBadRequest, "invalid_moves", INVALID_MOVES, "moves"}
+	ErrInvalidDelay      = &APIError{http.StatusBadRequest, "invalid_delay", INVALID_DELAY, "delay"}
+	ErrReplayTooLarge    = &APIError{http.StatusBadRequest, "replay_too_large", REPLAY_TOO_LARGE, "cellSize"}
+	ErrRenderTimeout     = &APIError{http.StatusServiceUnavailable, "render_timeout", "The replay took too long to draw. Try again with a smaller cellSize.", ""}
+
+	ErrInvalidDisplayStyle = &APIError{http.StatusBadRequest, "invalid_display_style", INVALID_DISPLAY, "displayStyle"}
+	ErrInvalidClientType   = &APIError{http.StatusBadRequest, "invalid_client_type", INVALID_CLIENT, "clientType"}
+	ErrInvalidFirstPlayer  = &APIError{http.StatusBadRequest, "invalid_first_player", INVALID_FIRST, "firstPlayer"}
+	ErrNotAITurn           = &APIError{http.StatusBadRequest, "not_ai_turn", NOT_AI_TURN, "aiPlayer"}
+
+	ErrGameNotFound        = &APIError{http.StatusNotFound, "game_not_found", GAME_NOT_FOUND, ""}
+	ErrInvalidGameMode     = &APIError{http.StatusBadRequest, "invalid_game_mode", INVALID_GAME_MODE, "mode"}
+	ErrInvalidAIPlayer     = &APIError{http.StatusBadRequest, "invalid_ai_player", INVALID_AI_PLAYER, "aiPlayer"}
+	ErrInvalidMaxTakebacks = &APIError{http.StatusBadRequest, "invalid_max_takebacks", INVALID_MAX_TAKEBACKS, "maxTakebacks"}
+	ErrInvalidGameAction   = &APIError{http.StatusBadRequest, "invalid_game_action", INVALID_GAME_ACTION, "type"}
+	ErrInvalidRecord       = &APIError{http.StatusBadRequest, "invalid_record", "Invalid game record.", ""}
+
+	ErrGameFull           = &APIError{http.StatusConflict, "game_full", session.ErrGameFull.Error(), ""}
+	ErrWaitingForOpponent = &APIError{http.StatusConflict, "waiting_for_opponent", session.ErrWaitingOpponent.Error(), ""}
+	ErrGameOver           = &APIError{http.StatusConflict, "game_over", session.ErrGameOver.Error(), ""}
+	ErrNotYourTurn        = &APIError{http.StatusConflict, "not_your_turn", session.ErrNotYourTurn.Error(), ""}
+	ErrInvalidMove        = &APIError{http.StatusBadRequest, "invalid_move", session.ErrInvalidPosition.Error(), "position"}
+	ErrWrongGameMode      = &APIError{http.StatusConflict, "wrong_game_mode", "The action is not available in this game mode.", ""}
+	ErrTakebacksDisabled  = &APIError{http.StatusConflict, "takebacks_disabled", session.ErrTakebacksDisabled.Error(), ""}
+	ErrTakebackLimit      = &APIError{http.StatusConflict, "takeback_limit_reached", session.ErrTakebackLimit.Error(), ""}
+	ErrNothingToUndo      = &APIError{http.StatusConflict, "nothing_to_undo", session.ErrNothingToUndo.Error(), ""}
+	ErrTooManyGames       = &APIError{http.StatusServiceUnavailable, "too_many_games", "The server holds too many games. Please retry later.", ""}
+)
+
+// errorKeys maps the API errors with their default messages to the keys of
+// their translations in the i18n catalogue.
+var errorKeys = map[*APIError]string{}
+
+func init() {
+	for _, apiErr := range []*APIError{
+		ErrInvalidRequestBody, ErrUnknownField, ErrInvalidFieldType, ErrInvalidQuery, ErrRequestTooLarge, ErrUnsupportedMedia,
+		ErrMethodNotAllowed, ErrNotFound, ErrRateLimited,
+		ErrUnauthorized, ErrFeatureNotAllowed, ErrInternal, ErrSearchTimeout,
+		ErrInvalidBoardSize, ErrInvalidBoard, ErrIllegalPieceCount, ErrInvalidDifficulty,
+		ErrInvalidPosition, ErrAmbiguousBoard, ErrInvalidLastMove, ErrInvalidFormat,
+		ErrInvalidCellSize, ErrInvalidTheme, ErrInvalidMoves, ErrInvalidDelay, ErrReplayTooLarge, ErrRenderTimeout, ErrInvalidDisplayStyle, ErrInvalidClientType,
+		ErrInvalidFirstPlayer, ErrNotAITurn,
+		ErrGameNotFound, ErrInvalidGameMode, ErrInvalidAIPlayer, ErrInvalidMaxTakebacks,
+		ErrInvalidGameAction, ErrInvalidRecord,
+		ErrGameFull, ErrWaitingForOpponent, ErrGameOver, ErrNotYourTurn, ErrInvalidMove,
+		ErrWrongGameMode, ErrTakebacksDisabled, ErrTakebackLimit, ErrNothingToUndo, ErrTooManyGames,
+	} {
+		errorKeys[apiErr] = "error." + apiErr.Code
+	}
+
+	// v2 errors share the codes of v1 but describe the v2 fields.
+	errorKeys[ErrInvalidBoardSizeV2] = "error.invalid_board_size"
+	for _, apiErr := range []*APIError{
+		ErrInvalidCellsV2, ErrIllegalPieceCountV2, ErrAmbiguousBoardV2,
+		ErrInvalidAIPlayerV2, ErrInvalidFirstPlayerV2, ErrInvalidDifficultyV2,
+	} {
+		errorKeys[apiErr] = "error.v2." + apiErr.Code
+	}
+}
+
+// gameError maps the errors of server-held games to API errors.
+func gameError(err error) *APIError {
+	switch {
+	case errors.Is(err, session.ErrGameFull):
+		return ErrGameFull
+	case errors.Is(err, session.ErrWaitingOpponent):
+		return ErrWaitingForOpponent
+	case errors.Is(err, session.ErrGameOver):
+		return ErrGameOver
+	case errors.Is(err, session.ErrNotYourTurn):
+		return ErrNotYourTurn
+	case errors.Is(err, session.ErrInvalidPosition):
+		return ErrInvalidMove
+	case errors.Is(err, session.ErrHumanGame), errors.Is(err, session.ErrAIGame):
+		return ErrWrongGameMode.WithMessage(err.Error())
+	case errors.Is(err, session.ErrTakebacksDisabled):
+		return ErrTakebacksDisabled
+	case errors.Is(err, session.ErrTakebackLimit):
+		return ErrTakebackLimit
+	case errors.Is(err, session.ErrNothingToUndo):
+		return ErrNothingToUndo
+	case errors.Is(err, session.ErrTooManyGames):
+		return ErrTooManyGames
+	case isSearchStopped(err):
+		return ErrSearchTimeout
+	default:
+		return ErrInternal
+	}
+}
+
+// isSearchStopped reports whether err is the context error of an AI search
+// that stopped before it found a move.
+func isSearchStopped(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled)
+}
+
+// writeError responds with a JSON error envelope when the client accepts
+// application/json, and with the plain-text message used by v1 otherwise. The
+// message is in the locale of the request.
+func writeError(w http.ResponseWriter, r *http.Request, apiErr *APIError) {
+	if acceptsJSON(r) {
+		writeJSONError(w, r, apiErr)
+		return
+	}
+
+	locale := requestLocale(r)
+	w.Header().Set("Content-Language", locale)
+	http.Error(w, localizedMessage(locale, apiErr), apiErr.Status)
+}
+
+// writeJSONError responds with the JSON error envelope whatever the client
+// accepts.
+func writeJSONError(w http.ResponseWriter, r *http.Request, apiErr *APIError) {
+	locale := requestLocale(r)
+	w.Header().Set("Content-Language", locale)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(apiErr.Status)
+	json.NewEncoder(w).Encode(model.ErrorResponse{
+		Error: model.ErrorDetail{
+			Code:    apiErr.Code,
+			Message: localizedMessage(locale, apiErr),
+			Field:   apiErr.Field,
+			Docs:    ERROR_DOCS_URL,
+		},
+	})
+}
+
+// acceptsJSON reports whether the Accept header explicitly lists a JSON media
+// type. Wildcards keep the plain-text errors of v1.
+func acceptsJSON(r *http.Request) bool {
+	return acceptsMediaType(r, "application/json", "application/problem+json")
+}
+
+// acceptsMediaType reports whether the Accept header explicitly lists one of
+// the media types with a non-zero quality.
+func acceptsMediaType(r *http.Request, mediaTypes ...string) bool {
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err != nil {
+			continue
+		}
+		if q, err := strconv.ParseFloat(params["q"], 64); err == nil && q == 0 {
+			continue
+		}
+		for _, want := range mediaTypes {
+			if mediaType == want {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,552 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/isavita/tictactoe_api/internal/game"
+	"github.com/isavita/tictactoe_api/internal/model"
+	"github.com/isavita/tictactoe_api/internal/notation"
+	"github.com/isavita/tictactoe_api/internal/router"
+	"github.com/isavita/tictactoe_api/internal/session"
+	"github.com/isavita/tictactoe_api/internal/websocket"
+)
+
+type GamesAPI struct {
+	store  *session.Store
+	limits game.Limits
+
+	// closing is closed by Close to end the long-lived connections.
+	closing   chan struct{}
+	closeOnce sync.Once
+}
+
+func NewGamesAPI(store *session.Store, limits game.Limits) *GamesAPI {
+	return &GamesAPI{
+		store:   store,
+		limits:  limits,
+		closing: make(chan struct{}),
+	}
+}
+
+// Close ends the event streams and WebSocket connections of the games, which
+// would otherwise keep the server from shutting down. WebSocket players get a
+// going-away close frame and event streams end, so that clients reconnect to
+// another server. Requests that are not streaming are left to finish.
+func (api *GamesAPI) Close() {
+	api.closeOnce.Do(func() { close(api.closing) })
+}
+
+const (
+	GAME_NOT_FOUND        = "Game not found."
+	INVALID_GAME_ACTION   = "Unknown message type: send {\"type\": \"move\", \"position\": n} with n numbered as in boardDisplay."
+	INVALID_GAME_MODE     = "Invalid mode: Use \"human\" (two players over WebSocket) or \"ai\" (play against the AI). Default is \"human\"."
+	INVALID_AI_PLAYER     = "Invalid aiPlayer: Use 1 (X) or 2 (O)."
+	INVALID_MAX_TAKEBACKS = "Invalid maxTakebacks: Must be 0 (unlimited) or a positive number."
+
+	INVALID_WIN_LENGTH     = "Invalid game record: WinLength must be equal to BoardSize."
+	INVALID_RECORD_PLAYERS = "Invalid game record: at most one side can be played by the AI."
+)
+
+const (
+	sseRetry     = 3 * time.Second
+	sseHeartbeat = 15 * time.Second
+
+	// wsCloseWait is how long Close waits for players to answer its close
+	// frame.
+	wsCloseWait = time.Second
+
+	// Players are pinged every wsPingPeriod and lose their seat when they
+	// send nothing, not even a pong, for wsPongWait.
+	wsPingPeriod = 30 * time.Second
+	wsPongWait   = time.Minute
+)
+
+// GamesHandler creates games on POST /v1/games, either between two humans or
+// against the AI.
+func (api *GamesAPI) GamesHandler(w http.ResponseWriter, r *http.Request) {
+	var createRequest model.CreateGameRequest
+	if apiErr := decodeJSON(w, r, &createRequest, true); apiErr != nil {
+		writeError(w, r, apiErr)
+		return
+	}
+
+	// Sets the default board size, 3 for 3x3 unless configured otherwise
+	if createRequest.BoardSize == 0 {
+		createRequest.BoardSize = api.limits.DefaultBoardSize
+	}
+
+	if !api.limits.IsBoardSize(createRequest.BoardSize) {
+		writeError(w, r, boardSizeError(api.limits, ErrInvalidBoardSize))
+		return
+	}
+
+	if createRequest.Mode == "" {
+		createRequest.Mode = model.GameModeHuman
+	}
+	if createRequest.Mode != model.GameModeHuman && createRequest.Mode != model.GameModeAI {
+		writeError(w, r, ErrInvalidGameMode)
+		return
+	}
+
+	difficulty, err := api.limits.ParseDifficulty(createRequest.Difficulty)
+	if err != nil {
+		writeError(w, r, difficultyError(api.limits, ErrInvalidDifficulty))
+		return
+	}
+
+	// The AI plays O by default so the human moves first.
+	if createRequest.AIPlayer == 0 {
+		createRequest.AIPlayer = game.OPlayer
+	}
+	if createRequest.AIPlayer != game.XPlayer && createRequest.AIPlayer != game.OPlayer {
+		writeError(w, r, ErrInvalidAIPlayer)
+		return
+	}
+
+	if createRequest.MaxTakebacks < 0 {
+		writeError(w, r, ErrInvalidMaxTakebacks)
+		return
+	}
+
+	if _, ok := game.LookupRenderer(createRequest.DisplayStyle); !ok {
+		writeError(w, r, ErrInvalidDisplayStyle)
+		return
+	}
+
+	g, err := api.store.Create(r.Context(), session.Options{
+		BoardSize:      createRequest.BoardSize,
+		Mode:           createRequest.Mode,
+		Difficulty:     difficulty,
+		AIPlayer:       createRequest.AIPlayer,
+		AllowTakebacks: createRequest.AllowTakebacks,
+		MaxTakebacks:   createRequest.MaxTakebacks,
+		DisplayStyle:   createRequest.DisplayStyle,
+		SearchDepth:    api.limits.SearchDepth,
+	})
+	if err != nil {
+		apiErr := gameError(err)
+		if apiErr == ErrInternal {
+			log.Printf("create game: %v", err)
+		}
+		writeError(w, r, apiErr)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/v1/games/"+g.ID())
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(g.Record())
+}
+
+// GameHandler returns the record of a game on GET /v1/games/{id}.
+func (api *GamesAPI) GameHandler(w http.ResponseWriter, r *http.Request) {
+	if g, ok := api.game(w, r); ok {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(g.Record())
+	}
+}
+
+// JoinHandler joins a game over WebSocket on GET /v1/games/{id}/ws.
+func (api *GamesAPI) JoinHandler(w http.ResponseWriter, r *http.Request) {
+	if g, ok := api.game(w, r); ok {
+		api.play(w, r, g)
+	}
+}
+
+// EventsHandler streams a game to spectators on GET /v1/games/{id}/events.
+func (api *GamesAPI) EventsHandler(w http.ResponseWriter, r *http.Request) {
+	if g, ok := api.game(w, r); ok {
+		api.spectate(w, r, g)
+	}
+}
+
+// RecordHandler exports a game in the text notation on
+// GET /v1/games/{id}/record.
+func (api *GamesAPI) RecordHandler(w http.ResponseWriter, r *http.Request) {
+	if g, ok := api.game(w, r); ok {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		notation.FromGame(g.Record()).Write(w)
+	}
+}
+
+// GameReplayHandler animates a game as a GIF on GET /v1/games/{id}/replay.
+func (api *GamesAPI) GameReplayHandler(w http.ResponseWriter, r *http.Request) {
+	g, ok := api.game(w, r)
+	if !ok {
+		return
+	}
+	replayRequest, apiErr := replayRequestFromQuery(r)
+	if apiErr != nil {
+		writeError(w, r, apiErr)
+		return
+	}
+	record := g.Record()
+	replayRequest.BoardSize = record.BoardSize
+	replayRequest.Moves = make([]int, len(record.Moves))
+	for i, move := range record.Moves {
+		replayRequest.Moves[i] = move.Position
+	}
+	writeReplay(w, r, replayRequest)
+}
+
+// MovesHandler plays a move in a game against the AI on
+// POST /v1/games/{id}/moves.
+func (api *GamesAPI) MovesHandler(w http.ResponseWriter, r *http.Request) {
+	g, ok := api.game(w, r)
+	if !ok {
+		return
+	}
+	var moveRequest model.GameMoveRequest
+	if apiErr := decodeJSON(w, r, &moveRequest, false); apiErr != nil {
+		writeError(w, r, apiErr)
+		return
+	}
+	writeGameResult(w, r, g, g.PlayAgainstAI(r.Context(), moveRequest.Position))
+}
+
+// UndoHandler takes back a move in a game against the AI on
+// POST /v1/games/{id}/undo.
+func (api *GamesAPI) UndoHandler(w http.ResponseWriter, r *http.Request) {
+	if g, ok := api.game(w, r); ok {
+		writeGameResult(w, r, g, g.Undo())
+	}
+}
+
+// game returns the game named by the id path parameter, or responds with
+// ErrGameNotFound.
+func (api *GamesAPI) game(w http.ResponseWriter, r *http.Request) (*session.Game, bool) {
+	g, ok := api.store.Get(router.Param(r, "id"))
+	if !ok {
+		writeError(w, r, ErrGameNotFound)
+	}
+	return g, ok
+}
+
+// ImportHandler validates a game record by replaying it through the game
+// rules and stores it as a new game, on POST /v1/games/import.
+func (api *GamesAPI) ImportHandler(w http.ResponseWriter, r *http.Request) {
+	// The record is read whole first, as a cut-off record would fail to parse
+	limitBody(w, r)
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, r, decodeError(err, nil))
+		return
+	}
+	record, err := notation.Parse(bytes.NewReader(data))
+	if err != nil {
+		writeError(w, r, ErrInvalidRecord.WithMessage("Invalid game record: "+err.Error()))
+		return
+	}
+
+	if !api.limits.IsBoardSize(record.BoardSize) {
+		writeError(w, r, boardSizeError(api.limits, ErrInvalidBoardSize))
+		return
+	}
+	if record.WinLength != record.BoardSize {
+		writeError(w, r, ErrInvalidRecord.WithMessage(INVALID_WIN_LENGTH))
+		return
+	}
+
+	options := session.Options{
+		BoardSize:    record.BoardSize,
+		Mode:         model.GameModeHuman,
+		DisplayStyle: r.URL.Query().Get("displayStyle"),
+		SearchDepth:  api.limits.SearchDepth,
+	}
+	if _, ok := game.LookupRenderer(options.DisplayStyle); !ok {
+		writeError(w, r, ErrInvalidDisplayStyle)
+		return
+	}
+	switch {
+	case record.X == notation.AIName && record.O == notation.AIName:
+		writeError(w, r, ErrInvalidRecord.WithMessage(INVALID_RECORD_PLAYERS))
+		return
+	case record.X == notation.AIName:
+		options.Mode = model.GameModeAI
+		options.AIPlayer = game.XPlayer
+	case record.O == notation.AIName:
+		options.Mode = model.GameModeAI
+		options.AIPlayer = game.OPlayer
+	}
+	options.Difficulty, err = api.limits.ParseDifficulty(record.Difficulty)
+	if err != nil {
+		writeError(w, r, difficultyError(api.limits, ErrInvalidDifficulty))
+		return
+	}
+
+	createdAt := record.Date
+	if createdAt.IsZero() {
+		createdAt = time.Now().UTC()
+	}
+
+	g, err := api.store.Import(r.Context(), options, record.Moves(), notation.StatusFromResult(record.Result), createdAt)
+	if errors.Is(err, session.ErrTooManyGames) || isSearchStopped(err) {
+		writeError(w, r, gameError(err))
+		return
+	}
+	if err != nil {
+		writeError(w, r, ErrInvalidRecord.WithMessage("Invalid game record: "+err.Error()))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/v1/games/"+g.ID())
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(g.Record())
+}
+
+// writeGameResult responds with the game record, or with the error of the
+// action performed on the game.
+func writeGameResult(w http.ResponseWriter, r *http.Request, g *session.Game, err error) {
+	if err != nil {
+		writeError(w, r, gameError(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(g.Record())
+}
+
+func (api *GamesAPI) play(w http.ResponseWriter, r *http.Request, g *session.Game) {
+	player, err := g.Join()
+	if err != nil {
+		writeError(w, r, gameError(err))
+		return
+	}
+	defer g.Leave(player)
+
+	conn, err := websocket.Upgrade(w, r)
+	if err != nil {
+		log.Printf("websocket upgrade for game %s: %v", g.ID(), err)
+		return
+	}
+	defer conn.Close()
+	stop := conn.KeepAlive(wsPingPeriod, wsPongWait)
+	defer stop()
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-api.closing:
+			conn.WriteClose(websocket.CloseGoingAway, "server shutting down")
+			conn.SetReadDeadline(time.Now().Add(wsCloseWait))
+		case <-done:
+		}
+	}()
+
+	events, unsubscribe := g.Subscribe()
+	defer unsubscribe()
+
+	locale := requestLocale(r)
+	record := g.Record()
+	if err := conn.WriteJSON(model.GameMessage{Type: model.MessageTypeJoined, Player: player, Game: &record}); err != nil {
+		return
+	}
+
+	go func() {
+		for event := range events {
+			event := event
+			message := model.GameMessage{
+				Type:     event.Type,
+				Player:   event.Player,
+				Position: event.Position,
+				Game:     &event.Game,
+			}
+			if err := conn.WriteJSON(message); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		var message model.GameMessage
+		if err := conn.ReadJSON(&message); err != nil {
+			var syntaxErr *json.SyntaxError
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+				writeGameMessageError(conn, locale, ErrInvalidRequestBody)
+				continue
+			}
+			return
+		}
+
+		if message.Type != model.MessageTypeMove {
+			writeGameMessageError(conn, locale, ErrInvalidGameAction)
+			continue
+		}
+
+		if err := g.Play(player, message.Position); err != nil {
+			writeGameMessageError(conn, locale, gameError(err))
+		}
+	}
+}
+
+func writeGameMessageError(conn *websocket.Conn, locale string, apiErr *APIError) error {
+	return conn.WriteJSON(model.GameMessage{Type: model.MessageTypeError, Code: apiErr.Code, Message: localizedMessage(locale, apiErr)})
+}
+
+// spectate streams the game's moves and takebacks as Server-Sent Events. A
+// reconnecting client sends the Last-Event-ID header (or lastEventId query
+// parameter) and receives the events it missed from the event log before the
+// live ones.
+func (api *GamesAPI) spectate(w http.ResponseWriter, r *http.Request, g *session.Game) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, r, ErrInternal.WithMessage("Streaming not supported."))
+		return
+	}
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("lastEventId")
+	}
+	lastSent, err := strconv.Atoi(lastEventID)
+	if err != nil || lastSent < 0 {
+		lastSent = 0
+	}
+
+	record, history, events, unsubscribe := g.Watch()
+	defer unsubscribe()
+
+	lastID := 0
+	if len(history) > 0 {
+		lastID = history[len(history)-1].ID
+	}
+	// Tell a reconnecting EventSource that a finished game has nothing more to send.
+	if lastEventID != "" && lastSent == lastID && record.GameStatus != model.GameStatusOngoing {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	// The stream outlasts the read and write timeouts of the server
+	controller := http.NewResponseController(w)
+	controller.SetReadDeadline(time.Time{})
+	controller.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", sseRetry.Milliseconds())
+
+	// An id from the future belongs to another game or server
+	if lastSent > lastID {
+		lastSent = 0
+	}
+	for _, event := range replayEvents(record.BoardSize, history, game.LookupRendererOrDefault(record.DisplayStyle)) {
+		if event.ID > lastSent {
+			writeSSE(w, event.ID, event.Type, event.GameEvent)
+		}
+	}
+	lastSent = lastID
+	if record.GameStatus != model.GameStatusOngoing {
+		writeSSE(w, lastSent, "status", model.GameEvent{Move: len(record.Moves), GameStatus: record.GameStatus, NextPlayer: -1})
+		flusher.Flush()
+		return
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-api.closing:
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case event, ok := <-events:
+			if !ok {
+				// Dropped for falling behind; the client reconnects and replays.
+				return
+			}
+			if event.ID <= lastSent {
+				continue
+			}
+
+			lastSent = event.ID
+			writeSSE(w, event.ID, event.Type, model.GameEvent{
+				Move:         event.Move,
+				Player:       event.Player,
+				Position:     event.Position,
+				Board:        event.Game.Board,
+				BoardDisplay: event.Game.BoardDisplay,
+				GameStatus:   event.Game.GameStatus,
+				NextPlayer:   event.Game.NextPlayer,
+			})
+			if event.Game.GameStatus != model.GameStatusOngoing {
+				writeSSE(w, lastSent, "status", model.GameEvent{Move: event.Move, GameStatus: event.Game.GameStatus, NextPlayer: -1})
+				flusher.Flush()
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func writeSSE(w io.Writer, id int, event string, data interface{}) {
+	payload, _ := json.Marshal(data)
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", id, event, payload)
+}
+
+// streamEvent is a move or takeback of the event stream of a game.
+type streamEvent struct {
+	ID   int
+	Type string
+	model.GameEvent
+}
+
+// replayEvents rebuilds the board after every move and takeback of the event
+// log of a game.
+func replayEvents(boardSize int, history []session.Event, renderer game.Renderer) []streamEvent {
+	var moves []model.Move
+	events := make([]streamEvent, 0, len(history))
+
+	for _, event := range history {
+		if event.Type == model.MessageTypeUndo {
+			moves = moves[:event.Move]
+		} else {
+			moves = append(moves, model.Move{Player: event.Player, Position: event.Position})
+		}
+
+		board := make([]int, boardSize*boardSize)
+		nextPlayer := game.XPlayer
+		for _, move := range moves {
+			board[move.Position-1] = move.Player
+			nextPlayer = game.GetOponent(move.Player)
+		}
+		status := game.NewGameState(board, boardSize, nextPlayer).Status()
+		if status != model.GameStatusOngoing {
+			nextPlayer = -1
+		}
+
+		events = append(events, streamEvent{
+			ID:   event.ID,
+			Type: event.Type,
+			GameEvent: model.GameEvent{
+				Move:         event.Move,
+				Player:       event.Player,
+				Position:     event.Position,
+				Board:        board,
+				BoardDisplay: renderer.Render(board, boardSize),
+				GameStatus:   status,
+				NextPlayer:   nextPlayer,
+			},
+		})
+	}
+
+	return events
+}
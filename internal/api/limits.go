@@ -0,0 +1,46 @@
+package api
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/isavita/tictactoe_api/internal/game"
+)
+
+// The board sizes and default difficulty are part of the game.Limits of the
+// APIs, so the errors that quote them are rewritten when they differ from the
+// defaults. The rewritten messages are in English only.
+
+// boardSizeError is apiErr, which rejects a board size, naming the sizes the
+// limits allow.
+func boardSizeError(limits game.Limits, apiErr *APIError) *APIError {
+	if limits.MinBoardSize == game.MinBoardSize && limits.MaxBoardSize == game.MaxBoardSize {
+		return apiErr
+	}
+	sizes := make([]string, 0, limits.MaxBoardSize-limits.MinBoardSize+1)
+	for size := limits.MinBoardSize; size <= limits.MaxBoardSize; size++ {
+		sizes = append(sizes, strconv.Itoa(size))
+	}
+	if len(sizes) == 1 {
+		return apiErr.WithMessage(fmt.Sprintf("The only supported boardSize value is %s.", sizes[0]))
+	}
+	list := strings.Join(sizes[:len(sizes)-1], ", ") + " and " + sizes[len(sizes)-1]
+	return apiErr.WithMessage(fmt.Sprintf("The supported boardSize values are %s.", list))
+}
+
+// difficultyNames are the names of the public difficulty levels.
+var difficultyNames = map[int]string{1: "Easy", 2: "Medium", 3: "Hard"}
+
+// difficultyError is ErrInvalidDifficulty or ErrInvalidDifficultyV2 naming
+// the default difficulty of the limits.
+func difficultyError(limits game.Limits, apiErr *APIError) *APIError {
+	level := limits.DefaultDifficulty
+	if level == game.DefaultLimits.DefaultDifficulty {
+		return apiErr
+	}
+	if apiErr == ErrInvalidDifficultyV2 {
+		return apiErr.WithMessage(fmt.Sprintf("Invalid difficulty: Use \"easy\", \"medium\" or \"hard\". Default is %q.", strings.ToLower(difficultyNames[level])))
+	}
+	return apiErr.WithMessage(fmt.Sprintf("Invalid difficulty: Use 1 (Easy), 2 (Medium), or 3 (Hard). Default is %d (%s) if not provided.", level, difficultyNames[level]))
+}
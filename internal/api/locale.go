@@ -0,0 +1,43 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/isavita/tictactoe_api/internal/i18n"
+)
+
+type localeKey struct{}
+
+// withLocale records the locale field of a request body so that later
+// responses, including errors, use it instead of Accept-Language. Unsupported
+// locales are ignored.
+func withLocale(r *http.Request, locale string) *http.Request {
+	matched, ok := i18n.Match(locale)
+	if !ok {
+		return r
+	}
+	return r.WithContext(context.WithValue(r.Context(), localeKey{}, matched))
+}
+
+// requestLocale returns the locale set by withLocale or else the one
+// negotiated from the Accept-Language header.
+func requestLocale(r *http.Request) string {
+	if locale, ok := r.Context().Value(localeKey{}).(string); ok {
+		return locale
+	}
+	return i18n.Negotiate(r.Header.Get("Accept-Language"))
+}
+
+// localizedMessage translates the message of an API error. A message made
+// more specific with WithMessage has no translation and stays in English.
+func localizedMessage(locale string, apiErr *APIError) string {
+	key, ok := errorKeys[apiErr]
+	if !ok {
+		return apiErr.Message
+	}
+	if message, ok := i18n.Lookup(locale, key, nil); ok {
+		return message
+	}
+	return apiErr.Message
+}
@@ -0,0 +1,320 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/isavita/tictactoe_api/internal/game"
+	"github.com/isavita/tictactoe_api/internal/model"
+	"github.com/isavita/tictactoe_api/internal/render"
+)
+
+const (
+	FORMAT_SVG  = "svg"
+	FORMAT_PNG  = "png"
+	FORMAT_ANSI = "ansi"
+)
+
+// RenderHandler draws a board as an image. GET takes the board from the
+// query (position, or board as comma-separated cells with boardSize, and
+// lastMove, format, cellSize and theme) and POST from a model.RenderRequest
+// body. The format defaults to the Accept header and then to SVG.
+func RenderHandler(w http.ResponseWriter, r *http.Request) {
+	var renderRequest model.RenderRequest
+
+	if r.Method == http.MethodPost {
+		if apiErr := decodeJSON(w, r, &renderRequest, false); apiErr != nil {
+			writeError(w, r, apiErr)
+			return
+		}
+	} else {
+		var apiErr *APIError
+		renderRequest, apiErr = renderRequestFromQuery(r)
+		if apiErr != nil {
+			writeError(w, r, apiErr)
+			return
+		}
+	}
+
+	// Images can be drawn for every board size, whatever the game limits
+	if _, apiErr := resolveBoard(game.DefaultLimits, &renderRequest.Board, &renderRequest.BoardSize, renderRequest.Position); apiErr != nil {
+		writeError(w, r, apiErr)
+		return
+	}
+
+	if renderRequest.LastMove < 0 || renderRequest.LastMove > len(renderRequest.Board) {
+		writeError(w, r, ErrInvalidLastMove)
+		return
+	}
+
+	if renderRequest.Format == "" {
+		renderRequest.Format = imageFormat(r)
+	}
+	if renderRequest.Format == "" {
+		renderRequest.Format = FORMAT_SVG
+	}
+	if renderRequest.Format != FORMAT_SVG && renderRequest.Format != FORMAT_PNG {
+		writeError(w, r, ErrInvalidFormat)
+		return
+	}
+
+	options, apiErr := renderOptions(renderRequest.CellSize, renderRequest.Theme)
+	if apiErr != nil {
+		writeError(w, r, apiErr)
+		return
+	}
+	options.LastMove = renderRequest.LastMove - 1
+
+	writeImage(w, r, renderRequest.Format, renderRequest.Board, renderRequest.BoardSize, options)
+}
+
+func renderRequestFromQuery(r *http.Request) (model.RenderRequest, *APIError) {
+	query := r.URL.Query()
+	renderRequest := model.RenderRequest{
+		Position: query.Get("position"),
+		Format:   query.Get("format"),
+		Theme:    query.Get("theme"),
+	}
+
+	for _, param := range []struct {
+		name  string
+		field *int
+	}{
+		{"boardSize", &renderRequest.BoardSize},
+		{"lastMove", &renderRequest.LastMove},
+		{"cellSize", &renderRequest.CellSize},
+	} {
+		if apiErr := queryInt(query, param.name, param.field); apiErr != nil {
+			return renderRequest, apiErr
+		}
+	}
+
+	var apiErr *APIError
+	renderRequest.Board, apiErr = queryInts(query, "board")
+	return renderRequest, apiErr
+}
+
+func renderOptions(cellSize int, themeName string) (render.Options, *APIError) {
+	if cellSize != 0 && (cellSize < render.MinCellSize || cellSize > render.MaxCellSize) {
+		return render.Options{}, ErrInvalidCellSize
+	}
+
+	theme, ok := render.LookupTheme(themeName)
+	if !ok {
+		return render.Options{}, ErrInvalidTheme
+	}
+
+	return render.Options{CellSize: cellSize, Theme: &theme}, nil
+}
+
+func writeImage(w http.ResponseWriter, r *http.Request, format string, board []int, boardSize int, options render.Options) {
+	if format == FORMAT_PNG {
+		image, err := render.PNG(board, boardSize, options)
+		if err != nil {
+			log.Printf("render png: %v", err)
+			writeError(w, r, ErrInternal)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(image)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/svg+xml")
+	w.Write(render.SVG(board, boardSize, options))
+}
+
+// ReplayHandler draws an animated GIF of a game from its move list. GET takes
+// the moves as comma-separated cells together with boardSize, delay, cellSize
+// and theme from the query, and POST a model.ReplayRequest body.
+func ReplayHandler(w http.ResponseWriter, r *http.Request) {
+	var replayRequest model.ReplayRequest
+
+	if r.Method == http.MethodPost {
+		if apiErr := decodeJSON(w, r, &replayRequest, false); apiErr != nil {
+			writeError(w, r, apiErr)
+			return
+		}
+	} else {
+		var apiErr *APIError
+		replayRequest, apiErr = replayRequestFromQuery(r)
+		if apiErr != nil {
+			writeError(w, r, apiErr)
+			return
+		}
+	}
+
+	if replayRequest.BoardSize == 0 {
+		replayRequest.BoardSize = 3
+	}
+	// Images can be drawn of every board the engine knows
+	if replayRequest.BoardSize > game.MaxBoardSize || replayRequest.BoardSize < game.MinBoardSize {
+		writeError(w, r, ErrInvalidBoardSize)
+		return
+	}
+
+	if apiErr := checkMoves(replayRequest.Moves, replayRequest.BoardSize); apiErr != nil {
+		writeError(w, r, apiErr)
+		return
+	}
+
+	writeReplay(w, r, replayRequest)
+}
+
+func replayRequestFromQuery(r *http.Request) (model.ReplayRequest, *APIError) {
+	query := r.URL.Query()
+	replayRequest := model.ReplayRequest{Theme: query.Get("theme")}
+
+	for _, param := range []struct {
+		name  string
+		field *int
+	}{
+		{"boardSize", &replayRequest.BoardSize},
+		{"delay", &replayRequest.Delay},
+		{"cellSize", &replayRequest.CellSize},
+	} {
+		if apiErr := queryInt(query, param.name, param.field); apiErr != nil {
+			return replayRequest, apiErr
+		}
+	}
+
+	var apiErr *APIError
+	replayRequest.Moves, apiErr = queryInts(query, "moves")
+	return replayRequest, apiErr
+}
+
+// queryInt parses the query parameter name into n, which is left as it is
+// when the parameter is missing.
+func queryInt(query url.Values, name string, n *int) *APIError {
+	value := query.Get(name)
+	if value == "" {
+		return nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return invalidQuery(name, value)
+	}
+	*n = parsed
+	return nil
+}
+
+// queryInts parses the comma-separated numbers of the query parameter name,
+// or returns nil when the parameter is missing.
+func queryInts(query url.Values, name string) ([]int, *APIError) {
+	value := query.Get(name)
+	if value == "" {
+		return nil, nil
+	}
+	var numbers []int
+	for _, item := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(item))
+		if err != nil {
+			return nil, invalidQuery(name, item)
+		}
+		numbers = append(numbers, n)
+	}
+	return numbers, nil
+}
+
+// invalidQuery is ErrInvalidQuery naming the query parameter and the value
+// that is not a number.
+func invalidQuery(name string, value string) *APIError {
+	apiErr := ErrInvalidQuery.WithMessage(fmt.Sprintf("Invalid query parameter %s: %q is not a number.", name, value))
+	apiErr.Field = name
+	return apiErr
+}
+
+// checkMoves plays the moves on an empty board and reports the first one that
+// breaks the rules.
+func checkMoves(moves []int, boardSize int) *APIError {
+	state := game.NewGameState(make([]int, boardSize*boardSize), boardSize, game.XPlayer)
+	for i, move := range moves {
+		if state.Status() != model.GameStatusOngoing || !state.Play(move-1) {
+			return ErrInvalidMoves.WithMessage(fmt.Sprintf("%s Move %d is not allowed.", INVALID_MOVES, i+1))
+		}
+		state.NextTurn()
+	}
+	return nil
+}
+
+// writeReplay writes the GIF of moves that have already been checked.
+func writeReplay(w http.ResponseWriter, r *http.Request, replayRequest model.ReplayRequest) {
+	delay := render.DefaultFrameDelay
+	if replayRequest.Delay != 0 {
+		delay = time.Duration(replayRequest.Delay) * time.Millisecond
+	}
+	if delay < render.MinFrameDelay || delay > render.MaxFrameDelay {
+		writeError(w, r, ErrInvalidDelay)
+		return
+	}
+
+	options, apiErr := renderOptions(replayRequest.CellSize, replayRequest.Theme)
+	if apiErr != nil {
+		writeError(w, r, apiErr)
+		return
+	}
+
+	if render.GIFPixels(len(replayRequest.Moves), replayRequest.BoardSize, options) > render.MaxGIFPixels {
+		writeError(w, r, ErrReplayTooLarge)
+		return
+	}
+
+	moves := make([]int, len(replayRequest.Moves))
+	for i, move := range replayRequest.Moves {
+		moves[i] = move - 1
+	}
+
+	image, err := render.GIFContext(r.Context(), moves, replayRequest.BoardSize, options, delay)
+	if isSearchStopped(err) {
+		writeError(w, r, ErrRenderTimeout)
+		return
+	}
+	if err != nil {
+		log.Printf("render gif: %v", err)
+		writeError(w, r, ErrInternal)
+		return
+	}
+	w.Header().Set("Content-Type", "image/gif")
+	w.Write(image)
+}
+
+// imageFormat returns the image format asked for by the format query
+// parameter or the Accept header, or "" when the client did not ask for one.
+func imageFormat(r *http.Request) string {
+	switch format := r.URL.Query().Get("format"); format {
+	case FORMAT_SVG, FORMAT_PNG:
+		return format
+	}
+
+	if acceptsMediaType(r, "image/svg+xml") {
+		return FORMAT_SVG
+	}
+	if acceptsMediaType(r, "image/png") {
+		return FORMAT_PNG
+	}
+	return ""
+}
+
+// wantsANSI reports whether the client asked for the coloured text board with
+// the format query parameter, or with an Accept header that lists text/plain
+// but not JSON.
+func wantsANSI(r *http.Request) bool {
+	if r.URL.Query().Get("format") == FORMAT_ANSI {
+		return true
+	}
+	return acceptsMediaType(r, "text/plain") && !acceptsJSON(r)
+}
+
+// writeANSI writes the board of a move response for a terminal, followed by
+// the message and a status line.
+func writeANSI(w http.ResponseWriter, moveResponse model.MoveResponse, lastMove int) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprint(w, render.ANSI(moveResponse.Board, moveResponse.BoardSize, render.Options{LastMove: lastMove}))
+	fmt.Fprintln(w, moveResponse.Message)
+	fmt.Fprintln(w, moveResponse.StatusText)
+}
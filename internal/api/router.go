@@ -0,0 +1,48 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/isavita/tictactoe_api/internal/router"
+)
+
+// NewRouter returns a router whose 404 and 405 responses are API errors.
+// Under /v1/ they follow the Accept header like every v1 error; elsewhere they
+// are always the JSON envelope.
+func NewRouter() *router.Router {
+	rt := router.New()
+	rt.NotFound = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeRouteError(w, r, ErrNotFound)
+	})
+	rt.MethodNotAllowed = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeRouteError(w, r, ErrMethodNotAllowed)
+	})
+	return rt
+}
+
+// RateLimited responds to requests over their rate limit with the
+// rate_limited API error.
+func RateLimited(w http.ResponseWriter, r *http.Request) {
+	writeRouteError(w, r, ErrRateLimited)
+}
+
+// Unauthorized responds to requests without a valid API key with the
+// unauthorized API error.
+func Unauthorized(w http.ResponseWriter, r *http.Request) {
+	writeRouteError(w, r, ErrUnauthorized)
+}
+
+// Forbidden responds to requests whose API key does not allow the endpoint
+// with the feature_not_allowed API error.
+func Forbidden(w http.ResponseWriter, r *http.Request) {
+	writeRouteError(w, r, ErrFeatureNotAllowed)
+}
+
+func writeRouteError(w http.ResponseWriter, r *http.Request, apiErr *APIError) {
+	if strings.HasPrefix(r.URL.Path, "/v1/") {
+		writeError(w, r, apiErr)
+		return
+	}
+	writeJSONError(w, r, apiErr)
+}
@@ -0,0 +1,219 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/isavita/tictactoe_api/internal/game"
+	"github.com/isavita/tictactoe_api/internal/i18n"
+	"github.com/isavita/tictactoe_api/internal/model"
+)
+
+const (
+	INVALID_CELLS_V2        = "Invalid board.cells: Must have board.size² cells, each \"x\", \"o\" or \"\" (empty), listed row by row from the top left."
+	ILLEGAL_PIECE_COUNT_V2  = "Illegal piece count: The side that moved first must have as many pieces as the other side or one more."
+	AMBIGUOUS_BOARD_V2      = "Send either board or position, not both."
+	INVALID_AI_PLAYER_V2    = "Invalid aiPlayer: Use \"x\" or \"o\" for the side the AI plays."
+	INVALID_FIRST_PLAYER_V2 = "Invalid firstPlayer: Use \"x\" or \"o\". Default is \"x\"."
+	INVALID_DIFFICULTY_V2   = "Invalid difficulty: Use \"easy\", \"medium\" or \"hard\". Default is \"hard\"."
+)
+
+var (
+	ErrInvalidBoardSizeV2   = &APIError{http.StatusBadRequest, "invalid_board_size", INVALID_BOARD_SIZE, "board.size"}
+	ErrInvalidCellsV2       = &APIError{http.StatusBadRequest, "invalid_cells", INVALID_CELLS_V2, "board.cells"}
+	ErrIllegalPieceCountV2  = &APIError{http.StatusBadRequest, "illegal_piece_count", ILLEGAL_PIECE_COUNT_V2, "board.cells"}
+	ErrAmbiguousBoardV2     = &APIError{http.StatusBadRequest, "ambiguous_board", AMBIGUOUS_BOARD_V2, "position"}
+	ErrInvalidAIPlayerV2    = &APIError{http.StatusBadRequest, "invalid_ai_player", INVALID_AI_PLAYER_V2, "aiPlayer"}
+	ErrInvalidFirstPlayerV2 = &APIError{http.StatusBadRequest, "invalid_first_player", INVALID_FIRST_PLAYER_V2, "firstPlayer"}
+	ErrInvalidDifficultyV2  = &APIError{http.StatusBadRequest, "invalid_difficulty", INVALID_DIFFICULTY_V2, "difficulty"}
+)
+
+var difficultiesV2 = map[string]int{
+	model.DifficultyV2Easy:   game.DifficultyEasy,
+	model.DifficultyV2Medium: game.DifficultyMedium,
+	model.DifficultyV2Hard:   game.DifficultyHard,
+}
+
+// MovesHandlerV2 serves POST /v2/moves: the AI plays one move on the
+// submitted board. Unlike v1 the AI's side must be given, a finished game is
+// an error and every error is a JSON envelope.
+func (api *TicTacToeAPI) MovesHandlerV2(w http.ResponseWriter, r *http.Request) {
+	var moveRequest model.MoveRequestV2
+	if apiErr := decodeJSON(w, r, &moveRequest, false); apiErr != nil {
+		writeJSONError(w, r, apiErr)
+		return
+	}
+	r = withLocale(r, moveRequest.Locale)
+	locale := requestLocale(r)
+
+	board, boardSize, apiErr := boardFromRequestV2(api.limits, moveRequest)
+	if apiErr != nil {
+		writeJSONError(w, r, apiErr)
+		return
+	}
+
+	aiPlayer, ok := playerFromSide(moveRequest.AIPlayer)
+	if !ok {
+		writeJSONError(w, r, ErrInvalidAIPlayerV2)
+		return
+	}
+	firstPlayer := game.XPlayer
+	if moveRequest.FirstPlayer != "" {
+		if firstPlayer, ok = playerFromSide(moveRequest.FirstPlayer); !ok {
+			writeJSONError(w, r, ErrInvalidFirstPlayerV2)
+			return
+		}
+	}
+	difficulty, ok := difficultyV2(api.limits, moveRequest.Difficulty)
+	if !ok {
+		writeJSONError(w, r, difficultyError(api.limits, ErrInvalidDifficultyV2))
+		return
+	}
+
+	currentPlayer, err := getCurrentPlayer(board, firstPlayer)
+	if err != nil {
+		writeJSONError(w, r, ErrIllegalPieceCountV2)
+		return
+	}
+	if moveRequest.Position != "" {
+		if _, _, sideToMove, _ := game.ParsePosition(moveRequest.Position); sideToMove != 0 && sideToMove != currentPlayer {
+			writeJSONError(w, r, ErrInvalidPosition)
+			return
+		}
+	}
+
+	state := game.NewGameState(board, boardSize, currentPlayer)
+	if state.Status() != model.GameStatusOngoing {
+		writeJSONError(w, r, ErrGameOver)
+		return
+	}
+	if aiPlayer != currentPlayer {
+		writeJSONError(w, r, ErrNotAITurn)
+		return
+	}
+
+	state.SetSearchDepth(api.limits.SearchDepth)
+	move, err := state.FindMoveContext(r.Context(), difficulty)
+	if err != nil && move == -1 {
+		writeJSONError(w, r, ErrSearchTimeout)
+		return
+	}
+	state.Play(move)
+
+	moveResponse := model.MoveResponseV2{
+		Move: model.MoveV2{
+			Player: sideName(aiPlayer),
+			Index:  move,
+			Row:    move / boardSize,
+			Column: move % boardSize,
+		},
+		Board:    boardV2(board, boardSize),
+		Position: game.FormatPosition(board, boardSize, 0),
+		Message: i18n.Text(locale, "v2.move.placed", map[string]any{
+			"Piece": game.PieceName(aiPlayer),
+			"Index": move,
+		}),
+	}
+
+	switch status := state.Status(); status {
+	case model.GameStatusOngoing:
+		moveResponse.Status = model.StatusV2Ongoing
+		moveResponse.ToMove = sideName(game.GetOponent(aiPlayer))
+		moveResponse.Position = game.FormatPosition(board, boardSize, game.GetOponent(aiPlayer))
+	case model.GameStatusDraw:
+		moveResponse.Status = model.StatusV2Draw
+	default:
+		moveResponse.Status = model.StatusV2Won
+		moveResponse.Winner = sideName(aiPlayer)
+		moveResponse.WinningLine = game.WinningLine(board, boardSize)
+	}
+
+	w.Header().Set("Content-Language", locale)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(moveResponse)
+}
+
+// difficultyV2 returns the engine difficulty of a v2 difficulty level, or
+// the default difficulty when there is none.
+func difficultyV2(limits game.Limits, level string) (int, bool) {
+	if level == "" {
+		difficulty, err := limits.ParseDifficulty(0)
+		return difficulty, err == nil
+	}
+	difficulty, ok := difficultiesV2[level]
+	return difficulty, ok
+}
+
+// boardFromRequestV2 returns the board of a v2 request, from either its
+// cells or its position string, as engine values.
+func boardFromRequestV2(limits game.Limits, moveRequest model.MoveRequestV2) ([]int, int, *APIError) {
+	if moveRequest.Position != "" {
+		if moveRequest.Board != nil {
+			return nil, 0, ErrAmbiguousBoardV2
+		}
+		board, boardSize, _, err := game.ParsePosition(moveRequest.Position)
+		if err != nil || !limits.IsBoardSize(boardSize) {
+			return nil, 0, ErrInvalidPosition
+		}
+		return board, boardSize, nil
+	}
+
+	requestBoard := model.BoardV2{Size: limits.DefaultBoardSize}
+	if moveRequest.Board != nil {
+		requestBoard = *moveRequest.Board
+	}
+	if requestBoard.Size == 0 {
+		requestBoard.Size = limits.DefaultBoardSize
+	}
+	if !limits.IsBoardSize(requestBoard.Size) {
+		return nil, 0, boardSizeError(limits, ErrInvalidBoardSizeV2)
+	}
+
+	board := make([]int, requestBoard.Size*requestBoard.Size)
+	if requestBoard.Cells == nil {
+		return board, requestBoard.Size, nil
+	}
+	if len(requestBoard.Cells) != len(board) {
+		return nil, 0, ErrInvalidCellsV2
+	}
+	for i, cell := range requestBoard.Cells {
+		if cell == "" {
+			continue
+		}
+		player, ok := playerFromSide(cell)
+		if !ok {
+			return nil, 0, ErrInvalidCellsV2
+		}
+		board[i] = player
+	}
+
+	return board, requestBoard.Size, nil
+}
+
+func boardV2(board []int, boardSize int) model.BoardV2 {
+	cells := make([]string, len(board))
+	for i, cell := range board {
+		if cell != 0 {
+			cells[i] = sideName(cell)
+		}
+	}
+	return model.BoardV2{Size: boardSize, Cells: cells}
+}
+
+func playerFromSide(side string) (int, bool) {
+	switch side {
+	case model.SideX:
+		return game.XPlayer, true
+	case model.SideO:
+		return game.OPlayer, true
+	default:
+		return 0, false
+	}
+}
+
+func sideName(player int) string {
+	if player == game.OPlayer {
+		return model.SideO
+	}
+	return model.SideX
+}
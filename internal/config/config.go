@@ -0,0 +1,409 @@
+// Package config loads the configuration of the server from its defaults, an
+// optional JSON or YAML file, environment variables and command-line flags,
+// each overriding the one before.
+//
+// Every setting is a flag, e.g. -max-board-size. Its environment variable is
+// the flag name in upper case with underscores, MAX_BOARD_SIZE, and its key
+// in the config file is the name in camel case, maxBoardSize. Keys may also
+// be grouped by their first word:
+//
+//	rateLimit: 2
+//	cors:
+//	  allowedOrigins: [https://app.example.com]
+//	  maxAge: 1h
+//
+// Lists are comma-separated in flags and environment variables, and arrays
+// in files. Durations are written like 5s or 1m30s. The file is named with
+// the -config flag or CONFIG_FILE and read as YAML when it ends in .yaml or
+// .yml, and as JSON otherwise.
+//
+// The API keys and the OpenAI verification token are secrets and are only
+// read from the environment, so that they stay out of files and process
+// lists.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/isavita/tictactoe_api/internal/game"
+	"github.com/isavita/tictactoe_api/internal/middleware"
+	"github.com/isavita/tictactoe_api/internal/session"
+	"github.com/isavita/tictactoe_api/internal/yaml"
+)
+
+// The defaults of the settings.
+const (
+	DEFAULT_PORT = "8080"
+	// DEFAULT_PUBLIC_URL is advertised in the plugin manifest and the OpenAPI
+	// document.
+	DEFAULT_PUBLIC_URL = "https://api.ludum.dev"
+	// DEFAULT_RATE_LIMIT is the number of tokens a client regains per second.
+	// Rate limiting is off by default, as the plugin's requests all come from
+	// a few OpenAI addresses and would share their buckets.
+	DEFAULT_RATE_LIMIT = 0
+	// DEFAULT_MAX_COMPUTE_TIME is how long the AI may search for a move.
+	DEFAULT_MAX_COMPUTE_TIME = 5 * time.Second
+	// DEFAULT_SHUTDOWN_TIMEOUT is how long the server waits for in-flight
+	// requests when it is told to stop.
+	DEFAULT_SHUTDOWN_TIMEOUT = 30 * time.Second
+
+	// The timeouts of the server. Reads are short as request bodies are
+	// small; writes also allow for the compute time of an AI move.
+	DEFAULT_READ_HEADER_TIMEOUT = 5 * time.Second
+	DEFAULT_READ_TIMEOUT        = 15 * time.Second
+	DEFAULT_WRITE_TIMEOUT       = 15 * time.Second
+	DEFAULT_IDLE_TIMEOUT        = 2 * time.Minute
+)
+
+// Config is the configuration of the server.
+type Config struct {
+	Port      string
+	PublicURL string
+	// WellKnownDir replaces the embedded plugin manifest, OpenAPI document
+	// and logo with the files of the directory when it is set.
+	WellKnownDir string
+
+	RateLimit      float64
+	RateLimitBurst int
+	TrustProxy     bool
+	APIKeysFile    string
+	LenientV1      bool
+
+	// MaxComputeTime stops the search for an AI move; zero means no limit.
+	MaxComputeTime    time.Duration
+	ShutdownTimeout   time.Duration
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	// WriteTimeout is the time to write a response on top of
+	// MaxComputeTime. There is no write timeout without a compute limit.
+	WriteTimeout time.Duration
+	IdleTimeout  time.Duration
+
+	// CORS is disabled unless CORSAllowedOrigins is set.
+	CORSAllowedOrigins []string
+	CORSAllowedMethods []string
+	CORSAllowedHeaders []string
+	CORSMaxAge         time.Duration
+
+	Game game.Limits
+	// Games bound the server-held games kept in memory.
+	Games session.StoreOptions
+
+	// APIKeys is the comma-separated list of API_KEYS.
+	APIKeys string
+	// OpenAIVerificationToken is the OPENAI_VERIFICATION_TOKEN of the plugin.
+	OpenAIVerificationToken string
+}
+
+// Default returns the configuration of a server without settings.
+func Default() Config {
+	return Config{
+		Port:              DEFAULT_PORT,
+		PublicURL:         DEFAULT_PUBLIC_URL,
+		RateLimit:         DEFAULT_RATE_LIMIT,
+		RateLimitBurst:    middleware.DefaultRateLimitBurst,
+		MaxComputeTime:    DEFAULT_MAX_COMPUTE_TIME,
+		ShutdownTimeout:   DEFAULT_SHUTDOWN_TIMEOUT,
+		ReadHeaderTimeout: DEFAULT_READ_HEADER_TIMEOUT,
+		ReadTimeout:       DEFAULT_READ_TIMEOUT,
+		WriteTimeout:      DEFAULT_WRITE_TIMEOUT,
+		IdleTimeout:       DEFAULT_IDLE_TIMEOUT,
+		CORSMaxAge:        middleware.DefaultCORSMaxAge,
+		Game:              game.DefaultLimits,
+		Games: session.StoreOptions{
+			IdleTimeout:     session.DefaultIdleTimeout,
+			FinishedTimeout: session.DefaultFinishedTimeout,
+			MaxGames:        session.DefaultMaxGames,
+		},
+	}
+}
+
+// newFlagSet binds the settings of c to flags. The config flag is bound to
+// configFile.
+func newFlagSet(c *Config, configFile *string) *flag.FlagSet {
+	flags := flag.NewFlagSet("server", flag.ContinueOnError)
+	flags.StringVar(configFile, "config", *configFile, "JSON or YAML `file` of settings")
+
+	flags.StringVar(&c.Port, "port", c.Port, "port to listen on")
+	flags.StringVar(&c.PublicURL, "public-url", c.PublicURL, "URL of the server advertised in the plugin manifest and the OpenAPI document")
+	flags.StringVar(&c.WellKnownDir, "well-known-dir", c.WellKnownDir, "`directory` of ai-plugin.json, openapi.yaml and logo.png to serve in place of the embedded files")
+
+	flags.Float64Var(&c.RateLimit, "rate-limit", c.RateLimit, "tokens a client regains per second, 0 to disable rate limiting")
+	flags.IntVar(&c.RateLimitBurst, "rate-limit-burst", c.RateLimitBurst, "tokens a client can spend at once")
+	flags.BoolVar(&c.TrustProxy, "trust-proxy", c.TrustProxy, "read client IPs from the X-Forwarded-For header of the proxy")
+	flags.StringVar(&c.APIKeysFile, "api-keys-file", c.APIKeysFile, "JSON `file` of API keys that clients must send as bearer tokens")
+	flags.BoolVar(&c.LenientV1, "lenient-v1", c.LenientV1, "ignore unknown fields and content types in the request bodies of v1")
+
+	flags.DurationVar(&c.MaxComputeTime, "max-compute-time", c.MaxComputeTime, "longest time the AI may search for a move, 0 for no limit")
+	flags.DurationVar(&c.ShutdownTimeout, "shutdown-timeout", c.ShutdownTimeout, "longest time to wait for in-flight requests on SIGTERM")
+	flags.DurationVar(&c.ReadHeaderTimeout, "read-header-timeout", c.ReadHeaderTimeout, "longest time to read the headers of a request")
+	flags.DurationVar(&c.ReadTimeout, "read-timeout", c.ReadTimeout, "longest time to read a whole request")
+	flags.DurationVar(&c.WriteTimeout, "write-timeout", c.WriteTimeout, "longest time to write a response, on top of the compute time")
+	flags.DurationVar(&c.IdleTimeout, "idle-timeout", c.IdleTimeout, "longest time to keep an idle connection open")
+
+	flags.Var((*listValue)(&c.CORSAllowedOrigins), "cors-allowed-origins", "`origins` allowed to call the API from a browser, * for any")
+	flags.Var((*listValue)(&c.CORSAllowedMethods), "cors-allowed-methods", "`methods` allowed in cross-origin requests")
+	flags.Var((*listValue)(&c.CORSAllowedHeaders), "cors-allowed-headers", "`headers` allowed in cross-origin requests")
+	flags.DurationVar(&c.CORSMaxAge, "cors-max-age", c.CORSMaxAge, "longest time browsers may cache a preflight")
+
+	flags.IntVar(&c.Game.MinBoardSize, "min-board-size", c.Game.MinBoardSize, "smallest board to play on, from 3")
+	flags.IntVar(&c.Game.MaxBoardSize, "max-board-size", c.Game.MaxBoardSize, "largest board to play on, up to 6")
+	flags.IntVar(&c.Game.DefaultBoardSize, "default-board-size", c.Game.DefaultBoardSize, "board size of requests without one")
+	flags.IntVar(&c.Game.DefaultDifficulty, "default-difficulty", c.Game.DefaultDifficulty, "difficulty of requests without one: 1 (Easy), 2 (Medium) or 3 (Hard)")
+	flags.IntVar(&c.Game.SearchDepth, "search-depth", c.Game.SearchDepth, "moves the hard AI looks ahead on boards larger than 3x3, up to 8")
+
+	flags.DurationVar(&c.Games.IdleTimeout, "game-idle-timeout", c.Games.IdleTimeout, "longest time to keep an unfinished game that nobody is connected to")
+	flags.DurationVar(&c.Games.FinishedTimeout, "game-finished-timeout", c.Games.FinishedTimeout, "longest time to keep a finished game that nobody is connected to")
+	flags.IntVar(&c.Games.MaxGames, "max-games", c.Games.MaxGames, "most games to keep, dropping the longest idle one for a new game")
+	return flags
+}
+
+// Load reads the configuration from the config file, the environment and the
+// command-line arguments, and validates it.
+func Load(args []string) (Config, error) {
+	// The arguments are parsed twice: first to find the config file, then
+	// again over the file and the environment. The first pass starts from
+	// the defaults so that -help shows them.
+	var configFile string
+	scratch := Default()
+	argFlags := newFlagSet(&scratch, &configFile)
+	if err := argFlags.Parse(args); err != nil {
+		return Config{}, err
+	}
+	if argFlags.NArg() > 0 {
+		return Config{}, fmt.Errorf("unexpected argument %q", argFlags.Arg(0))
+	}
+	if configFile == "" {
+		configFile = os.Getenv("CONFIG_FILE")
+	}
+
+	c := Default()
+	flags := newFlagSet(&c, new(string))
+	if configFile != "" {
+		if err := loadFile(flags, configFile); err != nil {
+			return Config{}, err
+		}
+	}
+
+	var err error
+	flags.VisitAll(func(f *flag.Flag) {
+		env := envName(f.Name)
+		if value, ok := os.LookupEnv(env); ok && value != "" && err == nil && f.Name != "config" {
+			err = set(flags, f.Name, value, env)
+		}
+	})
+	if err != nil {
+		return Config{}, err
+	}
+	argFlags.Visit(func(f *flag.Flag) {
+		if err == nil && f.Name != "config" {
+			err = set(flags, f.Name, f.Value.String(), "-"+f.Name)
+		}
+	})
+	if err != nil {
+		return Config{}, err
+	}
+
+	c.APIKeys = os.Getenv("API_KEYS")
+	c.OpenAIVerificationToken = os.Getenv("OPENAI_VERIFICATION_TOKEN")
+	return c, c.Validate()
+}
+
+// Validate reports the first setting that is out of range.
+func (c Config) Validate() error {
+	if port, err := strconv.Atoi(c.Port); err != nil || port < 0 || port > 65535 {
+		return fmt.Errorf("invalid port %q: use a number from 0 to 65535", c.Port)
+	}
+	if c.RateLimit < 0 || math.IsNaN(c.RateLimit) || math.IsInf(c.RateLimit, 0) {
+		return fmt.Errorf("invalid rate limit %v: use 0 or more tokens per second", c.RateLimit)
+	}
+	if c.RateLimitBurst < 1 {
+		return fmt.Errorf("invalid rate limit burst %d: use 1 or more tokens", c.RateLimitBurst)
+	}
+	for _, d := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"max compute time", c.MaxComputeTime},
+		{"shutdown timeout", c.ShutdownTimeout},
+		{"read header timeout", c.ReadHeaderTimeout},
+		{"read timeout", c.ReadTimeout},
+		{"write timeout", c.WriteTimeout},
+		{"idle timeout", c.IdleTimeout},
+		{"CORS max age", c.CORSMaxAge},
+	} {
+		if d.value < 0 {
+			return fmt.Errorf("invalid %s %v: use 0 or a positive duration", d.name, d.value)
+		}
+	}
+	if c.Games.IdleTimeout <= 0 || c.Games.FinishedTimeout <= 0 {
+		return fmt.Errorf("invalid game timeouts %v and %v: use positive durations", c.Games.IdleTimeout, c.Games.FinishedTimeout)
+	}
+	if c.Games.MaxGames < 1 {
+		return fmt.Errorf("invalid max games %d: use 1 or more games", c.Games.MaxGames)
+	}
+	return c.Game.Validate()
+}
+
+// set sets the flag to a value read from source, naming the source and the
+// kind of value the flag wants when the value is invalid.
+func set(flags *flag.FlagSet, name string, value string, source string) error {
+	if err := flags.Set(name, value); err != nil {
+		kind, _ := flag.UnquoteUsage(flags.Lookup(name))
+		switch kind {
+		case "":
+			kind = "true or false"
+		case "int":
+			kind = "a whole number"
+		case "float":
+			kind = "a number"
+		case "duration":
+			kind = "a duration such as 5s"
+		}
+		return fmt.Errorf("invalid %s %q: use %s", source, value, kind)
+	}
+	return nil
+}
+
+// loadFile sets the flags to the settings of a config file.
+func loadFile(flags *flag.FlagSet, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var settings any
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		settings, err = yaml.Unmarshal(data)
+	default:
+		err = json.Unmarshal(data, &settings)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	section, ok := settings.(map[string]any)
+	if !ok && settings != nil {
+		return fmt.Errorf("invalid config file %s: want a mapping of settings", path)
+	}
+
+	names := map[string]string{}
+	flags.VisitAll(func(f *flag.Flag) {
+		if f.Name != "config" {
+			names[keyName(f.Name)] = f.Name
+		}
+	})
+	return loadSection(flags, names, path, "", section)
+}
+
+// loadSection sets the flags to the settings of a section of a config file,
+// whose keys start with prefix.
+func loadSection(flags *flag.FlagSet, names map[string]string, path string, prefix string, section map[string]any) error {
+	// Sorted so that the first invalid setting is always the one reported
+	keys := make([]string, 0, len(section))
+	for key := range section {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if key == "" {
+			return fmt.Errorf("invalid config file %s: empty setting name", path)
+		}
+		name := key
+		if prefix != "" {
+			name = prefix + strings.ToUpper(key[:1]) + key[1:]
+		}
+		if subsection, ok := section[key].(map[string]any); ok {
+			if err := loadSection(flags, names, path, name, subsection); err != nil {
+				return err
+			}
+			continue
+		}
+		flagName, ok := names[name]
+		if !ok {
+			return fmt.Errorf("invalid config file %s: unknown setting %q", path, name)
+		}
+		if section[key] == nil {
+			continue
+		}
+		value, err := settingValue(section[key])
+		if err != nil {
+			return fmt.Errorf("invalid config file %s: %s %w", path, name, err)
+		}
+		if err := set(flags, flagName, value, fmt.Sprintf("%s in %s", name, path)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// settingValue writes a value of a config file as a flag value.
+func settingValue(value any) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			s, err := settingValue(item)
+			if _, nested := item.([]any); err != nil || nested {
+				return "", errors.New("is not a list of values")
+			}
+			items[i] = s
+		}
+		return strings.Join(items, ","), nil
+	default:
+		return "", errors.New("is not a value")
+	}
+}
+
+// envName is the environment variable of a flag, e.g. MAX_BOARD_SIZE for
+// max-board-size.
+func envName(flagName string) string {
+	return strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// keyName is the config file key of a flag, e.g. maxBoardSize for
+// max-board-size.
+func keyName(flagName string) string {
+	words := strings.Split(flagName, "-")
+	for i := 1; i < len(words); i++ {
+		words[i] = strings.ToUpper(words[i][:1]) + words[i][1:]
+	}
+	return strings.Join(words, "")
+}
+
+// listValue is a comma-separated list flag.
+type listValue []string
+
+func (l *listValue) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ",")
+}
+
+// Set replaces the list, dropping empty items.
+func (l *listValue) Set(s string) error {
+	*l = nil
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
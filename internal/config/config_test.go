@@ -0,0 +1,135 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	writeFile := func(t *testing.T, name string, data string) string {
+		path := filepath.Join(t.TempDir(), name)
+		if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	t.Run("defaults", func(t *testing.T) {
+		got, err := Load(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, Default()) {
+			t.Errorf("got %+v want %+v", got, Default())
+		}
+	})
+
+	t.Run("flags override the environment, which overrides the file", func(t *testing.T) {
+		path := writeFile(t, "server.yaml", `
+rateLimit: 2
+rateLimitBurst: 20
+maxComputeTime: 2s
+cors:
+  allowedOrigins: [https://app.example.com, http://localhost:3000]
+  maxAge: 1h
+maxBoardSize: 5
+`)
+		t.Setenv("CONFIG_FILE", path)
+		t.Setenv("RATE_LIMIT_BURST", "30")
+		t.Setenv("MAX_BOARD_SIZE", "4")
+		t.Setenv("API_KEYS", "secret-key")
+
+		got, err := Load([]string{"-max-board-size", "3", "-trust-proxy"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := Default()
+		want.RateLimit = 2
+		want.RateLimitBurst = 30
+		want.MaxComputeTime = 2 * time.Second
+		want.CORSAllowedOrigins = []string{"https://app.example.com", "http://localhost:3000"}
+		want.CORSMaxAge = time.Hour
+		want.Game.MaxBoardSize = 3
+		want.TrustProxy = true
+		want.APIKeys = "secret-key"
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %+v want %+v", got, want)
+		}
+	})
+
+	t.Run("JSON file named by a flag", func(t *testing.T) {
+		path := writeFile(t, "server.json", `{"port": 9090, "lenientV1": true, "corsAllowedMethods": ["GET", "POST"], "defaultDifficulty": 2}`)
+
+		got, err := Load([]string{"-config", path})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Port != "9090" || !got.LenientV1 || !reflect.DeepEqual(got.CORSAllowedMethods, []string{"GET", "POST"}) || got.Game.DefaultDifficulty != 2 {
+			t.Errorf("got %+v", got)
+		}
+	})
+
+	t.Run("invalid settings", func(t *testing.T) {
+		cases := []struct {
+			name    string
+			args    []string
+			env     map[string]string
+			file    string
+			wantErr string
+		}{
+			{"negative rate limit", []string{"-rate-limit", "-1"}, nil, "", "invalid rate limit -1"},
+			{"empty bucket", []string{"-rate-limit-burst", "0"}, nil, "", "invalid rate limit burst 0"},
+			{"invalid bool flag", []string{"-trust-proxy=maybe"}, nil, "", "trust-proxy"},
+			{"unknown flag", []string{"-board-size", "4"}, nil, "", "board-size"},
+			{"invalid environment variable", nil, map[string]string{"RATE_LIMIT": "fast"}, "", `invalid RATE_LIMIT "fast": use a number`},
+			{"invalid duration", nil, map[string]string{"CORS_MAX_AGE": "soon"}, "", `invalid CORS_MAX_AGE "soon": use a duration such as 5s`},
+			{"negative duration", []string{"-max-compute-time", "-1s"}, nil, "", "invalid max compute time -1s"},
+			{"port", nil, map[string]string{"PORT": "http"}, "", `invalid port "http"`},
+			{"board sizes", []string{"-min-board-size", "5", "-max-board-size", "4"}, nil, "", "invalid board sizes 5 to 4"},
+			{"default board size", []string{"-max-board-size", "4", "-default-board-size", "5"}, nil, "", "invalid default board size 5"},
+			{"default difficulty", []string{"-default-difficulty", "4"}, nil, "", "invalid default difficulty 4"},
+			{"search depth", []string{"-search-depth", "0"}, nil, "", "invalid search depth 0"},
+			{"deep search", []string{"-search-depth", "30"}, nil, "", "invalid search depth 30: use 1 to 8 moves"},
+			{"game timeout", []string{"-game-idle-timeout", "0s"}, nil, "", "invalid game timeouts 0s and 1h0m0s"},
+			{"max games", nil, map[string]string{"MAX_GAMES": "0"}, "", "invalid max games 0"},
+			{"unknown setting in file", nil, nil, "boardSize: 4\n", `unknown setting "boardSize"`},
+			{"secret in file", nil, nil, "apiKeys: secret-key\n", `unknown setting "apiKeys"`},
+			{"invalid value in file", nil, nil, "searchDepth: deep\n", `invalid searchDepth in`},
+			{"section as value", nil, nil, "corsAllowedOrigins:\n  first: x\n", `unknown setting "corsAllowedOriginsFirst"`},
+			{"malformed file", nil, nil, "rateLimit: [1\n", "invalid config file"},
+		}
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				for name, value := range tc.env {
+					t.Setenv(name, value)
+				}
+				args := tc.args
+				if tc.file != "" {
+					args = append([]string{"-config", writeFile(t, "server.yaml", tc.file)}, args...)
+				}
+
+				_, err := Load(args)
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Errorf("got error %v want it to contain %q", err, tc.wantErr)
+				}
+			})
+		}
+	})
+}
+
+func TestNames(t *testing.T) {
+	for flagName, want := range map[string][2]string{
+		"max-board-size":       {"MAX_BOARD_SIZE", "maxBoardSize"},
+		"lenient-v1":           {"LENIENT_V1", "lenientV1"},
+		"cors-allowed-origins": {"CORS_ALLOWED_ORIGINS", "corsAllowedOrigins"},
+		"port":                 {"PORT", "port"},
+	} {
+		if got := [2]string{envName(flagName), keyName(flagName)}; got != want {
+			t.Errorf("got %v for %s want %v", got, flagName, want)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package game
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Renderer draws a board as text for boardDisplay. Empty cells show their
+// number from 1 to n so that players can name the cell they want.
+type Renderer interface {
+	Render(board []int, boardSize int) string
+}
+
+const (
+	DisplayStyleClassic  = "classic"
+	DisplayStyleUnicode  = "unicode"
+	DisplayStyleEmoji    = "emoji"
+	DisplayStyleCompact  = "compact"
+	DisplayStyleMarkdown = "markdown"
+)
+
+var renderers = map[string]Renderer{
+	DisplayStyleClassic:  ClassicRenderer{},
+	DisplayStyleUnicode:  UnicodeRenderer{},
+	DisplayStyleEmoji:    EmojiRenderer{},
+	DisplayStyleCompact:  CompactRenderer{},
+	DisplayStyleMarkdown: MarkdownRenderer{},
+}
+
+// LookupRenderer returns the renderer for a display style; an empty style is
+// the classic one.
+func LookupRenderer(style string) (Renderer, bool) {
+	if style == "" {
+		style = DisplayStyleClassic
+	}
+	renderer, ok := renderers[style]
+	return renderer, ok
+}
+
+// LookupRendererOrDefault is like LookupRenderer but falls back to the classic
+// style for unknown styles.
+func LookupRendererOrDefault(style string) Renderer {
+	if renderer, ok := LookupRenderer(style); ok {
+		return renderer
+	}
+	return ClassicRenderer{}
+}
+
+// DisplayStyles lists the available display styles in alphabetical order.
+func DisplayStyles() []string {
+	styles := make([]string, 0, len(renderers))
+	for style := range renderers {
+		styles = append(styles, style)
+	}
+	sort.Strings(styles)
+	return styles
+}
+
+// ClassicRenderer draws the ASCII board of the first version of the API:
+//
+//	X | 2 | O
+//	---------
+//	4 | X | 6
+//	---------
+//	7 | 8 | O
+//
+// Boards bigger than 3x3 use wider cells so that two-digit numbers line up.
+type ClassicRenderer struct{}
+
+func (ClassicRenderer) Render(board []int, boardSize int) string {
+	if boardSize > 3 {
+		return classicWhenBig(board, boardSize)
+	}
+	return classicWhenSmall(board, boardSize)
+}
+
+func classicWhenSmall(board []int, size int) string {
+	var display strings.Builder
+
+	for i := 0; i < len(board); i++ {
+		if i > 0 && i%size == 0 {
+			display.WriteString("\n " + strings.Repeat("-", 4*size-3) + " \n")
+		}
+
+		display.WriteString(" " + cellLabel(board, i) + " ")
+
+		if i%size != size-1 {
+			display.WriteString("|")
+		}
+	}
+
+	return display.String()
+}
+
+func classicWhenBig(board []int, size int) string {
+	var display strings.Builder
+
+	for i := 0; i < len(board); i++ {
+		if i > 0 && i%size == 0 {
+			display.WriteString("\n")
+			display.WriteString(strings.Repeat("-----", size))
+			display.WriteString("\n")
+		}
+
+		display.WriteString(fmt.Sprintf(" %2s ", cellLabel(board, i)))
+
+		if i%size != size-1 {
+			display.WriteString("|")
+		}
+	}
+
+	return display.String()
+}
+
+// UnicodeRenderer draws a grid with box-drawing characters:
+//
+//	┌───┬───┬───┐
+//	│ X │ 2 │ O │
+//	├───┼───┼───┤
+//	│ 4 │ X │ 6 │
+//	├───┼───┼───┤
+//	│ 7 │ 8 │ O │
+//	└───┴───┴───┘
+type UnicodeRenderer struct{}
+
+func (UnicodeRenderer) Render(board []int, boardSize int) string {
+	width := len(strconv.Itoa(len(board)))
+	border := func(left, middle, right string) string {
+		return left + strings.Repeat(strings.Repeat("─", width+2)+middle, boardSize-1) + strings.Repeat("─", width+2) + right
+	}
+
+	rows := []string{border("┌", "┬", "┐")}
+	for row := 0; row < boardSize; row++ {
+		if row > 0 {
+			rows = append(rows, border("├", "┼", "┤"))
+		}
+		var line strings.Builder
+		for col := 0; col < boardSize; col++ {
+			fmt.Fprintf(&line, "│ %*s ", width, cellLabel(board, row*boardSize+col))
+		}
+		line.WriteString("│")
+		rows = append(rows, line.String())
+	}
+	rows = append(rows, border("└", "┴", "┘"))
+
+	return strings.Join(rows, "\n")
+}
+
+// EmojiRenderer draws the pieces as ❌ and ⭕. Empty cells are numbers padded
+// to the two columns an emoji takes:
+//
+//	❌  2 ⭕
+//	 4 ❌  6
+//	 7  8 ⭕
+type EmojiRenderer struct{}
+
+func (EmojiRenderer) Render(board []int, boardSize int) string {
+	rows := make([]string, boardSize)
+	for row := range rows {
+		cells := make([]string, boardSize)
+		for col := range cells {
+			i := row*boardSize + col
+			switch board[i] {
+			case XPlayer:
+				cells[col] = "❌"
+			case OPlayer:
+				cells[col] = "⭕"
+			default:
+				cells[col] = fmt.Sprintf("%2d", i+1)
+			}
+		}
+		rows[row] = strings.Join(cells, " ")
+	}
+	return strings.Join(rows, "\n")
+}
+
+// CompactRenderer draws the board on a single line with the rows separated
+// by slashes, e.g. "X 2 O / 4 X 6 / 7 8 O".
+type CompactRenderer struct{}
+
+func (CompactRenderer) Render(board []int, boardSize int) string {
+	rows := make([]string, boardSize)
+	for row := range rows {
+		cells := make([]string, boardSize)
+		for col := range cells {
+			cells[col] = cellLabel(board, row*boardSize+col)
+		}
+		rows[row] = strings.Join(cells, " ")
+	}
+	return strings.Join(rows, " / ")
+}
+
+// MarkdownRenderer draws the board as a Markdown table. Tables need a header,
+// so the first row is left empty and the board starts below the delimiter:
+//
+//	|   |   |   |
+//	|---|---|---|
+//	| X | 2 | O |
+//	| 4 | X | 6 |
+//	| 7 | 8 | O |
+type MarkdownRenderer struct{}
+
+func (MarkdownRenderer) Render(board []int, boardSize int) string {
+	rows := []string{
+		"|" + strings.Repeat("   |", boardSize),
+		"|" + strings.Repeat("---|", boardSize),
+	}
+	for row := 0; row < boardSize; row++ {
+		var line strings.Builder
+		line.WriteString("|")
+		for col := 0; col < boardSize; col++ {
+			line.WriteString(" " + cellLabel(board, row*boardSize+col) + " |")
+		}
+		rows = append(rows, line.String())
+	}
+	return strings.Join(rows, "\n")
+}
+
+// cellLabel is the piece in a cell or, for an empty cell, its number.
+func cellLabel(board []int, i int) string {
+	switch board[i] {
+	case XPlayer:
+		return "X"
+	case OPlayer:
+		return "O"
+	default:
+		return strconv.Itoa(i + 1)
+	}
+}
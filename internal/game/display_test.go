@@ -0,0 +1,87 @@
+package game
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestRenderers(t *testing.T) {
+	board := []int{1, 0, 2, 0, 1, 0, 0, 0, 2}
+
+	cases := []struct {
+		style string
+		want  string
+	}{
+		{DisplayStyleClassic, " X | 2 | O \n --------- \n 4 | X | 6 \n --------- \n 7 | 8 | O "},
+		{DisplayStyleUnicode, "┌───┬───┬───┐\n│ X │ 2 │ O │\n├───┼───┼───┤\n│ 4 │ X │ 6 │\n├───┼───┼───┤\n│ 7 │ 8 │ O │\n└───┴───┴───┘"},
+		{DisplayStyleEmoji, "❌  2 ⭕\n 4 ❌  6\n 7  8 ⭕"},
+		{DisplayStyleCompact, "X 2 O / 4 X 6 / 7 8 O"},
+		{DisplayStyleMarkdown, "|   |   |   |\n|---|---|---|\n| X | 2 | O |\n| 4 | X | 6 |\n| 7 | 8 | O |"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.style, func(t *testing.T) {
+			renderer, ok := LookupRenderer(tc.style)
+			if !ok {
+				t.Fatalf("no renderer for %q", tc.style)
+			}
+
+			if got := renderer.Render(board, 3); got != tc.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRenderersAllBoardSizes(t *testing.T) {
+	for _, style := range DisplayStyles() {
+		renderer, _ := LookupRenderer(style)
+
+		for boardSize := 3; boardSize <= 6; boardSize++ {
+			board := make([]int, boardSize*boardSize)
+			board[0] = XPlayer
+			board[len(board)-1] = OPlayer
+
+			got := renderer.Render(board, boardSize)
+			for i := 2; i < len(board); i++ {
+				if !containsNumber(got, i) {
+					t.Errorf("%s %dx%d: cell %d is missing from\n%s", style, boardSize, boardSize, i, got)
+				}
+			}
+			if style != DisplayStyleCompact && strings.Count(got, "\n") < boardSize-1 {
+				t.Errorf("%s %dx%d: got fewer than %d rows\n%s", style, boardSize, boardSize, boardSize, got)
+			}
+		}
+	}
+}
+
+func TestClassicRendererKeepsBigBoardLayout(t *testing.T) {
+	board := make([]int, 16)
+	board[5] = XPlayer
+
+	got := ClassicRenderer{}.Render(board, 4)
+	want := "  1 |  2 |  3 |  4 \n--------------------\n  5 |  X |  7 |  8 \n--------------------\n  9 | 10 | 11 | 12 \n--------------------\n 13 | 14 | 15 | 16 "
+
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestLookupRenderer(t *testing.T) {
+	if renderer, ok := LookupRenderer(""); !ok || renderer != (ClassicRenderer{}) {
+		t.Errorf("got %v, %v for the default style", renderer, ok)
+	}
+	if _, ok := LookupRenderer("fancy"); ok {
+		t.Errorf("got a renderer for an unknown style")
+	}
+}
+
+func containsNumber(s string, n int) bool {
+	for _, field := range strings.FieldsFunc(s, func(r rune) bool { return r < '0' || r > '9' }) {
+		if field == strconv.Itoa(n) {
+			return true
+		}
+	}
+	return false
+}
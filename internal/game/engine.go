@@ -1,8 +1,12 @@
 package game
 
 import (
+	"context"
+	"errors"
 	"math"
 	"math/rand"
+
+	"github.com/isavita/tictactoe_api/internal/model"
 )
 
 const (
@@ -12,15 +16,97 @@ const (
 	DifficultyEasy   = 101
 	DifficultyMedium = 102
 	DifficultyHard   = 103
-	MaxDepth         = 6 // change this to adjust the search depth
+	MaxDepth         = 6 // the default search depth, see Limits.SearchDepth
 )
 
+var ErrInvalidDifficulty = errors.New("invalid difficulty")
+
+// ParseDifficulty maps the public difficulty levels 1 (Easy), 2 (Medium) and
+// 3 (Hard) to the engine constants. 0 is the default, Hard; see
+// Limits.ParseDifficulty for a configured default.
+func ParseDifficulty(level int) (int, error) {
+	if level == 0 {
+		level = DefaultLimits.DefaultDifficulty
+	}
+	switch level {
+	case 3:
+		return DifficultyHard, nil
+	case 1:
+		return DifficultyEasy, nil
+	case 2:
+		return DifficultyMedium, nil
+	default:
+		return 0, ErrInvalidDifficulty
+	}
+}
+
+// DifficultyLevel is the inverse of ParseDifficulty.
+func DifficultyLevel(difficulty int) int {
+	return difficulty - DifficultyEasy + 1
+}
+
 type GameState struct {
 	board         []int
 	boardSize     int
 	currentPlayer int
 	player        int
 	difficulty    int
+
+	// ctx stops the search of findBestMove once it is done
+	ctx     context.Context
+	nodes   int
+	stopped bool
+	// depth is how many moves ahead findBestMove looks on large boards, or
+	// zero for MaxDepth
+	depth int
+}
+
+func NewGameState(board []int, boardSize int, currentPlayer int) *GameState {
+	return &GameState{
+		board:         board,
+		boardSize:     boardSize,
+		currentPlayer: currentPlayer,
+		player:        GetOponent(currentPlayer),
+	}
+}
+
+// SetSearchDepth sets how many moves ahead the hard AI looks on boards larger
+// than 3x3, MaxDepth by default.
+func (gs *GameState) SetSearchDepth(depth int) {
+	gs.depth = depth
+}
+
+func (gs *GameState) searchDepth() int {
+	if gs.depth == 0 {
+		return MaxDepth
+	}
+	return gs.depth
+}
+
+func (gs *GameState) Board() []int {
+	return gs.board
+}
+
+func (gs *GameState) BoardSize() int {
+	return gs.boardSize
+}
+
+func (gs *GameState) CurrentPlayer() int {
+	return gs.currentPlayer
+}
+
+// Status reports the game status for the current board using the model constants.
+func (gs *GameState) Status() string {
+	switch gs.checkWinner() {
+	case XPlayer:
+		return model.GameStatusPlayer1Wins
+	case OPlayer:
+		return model.GameStatusPlayer2Wins
+	case Draw:
+		return model.GameStatusDraw
+	default:
+		return model.GameStatusOngoing
+	}
 }
 
 func (gs *GameState) Play(move int) bool {
@@ -113,13 +199,36 @@ func GetOponent(player int) int {
 	return XPlayer
 }
 
+// FindMove picks a move for the current player at the given difficulty
+// without playing it.
+func (gs *GameState) FindMove(difficulty int) int {
+	move, _ := gs.FindMoveContext(context.Background(), difficulty)
+	return move
+}
+
+// FindMoveContext is FindMove with a search that stops once ctx is done. It
+// then returns ctx.Err() together with the best move found so far, or -1 if
+// the search had not finished weighing any move.
+func (gs *GameState) FindMoveContext(ctx context.Context, difficulty int) (int, error) {
+	gs.player = GetOponent(gs.currentPlayer)
+	gs.difficulty = difficulty
+	return gs.MakeMoveContext(ctx)
+}
+
 func (gs *GameState) MakeMove() int {
+	move, _ := gs.MakeMoveContext(context.Background())
+	return move
+}
+
+// MakeMoveContext is MakeMove with a search that stops once ctx is done, as
+// FindMoveContext does.
+func (gs *GameState) MakeMoveContext(ctx context.Context) (int, error) {
 	if gs.difficulty == DifficultyEasy {
-		return gs.findRandomMove()
+		return gs.findRandomMove(), nil
 	} else if gs.difficulty == DifficultyMedium {
-		return gs.findMediumMove()
+		return gs.findMediumMove(), nil
 	}
-	return gs.findBestMove()
+	return gs.findBestMove(ctx)
 }
 
 func (gs *GameState) findRandomMove() int {
@@ -167,16 +276,27 @@ func (gs *GameState) findMediumMove() int {
 	return gs.findRandomMove()
 }
 
-func (gs *GameState) findBestMove() int {
+func (gs *GameState) findBestMove(ctx context.Context) (int, error) {
 	bestScore := math.Inf(-1)
 	bestMove := -1
 
+	gs.ctx, gs.nodes, gs.stopped = ctx, 0, false
+	defer func() { gs.ctx = nil }()
+
 	for i := 0; i < gs.boardSize*gs.boardSize; i++ {
 		if gs.board[i] == 0 {
+			if ctx.Err() != nil {
+				return bestMove, ctx.Err()
+			}
+
 			gs.board[i] = gs.player
 			score := gs.minimax(0, true, math.Inf(-1), math.Inf(1))
 			gs.board[i] = 0
 
+			// The score of a stopped search is meaningless
+			if gs.stopped {
+				return bestMove, ctx.Err()
+			}
 			if score > bestScore {
 				bestScore = score
 				bestMove = i
@@ -184,11 +304,30 @@ func (gs *GameState) findBestMove() int {
 		}
 	}
 
-	return bestMove
+	return bestMove, nil
+}
+
+// searchCheckInterval is the number of positions the search weighs between
+// checks of its context.
+const searchCheckInterval = 1024
+
+// stop reports whether the search has to stop because its context is done.
+func (gs *GameState) stop() bool {
+	if gs.stopped || gs.ctx == nil {
+		return gs.stopped
+	}
+	gs.nodes++
+	if gs.nodes%searchCheckInterval == 0 && gs.ctx.Err() != nil {
+		gs.stopped = true
+	}
+	return gs.stopped
 }
 
 func (gs *GameState) minimax(depth int, isMaximizing bool, alpha, beta float64) float64 {
-	if gs.boardSize > 3 && depth == MaxDepth {
+	if gs.stop() {
+		return 0
+	}
+	if gs.boardSize > 3 && depth == gs.searchDepth() {
 		return gs.heuristic()
 	}
 	winner := gs.checkWinner()
@@ -395,3 +534,42 @@ func (gs *GameState) countPotentialWins(player int) int {
 
 	return count
 }
+
+// WinningLine returns the cells of the completed row, column or diagonal, or
+// nil when nobody has won.
+func WinningLine(board []int, boardSize int) []int {
+	lines := make([][]int, 0, 2*boardSize+2)
+	mainDiagonal := make([]int, boardSize)
+	secondaryDiagonal := make([]int, boardSize)
+	for i := 0; i < boardSize; i++ {
+		row := make([]int, boardSize)
+		column := make([]int, boardSize)
+		for j := 0; j < boardSize; j++ {
+			row[j] = i*boardSize + j
+			column[j] = i + j*boardSize
+		}
+		lines = append(lines, row, column)
+		mainDiagonal[i] = i*boardSize + i
+		secondaryDiagonal[i] = i*boardSize + (boardSize - i - 1)
+	}
+	lines = append(lines, mainDiagonal, secondaryDiagonal)
+
+	for _, line := range lines {
+		player := board[line[0]]
+		if player == 0 {
+			continue
+		}
+		win := true
+		for _, cell := range line[1:] {
+			if board[cell] != player {
+				win = false
+				break
+			}
+		}
+		if win {
+			return line
+		}
+	}
+
+	return nil
+}
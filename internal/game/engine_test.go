@@ -1,6 +1,8 @@
 package game
 
 import (
+	"context"
+	"reflect"
 	"testing"
 )
 
@@ -13,7 +15,7 @@ func TestFindBestMove(t *testing.T) {
 	}
 
 	expectedMove := 2
-	actualMove := gs.findBestMove()
+	actualMove, _ := gs.findBestMove(context.Background())
 
 	if actualMove != expectedMove {
 		t.Errorf("Expected move at index %d, but got %d", expectedMove, actualMove)
@@ -29,7 +31,7 @@ func TestFindBestMove4By4Board(t *testing.T) {
 	}
 
 	expectedMove := 3
-	actualMove := gs.findBestMove()
+	actualMove, _ := gs.findBestMove(context.Background())
 
 	if actualMove != expectedMove {
 		t.Errorf("Expected move at index %d, but got %d", expectedMove, actualMove)
@@ -45,7 +47,7 @@ func TestFindBestMove5By5Board(t *testing.T) {
 	}
 
 	expectedMove := 4
-	actualMove := gs.findBestMove()
+	actualMove, _ := gs.findBestMove(context.Background())
 
 	if actualMove != expectedMove {
 		t.Errorf("Expected move at index %d, but got %d", expectedMove, actualMove)
@@ -61,9 +63,87 @@ func TestFindBestMove6By6Board(t *testing.T) {
 	}
 
 	expectedMove := 5
-	actualMove := gs.findBestMove()
+	actualMove, _ := gs.findBestMove(context.Background())
 
 	if actualMove != expectedMove {
 		t.Errorf("Expected move at index %d, but got %d", expectedMove, actualMove)
 	}
 }
+
+func TestFindMoveContext(t *testing.T) {
+	t.Run("stops before weighing any move", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		gs := NewGameState(make([]int, 36), 6, XPlayer)
+
+		move, err := gs.FindMoveContext(ctx, DifficultyHard)
+		if move != -1 || err != context.Canceled {
+			t.Errorf("got move %d and error %v, want -1 and %v", move, err, context.Canceled)
+		}
+	})
+
+	t.Run("returns the best move so far at the deadline", func(t *testing.T) {
+		board := make([]int, 16)
+		board[0] = XPlayer
+		gs := NewGameState(board, 4, OPlayer)
+		// The deadline passes once the first candidate, cell 1, has been
+		// weighed and taken back.
+		ctx := &hookContext{Context: context.Background(), done: func() bool {
+			return gs.nodes > 0 && board[1] == 0
+		}}
+
+		move, err := gs.FindMoveContext(ctx, DifficultyHard)
+		if err != context.DeadlineExceeded || move != 1 {
+			t.Errorf("got move %d and error %v, want 1 and %v", move, err, context.DeadlineExceeded)
+		}
+		if !reflect.DeepEqual(board[1:], make([]int, 15)) {
+			t.Errorf("the stopped search left the board %v", board)
+		}
+	})
+
+	t.Run("easy and medium moves ignore the context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		gs := NewGameState(make([]int, 9), 3, XPlayer)
+
+		if move, err := gs.FindMoveContext(ctx, DifficultyMedium); move == -1 || err != nil {
+			t.Errorf("got move %d and error %v", move, err)
+		}
+	})
+}
+
+// hookContext is a context whose deadline passes once done reports true.
+type hookContext struct {
+	context.Context
+	done func() bool
+}
+
+func (ctx *hookContext) Err() error {
+	if ctx.done() {
+		return context.DeadlineExceeded
+	}
+	return nil
+}
+
+func TestWinningLine(t *testing.T) {
+	cases := []struct {
+		name  string
+		board []int
+		size  int
+		want  []int
+	}{
+		{"no winner", []int{1, 2, 1, 0, 0, 0, 0, 0, 0}, 3, nil},
+		{"row", []int{2, 2, 0, 1, 1, 1, 0, 0, 2}, 3, []int{3, 4, 5}},
+		{"column", []int{0, 2, 1, 0, 2, 1, 0, 2, 0}, 3, []int{1, 4, 7}},
+		{"secondary diagonal", []int{0, 0, 0, 1, 0, 0, 1, 2, 0, 1, 2, 0, 1, 0, 0, 2}, 4, []int{3, 6, 9, 12}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := WinningLine(tc.board, tc.size)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %v want %v", got, tc.want)
+			}
+		})
+	}
+}
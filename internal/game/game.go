@@ -1,85 +1,85 @@
 package game
 
 import (
-	"fmt"
-	"math"
-	"strconv"
-	"strings"
+	"context"
 
+	"github.com/isavita/tictactoe_api/internal/i18n"
 	"github.com/isavita/tictactoe_api/internal/model"
 )
 
+// TicTacToeGame answers the moves of POST /v1/tictactoe. It keeps no state
+// between moves and is safe for concurrent use.
 type TicTacToeGame struct {
-	gameState *GameState
+	limits Limits
 }
 
-func NewTicTacToeGame() *TicTacToeGame {
-	return &TicTacToeGame{
-		gameState: &GameState{
-			board:         make([]int, 9),
-			boardSize:     3,
-			currentPlayer: XPlayer,
-		},
-	}
+// NewTicTacToeGame returns a game whose AI searches as deep as the limits
+// allow.
+func NewTicTacToeGame(limits Limits) *TicTacToeGame {
+	return &TicTacToeGame{limits: limits}
 }
 
-func (g *TicTacToeGame) MakeMove(currentPlayer int, moveRequest model.MoveRequest) model.MoveResponse {
-	g.gameState.board = moveRequest.Board
-	g.gameState.boardSize = moveRequest.BoardSize
-	g.gameState.currentPlayer = currentPlayer
-	g.gameState.player = GetOponent(currentPlayer)
-	g.gameState.difficulty = moveRequest.Difficulty
+// MakeMove plays the AI's move on the board of the request. The search for
+// the move stops once ctx is done: the best move found so far is played, and
+// ctx.Err() is returned if there is none yet.
+func (g *TicTacToeGame) MakeMove(ctx context.Context, currentPlayer int, moveRequest model.MoveRequest) (model.MoveResponse, error) {
+	gameState := NewGameState(moveRequest.Board, moveRequest.BoardSize, currentPlayer)
+	gameState.difficulty = moveRequest.Difficulty
+	gameState.SetSearchDepth(g.limits.SearchDepth)
 
-	var message string = "Game Over."
+	locale := moveRequest.Locale
+	var message string = i18n.Text(locale, "move.game_over", nil)
 	// Make a move and update the game state
-	aiMove := g.gameState.MakeMove()
+	aiMove, err := gameState.MakeMoveContext(ctx)
+	if err != nil && aiMove == -1 {
+		return model.MoveResponse{}, err
+	}
 	success := false
 
 	if aiMove != -1 {
-		success = g.gameState.Play(aiMove)
+		success = gameState.Play(aiMove)
 		if success {
-			message = "Player " + strconv.Itoa(currentPlayer) + " has placed "
-			if currentPlayer == XPlayer {
-				message += "'X'"
-			} else {
-				message += "'O'"
-			}
-			message += " in position " + strconv.Itoa(aiMove+1) + "."
-
-			if isFirstMove(g.gameState.board) {
-				message += " Please note: If the user is playing with 'X', disregard this move. Instead, ask the user where they would like to place their first move, then present the game board reflecting their choice."
-			}
+			message = i18n.Text(locale, "move.placed", map[string]any{
+				"Player":   currentPlayer,
+				"Piece":    PieceName(currentPlayer),
+				"Position": aiMove + 1,
+			})
 		} else {
-			message = "Invalid move."
+			message = i18n.Text(locale, "move.invalid", nil)
 		}
 	}
 
 	// Check for winner and game status
 	var gameStatus string
 	var nextPlayer int = -1
-	if g.gameState.HasWinner() {
+	if gameState.HasWinner() {
 		gameStatus = model.GameStatusPlayer1Wins
-		if g.gameState.currentPlayer == OPlayer {
+		if gameState.currentPlayer == OPlayer {
 			gameStatus = model.GameStatusPlayer2Wins
 		}
-	} else if g.gameState.IsDraw() {
+	} else if gameState.IsDraw() {
 		gameStatus = model.GameStatusDraw
 	} else {
 		gameStatus = model.GameStatusOngoing
-		g.gameState.NextTurn()
-		nextPlayer = g.gameState.currentPlayer
+		gameState.NextTurn()
+		nextPlayer = gameState.currentPlayer
 	}
 
 	// Create a response
-	return model.MoveResponse{
+	moveResponse := model.MoveResponse{
 		Success:      success,
 		Message:      message,
-		Board:        g.gameState.board,
-		BoardSize:    g.gameState.boardSize,
-		BoardDisplay: boardToDisplay(g.gameState.board),
+		Board:        gameState.board,
+		BoardSize:    gameState.boardSize,
+		BoardDisplay: LookupRendererOrDefault(moveRequest.DisplayStyle).Render(gameState.board, gameState.boardSize),
 		GameStatus:   gameStatus,
+		StatusText:   StatusText(locale, gameStatus, nextPlayer),
 		NextPlayer:   nextPlayer,
+		Position:     FormatPosition(gameState.board, gameState.boardSize, nextPlayer),
 	}
+	moveResponse.AssistantHints = AssistantHints(moveRequest, moveResponse, currentPlayer)
+
+	return moveResponse, nil
 }
 
 func isFirstMove(board []int) bool {
@@ -95,65 +95,15 @@ func isFirstMove(board []int) bool {
 	return true
 }
 
-func boardToDisplay(board []int) string {
-	size := int(math.Sqrt(float64(len(board))))
-	if size > 3 {
-		return boardToDisplayWhenBig(board, size)
-	} else {
-		return boardToDisplayWhenSmall(board, size)
-	}
-}
-
-func boardToDisplayWhenSmall(board []int, size int) string {
-	var display strings.Builder
-
-	for i := 0; i < len(board); i++ {
-		if i > 0 && i%size == 0 {
-			display.WriteString("\n --------- \n")
-		}
-
-		switch board[i] {
-		case XPlayer:
-			display.WriteString(" X ")
-		case OPlayer:
-			display.WriteString(" O ")
-		default:
-			display.WriteString(fmt.Sprintf(" %d ", i+1))
-		}
-
-		if i%size != 2 {
-			display.WriteString("|")
-		}
-	}
-
-	return display.String()
+// StatusText describes the game status in the locale, e.g. "O to move.".
+func StatusText(locale string, gameStatus string, nextPlayer int) string {
+	return i18n.Text(locale, "status."+gameStatus, map[string]any{"Piece": PieceName(nextPlayer)})
 }
 
-func boardToDisplayWhenBig(board []int, size int) string {
-	var display strings.Builder
-
-	for i := 0; i < len(board); i++ {
-		if i > 0 && i%size == 0 {
-			display.WriteString("\n")
-			for j := 0; j < size; j++ {
-				display.WriteString("-----")
-			}
-			display.WriteString("\n")
-		}
-
-		switch board[i] {
-		case XPlayer:
-			display.WriteString("  X ")
-		case OPlayer:
-			display.WriteString("  O ")
-		default:
-			display.WriteString(fmt.Sprintf(" %2d ", i+1))
-		}
-
-		if i%size != size-1 {
-			display.WriteString("|")
-		}
+// PieceName is the letter of the player's piece, "X" or "O".
+func PieceName(player int) string {
+	if player == OPlayer {
+		return "O"
 	}
-
-	return display.String()
+	return "X"
 }
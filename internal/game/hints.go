@@ -0,0 +1,55 @@
+package game
+
+import (
+	"fmt"
+
+	"github.com/isavita/tictactoe_api/internal/model"
+)
+
+// Client types select which assistantHints a move response carries.
+const (
+	// ClientTypeAssistant is an LLM assistant that talks to the user and
+	// relays the board, such as the ChatGPT plugin. It gets every hint.
+	ClientTypeAssistant = "assistant"
+	// ClientTypeApp is a program that draws the board itself and needs no
+	// hints.
+	ClientTypeApp = "app"
+)
+
+// IsClientType reports whether t is a known client type; empty means
+// ClientTypeAssistant.
+func IsClientType(t string) bool {
+	return t == "" || t == ClientTypeAssistant || t == ClientTypeApp
+}
+
+// AssistantHints returns guidance for the model relaying the move response:
+// how to handle the AI opening the game, how to read boardDisplay and what to
+// ask the user next. The hints are in English whatever the locale of the
+// message.
+func AssistantHints(moveRequest model.MoveRequest, moveResponse model.MoveResponse, aiPlayer int) []string {
+	if moveRequest.ClientType == ClientTypeApp {
+		return nil
+	}
+
+	var hints []string
+	// Without aiPlayer the AI may have opened a game the user meant to start.
+	if moveRequest.AIPlayer == 0 && moveResponse.Success && isFirstMove(moveResponse.Board) {
+		hints = append(hints, "If the user is playing with 'X', disregard this move. Instead, ask the user where they would like to place their first move, then present the game board reflecting their choice.")
+	}
+
+	if moveRequest.DisplayStyle == DisplayStyleMarkdown {
+		hints = append(hints, "Show boardDisplay to the user as it is; it is a Markdown table.")
+	} else {
+		hints = append(hints, "Show boardDisplay to the user in a code block so that the columns line up.")
+	}
+	cells := moveResponse.BoardSize * moveResponse.BoardSize
+	hints = append(hints, fmt.Sprintf("Empty cells in boardDisplay are numbered from 1 to %d. To play cell n, set board[n-1] to the user's player number (1 for X, 2 for O) and send the whole board.", cells))
+
+	if moveResponse.GameStatus == model.GameStatusOngoing {
+		hints = append(hints, fmt.Sprintf("Ask the user which numbered cell they want to play their '%s' in.", PieceName(GetOponent(aiPlayer))))
+	} else {
+		hints = append(hints, "Tell the user how the game ended and ask whether they want to play again.")
+	}
+
+	return hints
+}
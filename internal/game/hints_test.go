@@ -0,0 +1,40 @@
+package game
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/isavita/tictactoe_api/internal/model"
+)
+
+func TestAssistantHints(t *testing.T) {
+	t.Run("asks for the next move", func(t *testing.T) {
+		moveResponse := model.MoveResponse{Success: true, Board: []int{1, 2, 0, 0, 0, 0, 0, 0, 0}, BoardSize: 3, GameStatus: model.GameStatusOngoing}
+
+		hints := AssistantHints(model.MoveRequest{DisplayStyle: DisplayStyleMarkdown}, moveResponse, OPlayer)
+
+		want := []string{
+			"Show boardDisplay to the user as it is; it is a Markdown table.",
+			"Empty cells in boardDisplay are numbered from 1 to 9. To play cell n, set board[n-1] to the user's player number (1 for X, 2 for O) and send the whole board.",
+			"Ask the user which numbered cell they want to play their 'X' in.",
+		}
+		if strings.Join(hints, "\n") != strings.Join(want, "\n") {
+			t.Errorf("got %q want %q", hints, want)
+		}
+	})
+
+	t.Run("offers a new game when it is over", func(t *testing.T) {
+		moveResponse := model.MoveResponse{Success: true, Board: []int{1, 1, 1, 2, 2, 0, 0, 0, 0}, BoardSize: 3, GameStatus: model.GameStatusPlayer1Wins}
+
+		hints := AssistantHints(model.MoveRequest{}, moveResponse, XPlayer)
+		if last := hints[len(hints)-1]; !strings.Contains(last, "play again") {
+			t.Errorf("got last hint %q", last)
+		}
+	})
+
+	t.Run("has no hints for apps", func(t *testing.T) {
+		if hints := AssistantHints(model.MoveRequest{ClientType: ClientTypeApp}, model.MoveResponse{}, XPlayer); hints != nil {
+			t.Errorf("got %q", hints)
+		}
+	})
+}
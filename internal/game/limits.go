@@ -0,0 +1,67 @@
+package game
+
+import "fmt"
+
+// The board sizes the engine can play on.
+const (
+	MinBoardSize = 3
+	MaxBoardSize = 6
+)
+
+// MaxSearchDepth bounds Limits.SearchDepth. Every extra move multiplies the
+// positions the hard AI weighs on large boards.
+const MaxSearchDepth = 8
+
+// Limits are the tunables of the games the server plays.
+type Limits struct {
+	// MinBoardSize and MaxBoardSize are the smallest and largest boards that
+	// games and moves may be played on, within 3 to 6.
+	MinBoardSize int
+	MaxBoardSize int
+	// DefaultBoardSize is the size of a board left out of a request.
+	DefaultBoardSize int
+	// DefaultDifficulty is the public level, 1 (Easy) to 3 (Hard), of a
+	// request without one.
+	DefaultDifficulty int
+	// SearchDepth is how many moves ahead the hard AI looks on boards larger
+	// than 3x3, which it cannot search to the end, up to MaxSearchDepth.
+	SearchDepth int
+}
+
+// DefaultLimits are the limits of the first version of the API.
+var DefaultLimits = Limits{
+	MinBoardSize:      MinBoardSize,
+	MaxBoardSize:      MaxBoardSize,
+	DefaultBoardSize:  3,
+	DefaultDifficulty: 3,
+	SearchDepth:       MaxDepth,
+}
+
+// Validate reports the first limit that is out of range.
+func (l Limits) Validate() error {
+	switch {
+	case l.MinBoardSize < MinBoardSize || l.MaxBoardSize > MaxBoardSize || l.MinBoardSize > l.MaxBoardSize:
+		return fmt.Errorf("invalid board sizes %d to %d: use sizes from %d to %d", l.MinBoardSize, l.MaxBoardSize, MinBoardSize, MaxBoardSize)
+	case l.DefaultBoardSize < l.MinBoardSize || l.DefaultBoardSize > l.MaxBoardSize:
+		return fmt.Errorf("invalid default board size %d: use a size from %d to %d", l.DefaultBoardSize, l.MinBoardSize, l.MaxBoardSize)
+	case l.DefaultDifficulty < 1 || l.DefaultDifficulty > 3:
+		return fmt.Errorf("invalid default difficulty %d: use 1 (Easy), 2 (Medium) or 3 (Hard)", l.DefaultDifficulty)
+	case l.SearchDepth < 1 || l.SearchDepth > MaxSearchDepth:
+		return fmt.Errorf("invalid search depth %d: use 1 to %d moves", l.SearchDepth, MaxSearchDepth)
+	}
+	return nil
+}
+
+// IsBoardSize reports whether games may be played on boards of the size.
+func (l Limits) IsBoardSize(size int) bool {
+	return size >= l.MinBoardSize && size <= l.MaxBoardSize
+}
+
+// ParseDifficulty is the package's ParseDifficulty with the default
+// difficulty of the limits for 0.
+func (l Limits) ParseDifficulty(level int) (int, error) {
+	if level == 0 {
+		level = l.DefaultDifficulty
+	}
+	return ParseDifficulty(level)
+}
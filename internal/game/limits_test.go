@@ -0,0 +1,55 @@
+package game
+
+import "testing"
+
+func TestLimits(t *testing.T) {
+	t.Run("validate", func(t *testing.T) {
+		if err := DefaultLimits.Validate(); err != nil {
+			t.Errorf("got error %v for the default limits", err)
+		}
+		for _, limits := range []Limits{
+			{MinBoardSize: 2, MaxBoardSize: 6, DefaultBoardSize: 3, DefaultDifficulty: 3, SearchDepth: 6},
+			{MinBoardSize: 3, MaxBoardSize: 7, DefaultBoardSize: 3, DefaultDifficulty: 3, SearchDepth: 6},
+			{MinBoardSize: 4, MaxBoardSize: 6, DefaultBoardSize: 3, DefaultDifficulty: 3, SearchDepth: 6},
+			{MinBoardSize: 3, MaxBoardSize: 6, DefaultBoardSize: 3, DefaultDifficulty: 0, SearchDepth: 6},
+			{MinBoardSize: 3, MaxBoardSize: 6, DefaultBoardSize: 3, DefaultDifficulty: 3, SearchDepth: 0},
+			{MinBoardSize: 3, MaxBoardSize: 6, DefaultBoardSize: 3, DefaultDifficulty: 3, SearchDepth: MaxSearchDepth + 1},
+		} {
+			if err := limits.Validate(); err == nil {
+				t.Errorf("got no error for %+v", limits)
+			}
+		}
+	})
+
+	t.Run("board sizes and default difficulty", func(t *testing.T) {
+		limits := Limits{MinBoardSize: 3, MaxBoardSize: 4, DefaultBoardSize: 3, DefaultDifficulty: 1, SearchDepth: MaxDepth}
+
+		if !limits.IsBoardSize(4) || limits.IsBoardSize(5) {
+			t.Errorf("got the wrong board sizes for %+v", limits)
+		}
+		if difficulty, err := limits.ParseDifficulty(0); difficulty != DifficultyEasy || err != nil {
+			t.Errorf("got difficulty %d and error %v, want %d", difficulty, err, DifficultyEasy)
+		}
+		if difficulty, _ := limits.ParseDifficulty(3); difficulty != DifficultyHard {
+			t.Errorf("got difficulty %d for level 3, want %d", difficulty, DifficultyHard)
+		}
+		if difficulty, _ := ParseDifficulty(0); difficulty != DifficultyHard {
+			t.Errorf("got default difficulty %d without limits, want %d", difficulty, DifficultyHard)
+		}
+	})
+
+	t.Run("search depth", func(t *testing.T) {
+		nodes := map[int]int{}
+		for _, depth := range []int{2, 4} {
+			gs := NewGameState(make([]int, 16), 4, XPlayer)
+			gs.SetSearchDepth(depth)
+			if move := gs.FindMove(DifficultyHard); move == -1 {
+				t.Errorf("got no move at depth %d", depth)
+			}
+			nodes[depth] = gs.nodes
+		}
+		if nodes[2] >= nodes[4] {
+			t.Errorf("weighed %d positions at depth 2 and %d at depth 4, want fewer at depth 2", nodes[2], nodes[4])
+		}
+	})
+}
@@ -0,0 +1,118 @@
+package game
+
+import (
+	"errors"
+	"strings"
+)
+
+var ErrInvalidPosition = errors.New("invalid position")
+
+// FormatPosition writes a board as a compact FEN-style string: rows from top to
+// bottom separated by '/', with 'x', 'o' and '.' for the cells, followed by a
+// space and the side to move ('x' or 'o', '-' when the game is over), for
+// example "x.o/.x./..o o".
+func FormatPosition(board []int, boardSize int, nextPlayer int) string {
+	var position strings.Builder
+
+	for i, cell := range board {
+		if i > 0 && i%boardSize == 0 {
+			position.WriteByte('/')
+		}
+		switch cell {
+		case XPlayer:
+			position.WriteByte('x')
+		case OPlayer:
+			position.WriteByte('o')
+		default:
+			position.WriteByte('.')
+		}
+	}
+
+	position.WriteByte(' ')
+	switch nextPlayer {
+	case XPlayer:
+		position.WriteByte('x')
+	case OPlayer:
+		position.WriteByte('o')
+	default:
+		position.WriteByte('-')
+	}
+
+	return position.String()
+}
+
+// ParsePosition reads a string written by FormatPosition. Like in FEN, a digit
+// may stand for that many empty cells. The side to move is optional and 0 is
+// returned when it is missing.
+func ParsePosition(position string) ([]int, int, int, error) {
+	fields := strings.Fields(position)
+	if len(fields) == 0 || len(fields) > 2 {
+		return nil, 0, 0, ErrInvalidPosition
+	}
+
+	// The board is sized from the rows, so a position with more rows or longer
+	// rows than the largest board is rejected before it is built.
+	rows := strings.Split(strings.ToLower(fields[0]), "/")
+	boardSize := len(rows)
+	if boardSize > MaxBoardSize {
+		return nil, 0, 0, ErrInvalidPosition
+	}
+	for _, row := range rows {
+		if rowCells(row, boardSize) != boardSize {
+			return nil, 0, 0, ErrInvalidPosition
+		}
+	}
+
+	board := make([]int, 0, boardSize*boardSize)
+	for _, row := range rows {
+		for _, c := range row {
+			switch {
+			case c == 'x':
+				board = append(board, XPlayer)
+			case c == 'o':
+				board = append(board, OPlayer)
+			case c >= '1' && c <= '9':
+				for n := 0; n < int(c-'0'); n++ {
+					board = append(board, 0)
+				}
+			default:
+				board = append(board, 0)
+			}
+		}
+	}
+
+	nextPlayer := 0
+	if len(fields) == 2 {
+		switch strings.ToLower(fields[1]) {
+		case "x":
+			nextPlayer = XPlayer
+		case "o":
+			nextPlayer = OPlayer
+		case "-":
+		default:
+			return nil, 0, 0, ErrInvalidPosition
+		}
+	}
+
+	return board, boardSize, nextPlayer, nil
+}
+
+// rowCells counts the cells of a row of a position, or returns -1 when the row
+// has an invalid character or more than boardSize cells.
+func rowCells(row string, boardSize int) int {
+	cells := 0
+	for _, c := range row {
+		switch {
+		case c == 'x' || c == 'o' || c == '.':
+			cells++
+		case c >= '1' && c <= '9':
+			cells += int(c - '0')
+		default:
+			return -1
+		}
+		if cells > boardSize {
+			return -1
+		}
+	}
+	return cells
+}
@@ -0,0 +1,77 @@
+package game
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFormatPosition(t *testing.T) {
+	board := []int{1, 0, 2, 0, 1, 0, 0, 0, 2}
+
+	got := FormatPosition(board, 3, XPlayer)
+	want := "x.o/.x./..o x"
+
+	if got != want {
+		t.Errorf("got %q want %q", got, want)
+	}
+}
+
+func TestPositionRoundTrip(t *testing.T) {
+	for boardSize := 3; boardSize <= 6; boardSize++ {
+		board := make([]int, boardSize*boardSize)
+		for i := range board {
+			board[i] = (i * 7) % 3
+		}
+
+		for _, nextPlayer := range []int{XPlayer, OPlayer, -1} {
+			position := FormatPosition(board, boardSize, nextPlayer)
+			gotBoard, gotSize, gotNext, err := ParsePosition(position)
+			if err != nil {
+				t.Fatalf("got error %v for %q", err, position)
+			}
+
+			wantNext := nextPlayer
+			if wantNext == -1 {
+				wantNext = 0
+			}
+			if !reflect.DeepEqual(gotBoard, board) || gotSize != boardSize || gotNext != wantNext {
+				t.Errorf("got %v, %d, %d from %q want %v, %d, %d", gotBoard, gotSize, gotNext, position, board, boardSize, wantNext)
+			}
+		}
+	}
+}
+
+func TestParsePosition(t *testing.T) {
+	t.Run("digits stand for empty cells", func(t *testing.T) {
+		board, boardSize, nextPlayer, err := ParsePosition("X3/4/4/3O")
+		if err != nil {
+			t.Fatalf("got error %v when no error was expected", err)
+		}
+
+		want := []int{1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 2}
+		if !reflect.DeepEqual(board, want) || boardSize != 4 || nextPlayer != 0 {
+			t.Errorf("got %v, %d, %d", board, boardSize, nextPlayer)
+		}
+	})
+
+	t.Run("oversized positions", func(t *testing.T) {
+		for _, position := range []string{
+			strings.Repeat("/", 60000),
+			strings.Repeat("7/", 6) + "7",
+			"x.o/.x./" + strings.Repeat("9", 60000),
+		} {
+			if _, _, _, err := ParsePosition(position); err != ErrInvalidPosition {
+				t.Errorf("got %v want %v for a position of %d bytes", err, ErrInvalidPosition, len(position))
+			}
+		}
+	})
+
+	for _, position := range []string{"", "x.o/.x. o", "x.o/.x./..z x", "x.o/.x./..o y", "x.o/.x./..o x extra"} {
+		t.Run(position, func(t *testing.T) {
+			if _, _, _, err := ParsePosition(position); err != ErrInvalidPosition {
+				t.Errorf("got %v want %v", err, ErrInvalidPosition)
+			}
+		})
+	}
+}
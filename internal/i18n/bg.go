@@ -0,0 +1,66 @@
+package i18n
+
+var bulgarian = map[string]string{
+	"move.placed":         "Играч {{.Player}} постави '{{.Piece}}' на позиция {{.Position}}.",
+	"move.game_over":      "Играта приключи.",
+	"move.invalid":        "Невалиден ход.",
+	"status.ongoing":      "{{.Piece}} е на ход.",
+	"status.player1_wins": "X печели.",
+	"status.player2_wins": "O печели.",
+	"status.draw":         "Играта завърши наравно.",
+
+	"error.invalid_request_body":   "Невалидно тяло на заявката.",
+	"error.unknown_field":          "Тялото на заявката съдържа непознато поле.",
+	"error.invalid_field_type":     "Поле от тялото на заявката е от грешен тип.",
+	"error.invalid_query":          "Параметър на заявката има невалидна стойност.",
+	"error.request_too_large":      "Тялото на заявката е твърде голямо.",
+	"error.unsupported_media_type": "Неподдържан тип на съдържанието: изпратете тялото на заявката като application/json.",
+	"error.method_not_allowed":     "Методът не е разрешен.",
+	"error.not_found":              "Не е намерено.",
+	"error.rate_limited":           "Твърде много заявки. Опитайте отново по-късно.",
+	"error.unauthorized":           "Липсващ или невалиден API ключ. Изпратете го в заглавката Authorization като bearer токен.",
+	"error.feature_not_allowed":    "API ключът не позволява тази крайна точка.",
+	"error.search_timeout":         "Времето на ИИ за намиране на ход изтече. Опитайте отново или с по-ниска трудност или по-малка дъска.",
+	"error.internal_error":         "Вътрешна грешка на сървъра.",
+	"error.invalid_board_size":     "Поддържаните стойности за boardSize са 3, 4, 5 и 6.",
+	"error.invalid_board":          "Невалидна дъска: Трябва да съдържа точно 9, 16, 25 или 36 числа (0, 1 или 2); 0 (празно), 1 (Играч 1), 2 (Играч 2); ходове на Играч 1 >= ходове на Играч 2; максимална разлика: 1.",
+	"error.illegal_piece_count":    "Невалидна дъска: Трябва да съдържа точно 9, 16, 25 или 36 числа (0, 1 или 2); 0 (празно), 1 (Играч 1), 2 (Играч 2); ходове на Играч 1 >= ходове на Играч 2; максимална разлика: 1.",
+	"error.invalid_difficulty":     "Невалидна трудност: Използвай 1 (Лесно), 2 (Средно) или 3 (Трудно). По подразбиране е 3 (Трудно).",
+	"error.invalid_position":       "Невалидна позиция: Използвай редове от 'x', 'o' и '.', разделени с '/', последвани от страната на ход, напр. \"x.o/.x./..o o\"; страната на ход трябва да отговаря на броя X и O.",
+	"error.ambiguous_board":        "Изпрати или board (с boardSize), или position, но не и двете.",
+	"error.invalid_last_move":      "Невалиден lastMove: Използвай поле с номер от 1 до n като в boardDisplay или 0 за никое.",
+	"error.invalid_format":         "Невалиден формат: Използвай \"svg\" или \"png\".",
+	"error.invalid_cell_size":      "Невалиден cellSize: Използвай размер между 16 и 256 пиксела. По подразбиране е 100.",
+	"error.invalid_theme":          "Невалидна тема: Използвай \"light\", \"dark\" или \"high-contrast\". По подразбиране е \"light\".",
+	"error.invalid_moves":          "Невалидни ходове: Използвай празни полета с номера от 1 до n като в boardDisplay, като X започва, без ходове след края на играта.",
+	"error.invalid_delay":          "Невалидно delay: Използвай забавяне между 100 и 5000 милисекунди. По подразбиране е 800.",
+	"error.replay_too_large":       "Повторението е твърде голямо: Използвай по-малък cellSize за игра с толкова много ходове.",
+	"error.render_timeout":         "Изчертаването на повторението отне твърде дълго. Опитай отново с по-малък cellSize.",
+	"error.invalid_display_style":  "Невалиден displayStyle: Използвай \"classic\", \"unicode\", \"emoji\", \"compact\" или \"markdown\". По подразбиране е \"classic\".",
+	"error.game_not_found":         "Играта не е намерена.",
+	"error.invalid_game_mode":      "Невалиден режим: Използвай \"human\" (двама играчи през WebSocket) или \"ai\" (срещу компютъра). По подразбиране е \"human\".",
+	"error.invalid_ai_player":      "Невалиден aiPlayer: Използвай 1 (X) или 2 (O).",
+	"error.invalid_first_player":   "Невалиден firstPlayer: Използвай 1 (X) или 2 (O). По подразбиране е 1 (X).",
+	"error.not_ai_turn":            "Посоченият играч не е на ход. Моля, изпрати хода на правилния играч.",
+	"error.invalid_max_takebacks":  "Невалиден maxTakebacks: Трябва да е 0 (без ограничение) или положително число.",
+	"error.invalid_game_action":    "Непознат тип съобщение: изпрати {\"type\": \"move\", \"position\": n}, където n е номерирано като в boardDisplay.",
+	"error.invalid_record":         "Невалиден запис на игра.",
+	"error.game_full":              "И двамата играчи вече са се присъединили към играта.",
+	"error.waiting_for_opponent":   "Изчакване на втория играч да се присъедини.",
+	"error.game_over":              "Играта вече е приключила.",
+	"error.not_your_turn":          "Не си на ход.",
+	"error.invalid_move":           "Невалидна позиция: избери празно поле, номерирано като в boardDisplay.",
+	"error.wrong_game_mode":        "Действието не е достъпно в този режим на игра.",
+	"error.takebacks_disabled":     "В тази игра не може да се връщат ходове.",
+	"error.takeback_limit_reached": "Не остават връщания на ходове в тази игра.",
+	"error.nothing_to_undo":        "Няма ход за връщане.",
+	"error.too_many_games":         "Сървърът пази твърде много игри. Опитайте отново по-късно.",
+
+	"v2.move.placed":                "Компютърът постави {{.Piece}} на поле {{.Index}}.",
+	"error.v2.invalid_cells":        "Невалидно board.cells: Трябва да има board.size² полета, всяко \"x\", \"o\" или \"\" (празно), ред по ред от горния ляв ъгъл.",
+	"error.v2.illegal_piece_count":  "Невалиден брой фигури: Страната, която е започнала, трябва да има толкова фигури, колкото другата, или с една повече.",
+	"error.v2.ambiguous_board":      "Изпрати или board, или position, но не и двете.",
+	"error.v2.invalid_ai_player":    "Невалиден aiPlayer: Използвай \"x\" или \"o\" за страната на компютъра.",
+	"error.v2.invalid_first_player": "Невалиден firstPlayer: Използвай \"x\" или \"o\". По подразбиране е \"x\".",
+	"error.v2.invalid_difficulty":   "Невалидна трудност: Използвай \"easy\", \"medium\" или \"hard\". По подразбиране е \"hard\".",
+}
@@ -0,0 +1,66 @@
+package i18n
+
+var german = map[string]string{
+	"move.placed":         "Spieler {{.Player}} hat '{{.Piece}}' auf Feld {{.Position}} gesetzt.",
+	"move.game_over":      "Spiel vorbei.",
+	"move.invalid":        "Ungültiger Zug.",
+	"status.ongoing":      "{{.Piece}} ist am Zug.",
+	"status.player1_wins": "X gewinnt.",
+	"status.player2_wins": "O gewinnt.",
+	"status.draw":         "Das Spiel endet unentschieden.",
+
+	"error.invalid_request_body":   "Ungültiger Anfragetext.",
+	"error.unknown_field":          "Der Anfragetext enthält ein unbekanntes Feld.",
+	"error.invalid_field_type":     "Ein Feld des Anfragetexts hat den falschen Typ.",
+	"error.invalid_query":          "Ein Query-Parameter hat einen ungültigen Wert.",
+	"error.request_too_large":      "Der Anfragetext ist zu groß.",
+	"error.unsupported_media_type": "Nicht unterstützter Inhaltstyp: Sende den Anfragetext als application/json.",
+	"error.method_not_allowed":     "Methode nicht erlaubt.",
+	"error.not_found":              "Nicht gefunden.",
+	"error.rate_limited":           "Zu viele Anfragen. Bitte später erneut versuchen.",
+	"error.unauthorized":           "Fehlender oder ungültiger API-Schlüssel. Sende ihn im Authorization-Header als Bearer-Token.",
+	"error.feature_not_allowed":    "Der API-Schlüssel erlaubt diesen Endpunkt nicht.",
+	"error.search_timeout":         "Der KI ist die Zeit ausgegangen, einen Zug zu finden. Bitte erneut versuchen, oder mit einer niedrigeren Schwierigkeit oder einem kleineren Brett.",
+	"error.internal_error":         "Interner Serverfehler.",
+	"error.invalid_board_size":     "Die unterstützten Werte für boardSize sind 3, 4, 5 und 6.",
+	"error.invalid_board":          "Ungültiges Spielbrett: Es muss genau 9, 16, 25 oder 36 Zahlen (0, 1 oder 2) enthalten; 0 (leer), 1 (Spieler 1), 2 (Spieler 2); Züge von Spieler 1 >= Züge von Spieler 2; maximaler Unterschied: 1.",
+	"error.illegal_piece_count":    "Ungültiges Spielbrett: Es muss genau 9, 16, 25 oder 36 Zahlen (0, 1 oder 2) enthalten; 0 (leer), 1 (Spieler 1), 2 (Spieler 2); Züge von Spieler 1 >= Züge von Spieler 2; maximaler Unterschied: 1.",
+	"error.invalid_difficulty":     "Ungültige Schwierigkeit: Verwende 1 (Leicht), 2 (Mittel) oder 3 (Schwer). Standard ist 3 (Schwer).",
+	"error.invalid_position":       "Ungültige Stellung: Verwende Reihen aus 'x', 'o' und '.', getrennt durch '/', gefolgt von der Seite am Zug, z. B. \"x.o/.x./..o o\"; die Seite am Zug muss zur Anzahl von X und O passen.",
+	"error.ambiguous_board":        "Sende entweder board (mit boardSize) oder position, nicht beides.",
+	"error.invalid_last_move":      "Ungültiger lastMove: Verwende ein Feld von 1 bis n wie in boardDisplay oder 0 für keines.",
+	"error.invalid_format":         "Ungültiges Format: Verwende \"svg\" oder \"png\".",
+	"error.invalid_cell_size":      "Ungültige cellSize: Verwende eine Größe zwischen 16 und 256 Pixeln. Standard ist 100.",
+	"error.invalid_theme":          "Ungültiges Theme: Verwende \"light\", \"dark\" oder \"high-contrast\". Standard ist \"light\".",
+	"error.invalid_moves":          "Ungültige Züge: Verwende leere Felder von 1 bis n wie in boardDisplay, X zuerst, ohne Züge nach Spielende.",
+	"error.invalid_delay":          "Ungültiges delay: Verwende eine Verzögerung zwischen 100 und 5000 Millisekunden. Standard ist 800.",
+	"error.replay_too_large":       "Die Wiederholung ist zu groß: Verwende eine kleinere cellSize für ein Spiel mit so vielen Zügen.",
+	"error.render_timeout":         "Das Zeichnen der Wiederholung hat zu lange gedauert. Versuche es erneut mit einer kleineren cellSize.",
+	"error.invalid_display_style":  "Ungültiger displayStyle: Verwende \"classic\", \"unicode\", \"emoji\", \"compact\" oder \"markdown\". Standard ist \"classic\".",
+	"error.game_not_found":         "Spiel nicht gefunden.",
+	"error.invalid_game_mode":      "Ungültiger Modus: Verwende \"human\" (zwei Spieler über WebSocket) oder \"ai\" (gegen die KI). Standard ist \"human\".",
+	"error.invalid_ai_player":      "Ungültiger aiPlayer: Verwende 1 (X) oder 2 (O).",
+	"error.invalid_first_player":   "Ungültiger firstPlayer: Verwende 1 (X) oder 2 (O). Standard ist 1 (X).",
+	"error.not_ai_turn":            "Der angegebene Spieler ist nicht am Zug. Bitte sende den Zug des richtigen Spielers.",
+	"error.invalid_max_takebacks":  "Ungültige maxTakebacks: Muss 0 (unbegrenzt) oder eine positive Zahl sein.",
+	"error.invalid_game_action":    "Unbekannter Nachrichtentyp: Sende {\"type\": \"move\", \"position\": n} mit n nummeriert wie in boardDisplay.",
+	"error.invalid_record":         "Ungültige Partieaufzeichnung.",
+	"error.game_full":              "Beide Spieler sind dem Spiel bereits beigetreten.",
+	"error.waiting_for_opponent":   "Warte auf den zweiten Spieler.",
+	"error.game_over":              "Das Spiel ist bereits vorbei.",
+	"error.not_your_turn":          "Du bist nicht am Zug.",
+	"error.invalid_move":           "Ungültiges Feld: Wähle ein leeres Feld, nummeriert wie in boardDisplay.",
+	"error.wrong_game_mode":        "Die Aktion ist in diesem Spielmodus nicht verfügbar.",
+	"error.takebacks_disabled":     "In diesem Spiel können keine Züge zurückgenommen werden.",
+	"error.takeback_limit_reached": "In diesem Spiel sind keine Zugrücknahmen mehr übrig.",
+	"error.nothing_to_undo":        "Es gibt keinen Zug zum Zurücknehmen.",
+	"error.too_many_games":         "Der Server hält zu viele Partien. Bitte später erneut versuchen.",
+
+	"v2.move.placed":                "Die KI hat {{.Piece}} auf Feld {{.Index}} gesetzt.",
+	"error.v2.invalid_cells":        "Ungültige board.cells: Es müssen board.size² Felder sein, jedes \"x\", \"o\" oder \"\" (leer), Reihe für Reihe von oben links.",
+	"error.v2.illegal_piece_count":  "Ungültige Steinanzahl: Die Seite, die begonnen hat, muss gleich viele Steine wie die andere oder einen mehr haben.",
+	"error.v2.ambiguous_board":      "Sende entweder board oder position, nicht beides.",
+	"error.v2.invalid_ai_player":    "Ungültiger aiPlayer: Verwende \"x\" oder \"o\" für die Seite der KI.",
+	"error.v2.invalid_first_player": "Ungültiger firstPlayer: Verwende \"x\" oder \"o\". Standard ist \"x\".",
+	"error.v2.invalid_difficulty":   "Ungültige Schwierigkeit: Verwende \"easy\", \"medium\" oder \"hard\". Standard ist \"hard\".",
+}
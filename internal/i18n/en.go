@@ -0,0 +1,12 @@
+package i18n
+
+var english = map[string]string{
+	"move.placed":         "Player {{.Player}} has placed '{{.Piece}}' in position {{.Position}}.",
+	"move.game_over":      "Game Over.",
+	"move.invalid":        "Invalid move.",
+	"status.ongoing":      "{{.Piece}} to move.",
+	"status.player1_wins": "X wins.",
+	"status.player2_wins": "O wins.",
+	"status.draw":         "The game is a draw.",
+	"v2.move.placed":      "The AI placed {{.Piece}} in cell {{.Index}}.",
+}
@@ -0,0 +1,66 @@
+package i18n
+
+var spanish = map[string]string{
+	"move.placed":         "El jugador {{.Player}} ha colocado '{{.Piece}}' en la posición {{.Position}}.",
+	"move.game_over":      "Fin de la partida.",
+	"move.invalid":        "Movimiento no válido.",
+	"status.ongoing":      "Mueve {{.Piece}}.",
+	"status.player1_wins": "Gana X.",
+	"status.player2_wins": "Gana O.",
+	"status.draw":         "La partida termina en empate.",
+
+	"error.invalid_request_body":   "Cuerpo de la petición no válido.",
+	"error.unknown_field":          "El cuerpo de la solicitud tiene un campo desconocido.",
+	"error.invalid_field_type":     "Un campo del cuerpo de la solicitud tiene un tipo incorrecto.",
+	"error.invalid_query":          "Un parámetro de la consulta tiene un valor no válido.",
+	"error.request_too_large":      "El cuerpo de la solicitud es demasiado grande.",
+	"error.unsupported_media_type": "Tipo de contenido no admitido: envía el cuerpo de la solicitud como application/json.",
+	"error.method_not_allowed":     "Método no permitido.",
+	"error.not_found":              "No encontrado.",
+	"error.rate_limited":           "Demasiadas solicitudes. Inténtalo de nuevo más tarde.",
+	"error.unauthorized":           "Falta la clave de API o no es válida. Envíala en la cabecera Authorization como token bearer.",
+	"error.feature_not_allowed":    "La clave de API no permite este endpoint.",
+	"error.search_timeout":         "La IA se quedó sin tiempo para encontrar un movimiento. Inténtalo de nuevo, o con una dificultad menor o un tablero más pequeño.",
+	"error.internal_error":         "Error interno del servidor.",
+	"error.invalid_board_size":     "Los valores admitidos de boardSize son 3, 4, 5 y 6.",
+	"error.invalid_board":          "Tablero no válido: debe tener exactamente 9, 16, 25 o 36 números (0, 1 o 2); 0 (vacío), 1 (Jugador 1), 2 (Jugador 2); movimientos del Jugador 1 >= movimientos del Jugador 2; diferencia máxima: 1.",
+	"error.illegal_piece_count":    "Tablero no válido: debe tener exactamente 9, 16, 25 o 36 números (0, 1 o 2); 0 (vacío), 1 (Jugador 1), 2 (Jugador 2); movimientos del Jugador 1 >= movimientos del Jugador 2; diferencia máxima: 1.",
+	"error.invalid_difficulty":     "Dificultad no válida: usa 1 (Fácil), 2 (Media) o 3 (Difícil). Por defecto es 3 (Difícil).",
+	"error.invalid_position":       "Posición no válida: usa filas de 'x', 'o' y '.' separadas por '/' seguidas del turno, p. ej. \"x.o/.x./..o o\"; el turno debe corresponder al número de X y O.",
+	"error.ambiguous_board":        "Envía board (con boardSize) o position, pero no ambos.",
+	"error.invalid_last_move":      "lastMove no válido: usa una casilla numerada de 1 a n como en boardDisplay, o 0 para ninguna.",
+	"error.invalid_format":         "Formato no válido: usa \"svg\" o \"png\".",
+	"error.invalid_cell_size":      "cellSize no válido: usa un tamaño entre 16 y 256 píxeles. Por defecto es 100.",
+	"error.invalid_theme":          "Tema no válido: usa \"light\", \"dark\" o \"high-contrast\". Por defecto es \"light\".",
+	"error.invalid_moves":          "Movimientos no válidos: usa casillas vacías numeradas de 1 a n como en boardDisplay, empezando por X, sin movimientos después de terminar la partida.",
+	"error.invalid_delay":          "delay no válido: usa un retardo entre 100 y 5000 milisegundos. Por defecto es 800.",
+	"error.replay_too_large":       "La repetición es demasiado grande: usa un cellSize menor para una partida con tantos movimientos.",
+	"error.render_timeout":         "La repetición tardó demasiado en dibujarse. Inténtalo de nuevo con un cellSize menor.",
+	"error.invalid_display_style":  "displayStyle no válido: usa \"classic\", \"unicode\", \"emoji\", \"compact\" o \"markdown\". Por defecto es \"classic\".",
+	"error.game_not_found":         "Partida no encontrada.",
+	"error.invalid_game_mode":      "Modo no válido: usa \"human\" (dos jugadores por WebSocket) o \"ai\" (contra la IA). Por defecto es \"human\".",
+	"error.invalid_ai_player":      "aiPlayer no válido: usa 1 (X) o 2 (O).",
+	"error.invalid_first_player":   "firstPlayer no válido: usa 1 (X) o 2 (O). Por defecto es 1 (X).",
+	"error.not_ai_turn":            "No es el turno del jugador indicado. Envía el movimiento del jugador correcto.",
+	"error.invalid_max_takebacks":  "maxTakebacks no válido: debe ser 0 (ilimitado) o un número positivo.",
+	"error.invalid_game_action":    "Tipo de mensaje desconocido: envía {\"type\": \"move\", \"position\": n} con n numerado como en boardDisplay.",
+	"error.invalid_record":         "Registro de partida no válido.",
+	"error.game_full":              "Los dos jugadores ya se han unido a la partida.",
+	"error.waiting_for_opponent":   "Esperando a que se una el segundo jugador.",
+	"error.game_over":              "La partida ya ha terminado.",
+	"error.not_your_turn":          "No es tu turno.",
+	"error.invalid_move":           "Posición no válida: elige una casilla vacía numerada como en boardDisplay.",
+	"error.wrong_game_mode":        "La acción no está disponible en este modo de juego.",
+	"error.takebacks_disabled":     "En esta partida no se pueden deshacer movimientos.",
+	"error.takeback_limit_reached": "No quedan movimientos por deshacer en esta partida.",
+	"error.nothing_to_undo":        "No hay ningún movimiento que deshacer.",
+	"error.too_many_games":         "El servidor tiene demasiadas partidas. Inténtalo de nuevo más tarde.",
+
+	"v2.move.placed":                "La IA ha colocado {{.Piece}} en la casilla {{.Index}}.",
+	"error.v2.invalid_cells":        "board.cells no válido: debe tener board.size² casillas, cada una \"x\", \"o\" o \"\" (vacía), fila por fila desde arriba a la izquierda.",
+	"error.v2.illegal_piece_count":  "Número de piezas no válido: el bando que empezó debe tener tantas piezas como el otro o una más.",
+	"error.v2.ambiguous_board":      "Envía board o position, pero no ambos.",
+	"error.v2.invalid_ai_player":    "aiPlayer no válido: usa \"x\" o \"o\" para el bando de la IA.",
+	"error.v2.invalid_first_player": "firstPlayer no válido: usa \"x\" o \"o\". Por defecto es \"x\".",
+	"error.v2.invalid_difficulty":   "Dificultad no válida: usa \"easy\", \"medium\" o \"hard\". Por defecto es \"hard\".",
+}
@@ -0,0 +1,114 @@
+// Package i18n holds the message catalogue used for the text the API shows to
+// players, and picks the locale of a request.
+package i18n
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+const DefaultLocale = "en"
+
+// catalogues maps a locale to its message templates. Error messages are keyed
+// by "error." and the stable error code; the English ones live with the API
+// errors, so they are missing from the English catalogue.
+var catalogues = map[string]map[string]string{
+	"en": english,
+	"es": spanish,
+	"de": german,
+	"bg": bulgarian,
+}
+
+var templates = parseCatalogues()
+
+func parseCatalogues() map[string]map[string]*template.Template {
+	parsed := make(map[string]map[string]*template.Template, len(catalogues))
+	for locale, messages := range catalogues {
+		parsed[locale] = make(map[string]*template.Template, len(messages))
+		for key, message := range messages {
+			parsed[locale][key] = template.Must(template.New(locale + "/" + key).Option("missingkey=error").Parse(message))
+		}
+	}
+	return parsed
+}
+
+// Lookup renders the message for key in the locale, falling back to English.
+// It reports false when neither catalogue has the message.
+func Lookup(locale string, key string, data map[string]any) (string, bool) {
+	for _, l := range []string{locale, DefaultLocale} {
+		t, ok := templates[l][key]
+		if !ok {
+			continue
+		}
+		var b strings.Builder
+		if err := t.Execute(&b, data); err != nil {
+			continue
+		}
+		return b.String(), true
+	}
+	return "", false
+}
+
+// Text is like Lookup but returns the key itself for unknown messages.
+func Text(locale string, key string, data map[string]any) string {
+	if message, ok := Lookup(locale, key, data); ok {
+		return message
+	}
+	return key
+}
+
+// Match returns the supported locale for a language tag such as "es" or
+// "de-AT", matching on the primary language only.
+func Match(tag string) (string, bool) {
+	language, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+	language, _, _ = strings.Cut(language, "_")
+	if _, ok := catalogues[language]; ok {
+		return language, true
+	}
+	return "", false
+}
+
+// Negotiate picks the supported locale the client prefers most from an
+// Accept-Language header, or DefaultLocale if there is none.
+func Negotiate(acceptLanguage string) string {
+	type preference struct {
+		tag string
+		q   float64
+	}
+
+	var preferences []preference
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if tag != "" && q > 0 {
+			preferences = append(preferences, preference{tag, q})
+		}
+	}
+	sort.SliceStable(preferences, func(i, j int) bool { return preferences[i].q > preferences[j].q })
+
+	for _, p := range preferences {
+		if locale, ok := Match(p.tag); ok {
+			return locale
+		}
+	}
+	return DefaultLocale
+}
+
+// Locales lists the supported locales in alphabetical order.
+func Locales() []string {
+	locales := make([]string, 0, len(catalogues))
+	for locale := range catalogues {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
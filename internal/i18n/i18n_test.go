@@ -0,0 +1,89 @@
+package i18n
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	cases := []struct {
+		acceptLanguage string
+		want           string
+	}{
+		{"", "en"},
+		{"es", "es"},
+		{"de-DE,de;q=0.9,en;q=0.8", "de"},
+		{"fr-FR, bg;q=0.7, en;q=0.3", "bg"},
+		{"en;q=0.2, es;q=0.8", "es"},
+		{"bg;q=0, de", "de"},
+		{"fr, *;q=0.5", "en"},
+		{"BG_bg", "bg"},
+	}
+
+	for _, tc := range cases {
+		if got := Negotiate(tc.acceptLanguage); got != tc.want {
+			t.Errorf("Negotiate(%q) got %q want %q", tc.acceptLanguage, got, tc.want)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	data := map[string]any{"Player": 2, "Piece": "O", "Position": 5}
+
+	got, ok := Lookup("es", "move.placed", data)
+	if !ok || got != "El jugador 2 ha colocado 'O' en la posición 5." {
+		t.Errorf("got %q, %v", got, ok)
+	}
+
+	t.Run("falls back to English", func(t *testing.T) {
+		got, ok := Lookup("fr", "move.placed", data)
+		if !ok || got != "Player 2 has placed 'O' in position 5." {
+			t.Errorf("got %q, %v", got, ok)
+		}
+	})
+
+	t.Run("reports unknown messages", func(t *testing.T) {
+		if _, ok := Lookup("en", "error.invalid_board", nil); ok {
+			t.Errorf("got an English error message, those live with the API errors")
+		}
+		if got := Text("de", "no.such.key", nil); got != "no.such.key" {
+			t.Errorf("got %q want the key", got)
+		}
+	})
+}
+
+func TestCataloguesAreComplete(t *testing.T) {
+	errorKeys := keys(spanish, "error.")
+	for _, locale := range Locales() {
+		catalogue := catalogues[locale]
+
+		for key := range english {
+			if _, ok := catalogue[key]; !ok {
+				t.Errorf("%s: missing %s", locale, key)
+			}
+		}
+		if locale == DefaultLocale {
+			continue
+		}
+		if got := keys(catalogue, "error."); strings.Join(got, ",") != strings.Join(errorKeys, ",") {
+			t.Errorf("%s: got error messages %v want %v", locale, got, errorKeys)
+		}
+		for key, message := range catalogue {
+			if _, ok := Lookup(locale, key, map[string]any{"Player": 1, "Piece": "X", "Position": 1, "Index": 0}); !ok {
+				t.Errorf("%s: %s does not render: %q", locale, key, message)
+			}
+		}
+	}
+}
+
+func keys(catalogue map[string]string, prefix string) []string {
+	var keys []string
+	for key := range catalogue {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
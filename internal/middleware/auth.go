@@ -0,0 +1,168 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// APIKey is a bearer token that clients send in the Authorization header.
+type APIKey struct {
+	// Name identifies the client in logs and rate limits.
+	Name string `json:"name"`
+	Key  string `json:"key"`
+	// RateLimit and Burst override the rate limit of the server for the key
+	// when they are set.
+	RateLimit float64 `json:"rateLimit,omitempty"`
+	Burst     int     `json:"burst,omitempty"`
+	// Features are the features the key may use; every feature when there
+	// are none.
+	Features []string `json:"features,omitempty"`
+}
+
+// Allows reports whether the key may use the feature.
+func (k *APIKey) Allows(feature string) bool {
+	if len(k.Features) == 0 {
+		return true
+	}
+	for _, f := range k.Features {
+		if f == feature {
+			return true
+		}
+	}
+	return false
+}
+
+// AuthOptions configure API key authentication.
+type AuthOptions struct {
+	// Keys are the accepted API keys. Authentication is disabled when there
+	// are none.
+	Keys []APIKey
+	// Unauthorized responds to requests without a valid key once the
+	// WWW-Authenticate header is set. It defaults to a plain-text 401.
+	Unauthorized http.Handler
+	// Forbidden responds to requests whose key does not allow the feature.
+	// It defaults to a plain-text 403.
+	Forbidden http.Handler
+}
+
+// Authenticator checks the API keys of requests.
+type Authenticator struct {
+	options AuthOptions
+	// keys are looked up by their hash so that the time a lookup takes does
+	// not tell how much of a key is right.
+	keys map[[sha256.Size]byte]*APIKey
+}
+
+// NewAuthenticator returns an authenticator for the keys, or nil when there
+// are none. A nil Authenticator lets every request through. Keys need a
+// unique name and a unique, non-empty key.
+func NewAuthenticator(options AuthOptions) (*Authenticator, error) {
+	if len(options.Keys) == 0 {
+		return nil, nil
+	}
+	if options.Unauthorized == nil {
+		options.Unauthorized = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		})
+	}
+	if options.Forbidden == nil {
+		options.Forbidden = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		})
+	}
+
+	a := &Authenticator{options: options, keys: map[[sha256.Size]byte]*APIKey{}}
+	names := map[string]bool{}
+	for i := range options.Keys {
+		key := &options.Keys[i]
+		switch {
+		case key.Name == "":
+			return nil, fmt.Errorf("API key %d has no name", i+1)
+		case names[key.Name]:
+			return nil, fmt.Errorf("API key name %q is used twice", key.Name)
+		case strings.TrimSpace(key.Key) == "" || strings.ContainsAny(key.Key, " \t,"):
+			return nil, fmt.Errorf("API key %q is empty or has spaces or commas", key.Name)
+		case key.RateLimit < 0 || key.Burst < 0:
+			return nil, fmt.Errorf("API key %q has a negative rate limit", key.Name)
+		}
+		hash := sha256.Sum256([]byte(key.Key))
+		if _, ok := a.keys[hash]; ok {
+			return nil, fmt.Errorf("API key %q has the key of another one", key.Name)
+		}
+		names[key.Name] = true
+		a.keys[hash] = key
+	}
+	return a, nil
+}
+
+// Require lets requests through to next when they carry a valid key that
+// allows the feature, with the key in their context.
+func (a *Authenticator) Require(feature string, next http.Handler) http.Handler {
+	if a == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, ok := a.authenticate(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+			a.options.Unauthorized.ServeHTTP(w, r)
+			return
+		}
+		if !key.Allows(feature) {
+			a.options.Forbidden.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), apiKeyKey{}, key)))
+	})
+}
+
+func (a *Authenticator) authenticate(r *http.Request) (*APIKey, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return nil, false
+	}
+	key, ok := a.keys[sha256.Sum256([]byte(strings.TrimSpace(token)))]
+	return key, ok
+}
+
+type apiKeyKey struct{}
+
+// AuthenticatedKey returns the API key of a request that passed Require, or
+// nil.
+func AuthenticatedKey(r *http.Request) *APIKey {
+	key, _ := r.Context().Value(apiKeyKey{}).(*APIKey)
+	return key
+}
+
+// LoadAPIKeys reads API keys from a JSON file holding an array of keys, e.g.
+//
+//	[{"name": "chatgpt", "key": "...", "rateLimit": 5, "features": ["moves"]}]
+func LoadAPIKeys(path string) ([]APIKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var keys []APIKey
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("invalid API keys file %s: %w", path, err)
+	}
+	return keys, nil
+}
+
+// ParseAPIKeys reads a comma-separated list of keys, named after their
+// place in the list, with the default quota and every feature.
+func ParseAPIKeys(list string) []APIKey {
+	var keys []APIKey
+	for _, key := range strings.Split(list, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, APIKey{Name: "key-" + strconv.Itoa(len(keys)+1), Key: key})
+		}
+	}
+	return keys
+}
@@ -0,0 +1,124 @@
+// Package middleware wraps the handlers of the server with behaviour shared
+// by every route.
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSOptions configure Cross-Origin Resource Sharing. Browsers on an
+// origin that is not allowed get no CORS headers and block the response.
+type CORSOptions struct {
+	// AllowedOrigins are origins such as https://example.com, or "*" for
+	// any origin. CORS is disabled when there are none.
+	AllowedOrigins []string
+	// AllowedMethods default to DefaultCORSMethods.
+	AllowedMethods []string
+	// AllowedHeaders are the request headers a browser may send, besides the
+	// CORS-safelisted ones. They default to DefaultCORSHeaders.
+	AllowedHeaders []string
+	// ExposedHeaders are the response headers scripts may read, besides the
+	// CORS-safelisted ones.
+	ExposedHeaders []string
+	// MaxAge is how long browsers may cache a preflight response. It is
+	// rounded down to seconds; zero leaves it to the browser.
+	MaxAge time.Duration
+}
+
+var (
+	DefaultCORSMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodOptions}
+	DefaultCORSHeaders = []string{"Accept", "Accept-Language", "Authorization", "Content-Type"}
+)
+
+// DefaultCORSMaxAge is the preflight cache time of the server.
+const DefaultCORSMaxAge = 10 * time.Minute
+
+// CORS answers preflight requests from allowed origins and adds the CORS
+// headers to their other requests. Requests without an Origin header pass
+// through untouched.
+func CORS(options CORSOptions, next http.Handler) http.Handler {
+	if len(options.AllowedOrigins) == 0 {
+		return next
+	}
+	if len(options.AllowedMethods) == 0 {
+		options.AllowedMethods = DefaultCORSMethods
+	}
+	if len(options.AllowedHeaders) == 0 {
+		options.AllowedHeaders = DefaultCORSHeaders
+	}
+
+	anyOrigin := false
+	origins := map[string]bool{}
+	for _, origin := range options.AllowedOrigins {
+		if origin == "*" {
+			anyOrigin = true
+		}
+		origins[strings.ToLower(strings.TrimSuffix(origin, "/"))] = true
+	}
+	methods := strings.Join(options.AllowedMethods, ", ")
+	headers := strings.Join(options.AllowedHeaders, ", ")
+	exposed := strings.Join(options.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(options.MaxAge / time.Second))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The answer depends on the origin unless every origin is allowed
+		if !anyOrigin {
+			w.Header().Add("Vary", "Origin")
+		}
+		origin := r.Header.Get("Origin")
+		if origin == "" || (!anyOrigin && !origins[strings.ToLower(origin)]) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		allowOrigin := origin
+		if anyOrigin {
+			allowOrigin = "*"
+		}
+
+		requestMethod := r.Header.Get("Access-Control-Request-Method")
+		if r.Method == http.MethodOptions && requestMethod != "" {
+			w.Header().Add("Vary", "Access-Control-Request-Method")
+			w.Header().Add("Vary", "Access-Control-Request-Headers")
+			if containsFold(options.AllowedMethods, requestMethod) && allowedHeaders(options.AllowedHeaders, r.Header.Get("Access-Control-Request-Headers")) {
+				w.Header().Set("Access-Control-Allow-Origin", allowOrigin)
+				w.Header().Set("Access-Control-Allow-Methods", methods)
+				w.Header().Set("Access-Control-Allow-Headers", headers)
+				if options.MaxAge > 0 {
+					w.Header().Set("Access-Control-Max-Age", maxAge)
+				}
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", allowOrigin)
+		if exposed != "" {
+			w.Header().Set("Access-Control-Expose-Headers", exposed)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allowedHeaders reports whether every header of a comma-separated
+// Access-Control-Request-Headers value is allowed.
+func allowedHeaders(allowed []string, requested string) bool {
+	for _, header := range strings.Split(requested, ",") {
+		if header = strings.TrimSpace(header); header != "" && !containsFold(allowed, header) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCORS(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("next"))
+	})
+	options := CORSOptions{
+		AllowedOrigins: []string{"https://app.example.com"},
+		ExposedHeaders: []string{"Location"},
+		MaxAge:         10 * time.Minute,
+	}
+
+	cases := []struct {
+		name        string
+		options     CORSOptions
+		method      string
+		headers     map[string]string
+		wantStatus  int
+		wantBody    string
+		wantHeaders map[string]string
+	}{
+		{
+			"preflight", options, http.MethodOptions,
+			map[string]string{"Origin": "https://app.example.com", "Access-Control-Request-Method": "POST", "Access-Control-Request-Headers": "content-type"},
+			http.StatusNoContent, "",
+			map[string]string{
+				"Access-Control-Allow-Origin":  "https://app.example.com",
+				"Access-Control-Allow-Methods": "GET, HEAD, POST, OPTIONS",
+				"Access-Control-Allow-Headers": "Accept, Accept-Language, Authorization, Content-Type",
+				"Access-Control-Max-Age":       "600",
+				"Vary":                         "Origin",
+			},
+		},
+		{
+			"preflight for a method that is not allowed", options, http.MethodOptions,
+			map[string]string{"Origin": "https://app.example.com", "Access-Control-Request-Method": "DELETE"},
+			http.StatusNoContent, "",
+			map[string]string{"Access-Control-Allow-Origin": "", "Access-Control-Allow-Methods": ""},
+		},
+		{
+			"preflight with a header that is not allowed", options, http.MethodOptions,
+			map[string]string{"Origin": "https://app.example.com", "Access-Control-Request-Method": "POST", "Access-Control-Request-Headers": "X-Secret"},
+			http.StatusNoContent, "",
+			map[string]string{"Access-Control-Allow-Origin": ""},
+		},
+		{
+			"request from an allowed origin", options, http.MethodPost,
+			map[string]string{"Origin": "https://app.example.com"},
+			http.StatusOK, "next",
+			map[string]string{"Access-Control-Allow-Origin": "https://app.example.com", "Access-Control-Expose-Headers": "Location", "Vary": "Origin"},
+		},
+		{
+			"request from another origin", options, http.MethodPost,
+			map[string]string{"Origin": "https://evil.example.com"},
+			http.StatusOK, "next",
+			map[string]string{"Access-Control-Allow-Origin": "", "Vary": "Origin"},
+		},
+		{
+			"options without a preflight", options, http.MethodOptions,
+			map[string]string{"Origin": "https://app.example.com"},
+			http.StatusOK, "next",
+			map[string]string{"Access-Control-Allow-Origin": "https://app.example.com"},
+		},
+		{
+			"request without an origin", options, http.MethodGet,
+			nil,
+			http.StatusOK, "next",
+			map[string]string{"Access-Control-Allow-Origin": ""},
+		},
+		{
+			"any origin", CORSOptions{AllowedOrigins: []string{"*"}, AllowedMethods: []string{"POST"}}, http.MethodOptions,
+			map[string]string{"Origin": "https://other.example.com", "Access-Control-Request-Method": "POST"},
+			http.StatusNoContent, "",
+			map[string]string{"Access-Control-Allow-Origin": "*", "Access-Control-Allow-Methods": "POST", "Access-Control-Max-Age": "", "Vary": "Access-Control-Request-Method"},
+		},
+		{
+			"disabled", CORSOptions{}, http.MethodOptions,
+			map[string]string{"Origin": "https://app.example.com", "Access-Control-Request-Method": "POST"},
+			http.StatusOK, "next",
+			map[string]string{"Access-Control-Allow-Origin": "", "Vary": ""},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, "/v1/tictactoe", nil)
+			for name, value := range tc.headers {
+				req.Header.Set(name, value)
+			}
+			recorder := httptest.NewRecorder()
+			CORS(tc.options, next).ServeHTTP(recorder, req)
+
+			if recorder.Code != tc.wantStatus || recorder.Body.String() != tc.wantBody {
+				t.Errorf("got %d %q want %d %q", recorder.Code, recorder.Body, tc.wantStatus, tc.wantBody)
+			}
+			got := map[string]string{}
+			for name := range tc.wantHeaders {
+				got[name] = recorder.Header().Get(name)
+			}
+			if !reflect.DeepEqual(got, tc.wantHeaders) {
+				t.Errorf("got headers %v want %v", got, tc.wantHeaders)
+			}
+		})
+	}
+}
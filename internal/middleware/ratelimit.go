@@ -0,0 +1,193 @@
+package middleware
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimitOptions configure token-bucket rate limiting. Every client has a
+// bucket of Burst tokens that refills at Rate tokens per second; a request
+// takes its cost in tokens or is rejected with 429 Too Many Requests.
+type RateLimitOptions struct {
+	// Rate is the number of tokens added to a bucket per second. Rate
+	// limiting is disabled when it is zero.
+	Rate float64
+	// Burst is the size of a bucket. It defaults to DefaultRateLimitBurst.
+	Burst int
+	// Key names the bucket of a request. It defaults to the client IP.
+	Key func(r *http.Request) string
+	// Quota returns the rate and burst of the bucket of a request in place
+	// of Rate and Burst, when ok. Zero values keep the defaults.
+	Quota func(r *http.Request) (rate float64, burst int, ok bool)
+	// Limited responds to rejected requests once the Retry-After and
+	// RateLimit-* headers are set. It defaults to a plain-text 429.
+	Limited http.Handler
+}
+
+// DefaultRateLimitBurst is the bucket size of the server.
+const DefaultRateLimitBurst = 60
+
+// RateLimiter shares the buckets of the clients between the routes it
+// limits, so that every request of a client draws on the same tokens.
+type RateLimiter struct {
+	options RateLimitOptions
+	now     func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	rate   float64
+	burst  float64
+}
+
+// sweepInterval is how often full buckets are dropped. A full bucket is the
+// same as no bucket.
+const sweepInterval = time.Minute
+
+// NewRateLimiter returns a rate limiter, or nil when options.Rate is zero. A
+// nil RateLimiter limits nothing.
+func NewRateLimiter(options RateLimitOptions) *RateLimiter {
+	if options.Rate <= 0 {
+		return nil
+	}
+	if options.Burst <= 0 {
+		options.Burst = DefaultRateLimitBurst
+	}
+	if options.Key == nil {
+		options.Key = ClientIP
+	}
+	if options.Limited == nil {
+		options.Limited = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+		})
+	}
+	return &RateLimiter{options: options, now: time.Now, buckets: map[string]*bucket{}}
+}
+
+// Limit charges each request the tokens returned by cost, or one token when
+// cost is nil, before passing it to next. Costs above the bucket size are
+// charged as a full bucket. Every response carries the RateLimit-Limit,
+// RateLimit-Remaining and RateLimit-Reset headers, and rejected ones
+// Retry-After.
+func (l *RateLimiter) Limit(cost func(r *http.Request) int, next http.Handler) http.Handler {
+	if l == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := 1
+		if cost != nil {
+			n = cost(r)
+		}
+		rate, burst := l.options.Rate, l.options.Burst
+		if l.options.Quota != nil {
+			if quotaRate, quotaBurst, ok := l.options.Quota(r); ok {
+				if quotaRate > 0 {
+					rate = quotaRate
+				}
+				if quotaBurst > 0 {
+					burst = quotaBurst
+				}
+			}
+		}
+		remaining, retryAfter, reset := l.take(l.options.Key(r), n, rate, burst)
+
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(burst))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(remaining))
+		w.Header().Set("RateLimit-Reset", strconv.Itoa(seconds(reset)))
+		if retryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(seconds(retryAfter)))
+			l.options.Limited.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// take takes n tokens from the bucket of key, which refills at rate up to
+// burst tokens. It returns the whole tokens left, how long to wait for n
+// tokens when there are too few (or zero), and how long until the bucket is
+// full.
+func (l *RateLimiter) take(key string, n int, rate float64, burst int) (int, time.Duration, time.Duration) {
+	cost := math.Max(math.Min(float64(n), float64(burst)), 0)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.Sub(l.lastSweep) >= sweepInterval {
+		for k, b := range l.buckets {
+			if b.refill(now) == b.burst {
+				delete(l.buckets, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(burst), last: now}
+		l.buckets[key] = b
+	}
+	b.rate, b.burst = rate, float64(burst)
+	tokens := b.refill(now)
+
+	var retryAfter time.Duration
+	if tokens < cost {
+		retryAfter = b.duration(cost - tokens)
+	} else {
+		tokens -= cost
+	}
+	b.tokens, b.last = tokens, now
+	return int(tokens), retryAfter, b.duration(b.burst - tokens)
+}
+
+// refill returns the tokens of the bucket at now.
+func (b *bucket) refill(now time.Time) float64 {
+	return math.Min(b.tokens+now.Sub(b.last).Seconds()*b.rate, b.burst)
+}
+
+// duration is how long the bucket takes to gain the tokens.
+func (b *bucket) duration(tokens float64) time.Duration {
+	return time.Duration(tokens / b.rate * float64(time.Second))
+}
+
+// seconds rounds a positive duration up to whole seconds, as the headers
+// count in seconds and a client retrying early would be rejected again.
+func seconds(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
+}
+
+// ClientIP returns the IP address of the client that sent the request.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// ForwardedClientIP returns the client IP that the proxy in front of the
+// server appended to X-Forwarded-For, or ClientIP without one. Use it only
+// behind a proxy, as clients can send the header themselves; earlier entries
+// of the header are for that reason ignored.
+func ForwardedClientIP(r *http.Request) string {
+	forwarded := r.Header.Values("X-Forwarded-For")
+	if len(forwarded) == 0 {
+		return ClientIP(r)
+	}
+	entries := strings.Split(forwarded[len(forwarded)-1], ",")
+	if ip := strings.TrimSpace(entries[len(entries)-1]); ip != "" {
+		return ip
+	}
+	return ClientIP(r)
+}
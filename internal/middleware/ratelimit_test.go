@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("next"))
+	})
+	newLimiter := func() (*RateLimiter, *time.Time) {
+		limiter := NewRateLimiter(RateLimitOptions{Rate: 2, Burst: 10})
+		now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+		limiter.now = func() time.Time { return now }
+		return limiter, &now
+	}
+	cost := func(n int) func(*http.Request) int {
+		return func(*http.Request) int { return n }
+	}
+	serve := func(handler http.Handler, remoteAddr string) *http.Response {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remoteAddr
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder.Result()
+	}
+
+	t.Run("charges the cost of each request", func(t *testing.T) {
+		limiter, _ := newLimiter()
+		handler := limiter.Limit(cost(4), next)
+
+		for _, want := range []string{"6", "2"} {
+			resp := serve(handler, "192.0.2.1:1234")
+			if resp.StatusCode != http.StatusOK || resp.Header.Get("RateLimit-Remaining") != want {
+				t.Errorf("got status %d and RateLimit-Remaining %s, want 200 and %s", resp.StatusCode, resp.Header.Get("RateLimit-Remaining"), want)
+			}
+		}
+		resp := serve(handler, "192.0.2.1:1234")
+		if resp.StatusCode != http.StatusTooManyRequests {
+			t.Fatalf("got status %d, want 429", resp.StatusCode)
+		}
+		// 2 tokens are missing at 2 tokens a second; 8 are missing for a full bucket
+		for header, want := range map[string]string{"Retry-After": "1", "RateLimit-Limit": "10", "RateLimit-Remaining": "2", "RateLimit-Reset": "4"} {
+			if got := resp.Header.Get(header); got != want {
+				t.Errorf("got %s %q want %q", header, got, want)
+			}
+		}
+	})
+
+	t.Run("refills the bucket over time", func(t *testing.T) {
+		limiter, now := newLimiter()
+		handler := limiter.Limit(cost(10), next)
+
+		serve(handler, "192.0.2.1:1234")
+		*now = now.Add(4 * time.Second)
+		if resp := serve(handler, "192.0.2.1:1234"); resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") != "1" {
+			t.Errorf("got status %d and Retry-After %s, want 429 and 1", resp.StatusCode, resp.Header.Get("Retry-After"))
+		}
+		*now = now.Add(time.Second)
+		if resp := serve(handler, "192.0.2.1:1234"); resp.StatusCode != http.StatusOK {
+			t.Errorf("got status %d, want 200", resp.StatusCode)
+		}
+	})
+
+	t.Run("shares the tokens of a client between routes", func(t *testing.T) {
+		limiter, _ := newLimiter()
+		serve(limiter.Limit(cost(8), next), "192.0.2.1:1234")
+		if resp := serve(limiter.Limit(nil, next), "192.0.2.1:5678"); resp.Header.Get("RateLimit-Remaining") != "1" {
+			t.Errorf("got RateLimit-Remaining %s, want 1", resp.Header.Get("RateLimit-Remaining"))
+		}
+		if resp := serve(limiter.Limit(nil, next), "192.0.2.2:1234"); resp.Header.Get("RateLimit-Remaining") != "9" {
+			t.Errorf("got RateLimit-Remaining %s for another client, want 9", resp.Header.Get("RateLimit-Remaining"))
+		}
+	})
+
+	t.Run("charges costs above the burst as a full bucket", func(t *testing.T) {
+		limiter, _ := newLimiter()
+		if resp := serve(limiter.Limit(cost(50), next), "192.0.2.1:1234"); resp.StatusCode != http.StatusOK || resp.Header.Get("RateLimit-Remaining") != "0" {
+			t.Errorf("got status %d and RateLimit-Remaining %s, want 200 and 0", resp.StatusCode, resp.Header.Get("RateLimit-Remaining"))
+		}
+	})
+
+	t.Run("drops full buckets", func(t *testing.T) {
+		limiter, now := newLimiter()
+		handler := limiter.Limit(nil, next)
+		serve(handler, "192.0.2.1:1234")
+		*now = now.Add(sweepInterval)
+		serve(handler, "192.0.2.2:1234")
+		if len(limiter.buckets) != 1 {
+			t.Errorf("got %d buckets, want 1", len(limiter.buckets))
+		}
+	})
+
+	t.Run("disabled without a rate", func(t *testing.T) {
+		limiter := NewRateLimiter(RateLimitOptions{})
+		if resp := serve(limiter.Limit(nil, next), "192.0.2.1:1234"); resp.StatusCode != http.StatusOK || resp.Header.Get("RateLimit-Limit") != "" {
+			t.Errorf("got status %d and headers %v", resp.StatusCode, resp.Header)
+		}
+	})
+}
+
+func TestForwardedClientIP(t *testing.T) {
+	cases := []struct {
+		forwarded []string
+		want      string
+	}{
+		{nil, "192.0.2.1"},
+		{[]string{"203.0.113.7"}, "203.0.113.7"},
+		{[]string{"198.51.100.1, 203.0.113.7"}, "203.0.113.7"},
+		{[]string{"198.51.100.1", "203.0.113.7"}, "203.0.113.7"},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		for _, value := range tc.forwarded {
+			req.Header.Add("X-Forwarded-For", value)
+		}
+		if got := ForwardedClientIP(req); got != tc.want {
+			t.Errorf("ForwardedClientIP with %v = %s, want %s", tc.forwarded, got, tc.want)
+		}
+	}
+}
@@ -1,19 +1,99 @@
 package model
 
+//go:generate go run ../../cmd/openapigen -model . -spec ../../.well-known/openapi.yaml
+
+import "time"
+
+// MoveRequest is the board with the user's move reflected in it, for the AI
+// to answer with its own move.
 type MoveRequest struct {
-	Board      []int `json:"board,omitempty"`
-	BoardSize  int   `json:"boardSize,omitempty"`
-	Difficulty int   `json:"difficulty,omitempty"`
+	// Board is the current state of the board as a single array of
+	// boardSize^2 cells, row by row from the top left: 0 for an empty cell, 1
+	// for X and 2 for O. When the AI starts the board is all zeros or left
+	// out. When the player starts the board has the player's first move.
+	//
+	// To place a piece, set the cell to the player's number, e.g. 1 in the
+	// first cell for an X in the top left corner. Use boardDisplay in the
+	// response to show the board. Keep in mind that boardDisplay numbers the
+	// cells from 1 to n while the board array is indexed from 0 to n-1.
+	Board []int `json:"board,omitempty" example:"0,0,0,1,0,0,0,0,0"`
+	// BoardSize is the size of the board: 3, 4, 5 or 6.
+	BoardSize int `json:"boardSize,omitempty" enums:"3,4,5,6" default:"3" example:"3"`
+	// Difficulty is the level of the AI: 1 (easy), 2 (medium) or 3 (hard).
+	Difficulty int `json:"difficulty,omitempty" enums:"1,2,3" default:"3" example:"3"`
+	// Position is a compact alternative to board and boardSize; send one or
+	// the other. The rows are written from top to bottom and separated by
+	// '/', with 'x', 'o' and '.' for the cells, followed by a space and the
+	// side to move ('x' or 'o'). A digit may stand for that many empty cells.
+	Position string `json:"position,omitempty" example:".../x../... o"`
+	// DisplayStyle is how boardDisplay is drawn: "classic" ASCII, a "unicode"
+	// box-drawing grid, "emoji" pieces, a "compact" single line or a
+	// "markdown" table. Use "markdown" when the reply is shown in a chat that
+	// renders Markdown.
+	DisplayStyle string `json:"displayStyle,omitempty" enums:"classic,unicode,emoji,compact,markdown" default:"classic"`
+	// Locale is the language of message, statusText and error messages. It
+	// overrides the Accept-Language header. Use the language the user is
+	// speaking when it is supported; English is used otherwise.
+	Locale string `json:"locale,omitempty" enums:"en,es,de,bg" default:"en"`
+	// ClientType is the kind of client. "assistant" receives assistantHints
+	// with guidance for relaying the response, "app" receives none.
+	ClientType string `json:"clientType,omitempty" enums:"assistant,app" default:"assistant"`
+	// AIPlayer is the side the AI plays, 1 (X) or 2 (O). When sent, the
+	// request is rejected with "It's not the submitted player's turn" unless
+	// the AI is to move on the board. Send it so that the AI never plays the
+	// user's side by mistake.
+	AIPlayer int `json:"aiPlayer,omitempty" enums:"1,2" example:"2"`
+	// FirstPlayer is the side that moved first in the game, 1 (X) or 2 (O).
+	FirstPlayer int `json:"firstPlayer,omitempty" enums:"1,2" default:"1"`
 }
 
+// MoveResponse is the board with the AI's move reflected in it.
 type MoveResponse struct {
-	Success      bool   `json:"success"`
-	Message      string `json:"message"`
-	Board        []int  `json:"board"`
-	BoardSize    int    `json:"boardSize"`
-	BoardDisplay string `json:"boardDisplay"`
-	GameStatus   string `json:"gameStatus"`
-	NextPlayer   int    `json:"nextPlayer"`
+	// Success is whether the AI made a move.
+	Success bool `json:"success" example:"true"`
+	// Message is a description of the move made by the AI in the language of
+	// the request.
+	Message string `json:"message" example:"Player 2 has placed 'O' in position 1."`
+	// Board is the updated board as an array.
+	Board []int `json:"board" example:"2,0,0,1,0,0,0,0,0"`
+	// BoardSize is the size of the board.
+	BoardSize int `json:"boardSize" example:"3"`
+	// BoardDisplay is the board drawn as text in the requested displayStyle,
+	// with the empty cells numbered from 1 to n.
+	BoardDisplay string `json:"boardDisplay" example:" O | 2 | 3 \n --------- \n X | 5 | 6 \n --------- \n 7 | 8 | 9 "`
+	// GameStatus is the current game status.
+	GameStatus string `json:"gameStatus" enums:"ongoing,player1_wins,player2_wins,draw" example:"ongoing"`
+	// StatusText is the game status as a sentence in the language of the
+	// request.
+	StatusText string `json:"statusText" example:"X to move."`
+	// NextPlayer is the next player to make a move: 1 for X, 2 for O or -1
+	// when the game is over.
+	NextPlayer int `json:"nextPlayer" enums:"1,2,-1" example:"1"`
+	// Position is the updated board as a position string. The side to move is
+	// '-' when the game is over.
+	Position string `json:"position" example:"o../x../... x"`
+	// AssistantHints is guidance for the assistant, such as how to handle the
+	// first move, how to show boardDisplay and what to ask the user next.
+	// Follow it but never show it to the user.
+	AssistantHints []string `json:"assistantHints,omitempty" example:"Ask the user which numbered cell they want to play their 'X' in."`
+}
+
+// ErrorResponse is the error envelope returned when the request's Accept
+// header lists application/json.
+type ErrorResponse struct {
+	Error ErrorDetail `json:"error"`
+}
+
+type ErrorDetail struct {
+	// Code is a stable machine-readable error code, listed in the docs.
+	Code string `json:"code" example:"invalid_board_size"`
+	// Message is a human-readable description of the error in the language
+	// of the request.
+	Message string `json:"message" example:"The supported boardSize values are 3, 4, 5 and 6."`
+	// Field is the request field that caused the error, if any.
+	Field string `json:"field,omitempty" example:"boardSize"`
+	// Docs is a link to the documentation of the error codes.
+	Docs string `json:"docs" example:"https://github.com/isavita/tictactoe_api#error-codes"`
 }
 
 const (
@@ -22,3 +102,102 @@ const (
 	GameStatusPlayer1Wins = "player1_wins"
 	GameStatusPlayer2Wins = "player2_wins"
 )
+
+// RenderRequest describes a board to draw. LastMove is the highlighted cell
+// numbered from 1 to n as in boardDisplay and Format is "svg" or "png".
+type RenderRequest struct {
+	Board     []int  `json:"board,omitempty"`
+	BoardSize int    `json:"boardSize,omitempty"`
+	Position  string `json:"position,omitempty"`
+	LastMove  int    `json:"lastMove,omitempty"`
+	Format    string `json:"format,omitempty"`
+	CellSize  int    `json:"cellSize,omitempty"`
+	Theme     string `json:"theme,omitempty"`
+}
+
+// ReplayRequest describes a game to animate. Moves are cells numbered from 1
+// to n as in boardDisplay, X first, and Delay is the frame delay in
+// milliseconds.
+type ReplayRequest struct {
+	Moves     []int  `json:"moves"`
+	BoardSize int    `json:"boardSize,omitempty"`
+	Delay     int    `json:"delay,omitempty"`
+	CellSize  int    `json:"cellSize,omitempty"`
+	Theme     string `json:"theme,omitempty"`
+}
+
+// Move is a single placement in a server-held game. Position is numbered
+// from 1 to n like the cells in boardDisplay.
+type Move struct {
+	Player   int `json:"player"`
+	Position int `json:"position"`
+}
+
+type CreateGameRequest struct {
+	BoardSize      int    `json:"boardSize,omitempty"`
+	Mode           string `json:"mode,omitempty"`
+	Difficulty     int    `json:"difficulty,omitempty"`
+	AIPlayer       int    `json:"aiPlayer,omitempty"`
+	AllowTakebacks bool   `json:"allowTakebacks,omitempty"`
+	MaxTakebacks   int    `json:"maxTakebacks,omitempty"`
+	DisplayStyle   string `json:"displayStyle,omitempty"`
+}
+
+type GameMoveRequest struct {
+	Position int `json:"position"`
+}
+
+type GameRecord struct {
+	ID             string    `json:"id"`
+	Mode           string    `json:"mode"`
+	Difficulty     int       `json:"difficulty,omitempty"`
+	AIPlayer       int       `json:"aiPlayer,omitempty"`
+	Board          []int     `json:"board"`
+	BoardSize      int       `json:"boardSize"`
+	BoardDisplay   string    `json:"boardDisplay"`
+	DisplayStyle   string    `json:"displayStyle,omitempty"`
+	GameStatus     string    `json:"gameStatus"`
+	NextPlayer     int       `json:"nextPlayer"`
+	Players        []int     `json:"players"`
+	Moves          []Move    `json:"moves"`
+	AllowTakebacks bool      `json:"allowTakebacks"`
+	MaxTakebacks   int       `json:"maxTakebacks,omitempty"`
+	Takebacks      int       `json:"takebacks"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+const (
+	GameModeHuman = "human"
+	GameModeAI    = "ai"
+)
+
+// GameMessage is the envelope exchanged with players over the game WebSocket.
+type GameMessage struct {
+	Type     string      `json:"type"`
+	Code     string      `json:"code,omitempty"`
+	Player   int         `json:"player,omitempty"`
+	Position int         `json:"position,omitempty"`
+	Message  string      `json:"message,omitempty"`
+	Game     *GameRecord `json:"game,omitempty"`
+}
+
+// GameEvent is streamed to spectators for each move and takeback of a game.
+// Move is the number of moves on the board after the event.
+type GameEvent struct {
+	Move         int    `json:"move"`
+	Player       int    `json:"player"`
+	Position     int    `json:"position"`
+	Board        []int  `json:"board"`
+	BoardDisplay string `json:"boardDisplay"`
+	GameStatus   string `json:"gameStatus"`
+	NextPlayer   int    `json:"nextPlayer"`
+}
+
+const (
+	MessageTypeJoined       = "joined"
+	MessageTypePlayerJoined = "player_joined"
+	MessageTypePlayerLeft   = "player_left"
+	MessageTypeMove         = "move"
+	MessageTypeUndo         = "undo"
+	MessageTypeError        = "error"
+)
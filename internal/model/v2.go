@@ -0,0 +1,62 @@
+package model
+
+// The v2 API names sides "x" and "o", numbers cells from 0 in row-major order
+// and always answers with JSON, including errors.
+
+const (
+	SideX = "x"
+	SideO = "o"
+)
+
+const (
+	StatusV2Ongoing = "ongoing"
+	StatusV2Won     = "won"
+	StatusV2Draw    = "draw"
+)
+
+const (
+	DifficultyV2Easy   = "easy"
+	DifficultyV2Medium = "medium"
+	DifficultyV2Hard   = "hard"
+)
+
+// BoardV2 lists the cells row by row from the top left, each "x", "o" or ""
+// for an empty cell.
+type BoardV2 struct {
+	Size  int      `json:"size"`
+	Cells []string `json:"cells"`
+}
+
+// MoveRequestV2 is the body of POST /v2/moves. The board is given either as
+// Board or as a Position string. AIPlayer is required; FirstPlayer defaults
+// to "x" and Difficulty to "hard".
+type MoveRequestV2 struct {
+	Board       *BoardV2 `json:"board,omitempty"`
+	Position    string   `json:"position,omitempty"`
+	AIPlayer    string   `json:"aiPlayer"`
+	FirstPlayer string   `json:"firstPlayer,omitempty"`
+	Difficulty  string   `json:"difficulty,omitempty"`
+	Locale      string   `json:"locale,omitempty"`
+}
+
+// MoveV2 is a placed piece. Index is the cell in BoardV2.Cells.
+type MoveV2 struct {
+	Player string `json:"player"`
+	Index  int    `json:"index"`
+	Row    int    `json:"row"`
+	Column int    `json:"column"`
+}
+
+// MoveResponseV2 is the AI's move and the board after it. Winner and
+// WinningLine are only set when the game is won, ToMove only while it is
+// ongoing.
+type MoveResponseV2 struct {
+	Move        MoveV2  `json:"move"`
+	Board       BoardV2 `json:"board"`
+	Position    string  `json:"position"`
+	Status      string  `json:"status"`
+	Winner      string  `json:"winner,omitempty"`
+	ToMove      string  `json:"toMove,omitempty"`
+	WinningLine []int   `json:"winningLine,omitempty"`
+	Message     string  `json:"message"`
+}
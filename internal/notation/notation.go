@@ -0,0 +1,345 @@
+// Package notation reads and writes game records in a PGN-like text format:
+//
+//	[Event "Tic Tac Toe"]
+//	[Date "2026.10.19"]
+//	[X "Human"]
+//	[O "AI"]
+//	[BoardSize "3"]
+//	[WinLength "3"]
+//	[Difficulty "3"]
+//	[Result "1-0"]
+//
+//	1. 1 4 2. 2 5 3. 3 1-0
+//
+// Moves are the cell positions numbered from 1 to n as in boardDisplay. X
+// always moves first, so each numbered pair is an X move followed by an O
+// move. Text in braces is a comment and is ignored.
+package notation
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/isavita/tictactoe_api/internal/game"
+	"github.com/isavita/tictactoe_api/internal/model"
+)
+
+const (
+	ResultXWins   = "1-0"
+	ResultOWins   = "0-1"
+	ResultDraw    = "1/2-1/2"
+	ResultOngoing = "*"
+)
+
+// AIName marks the side played by the AI; any other name is a human player.
+const (
+	AIName    = "AI"
+	HumanName = "Human"
+)
+
+const dateLayout = "2006.01.02"
+
+type Record struct {
+	Event     string
+	Date      time.Time
+	X         string
+	O         string
+	BoardSize int
+	WinLength int
+	// Difficulty is the public level 1 to 3, or 0 when no AI is involved.
+	Difficulty int
+	Result     string
+	// Positions are the moves in order, numbered from 1 to BoardSize^2.
+	Positions []int
+}
+
+// SyntaxError reports a malformed record together with the offending line.
+type SyntaxError struct {
+	Line int
+	Msg  string
+}
+
+func (e *SyntaxError) Error() string {
+	return "line " + strconv.Itoa(e.Line) + ": " + e.Msg
+}
+
+// Moves returns the positions as moves with alternating players, X first.
+func (r *Record) Moves() []model.Move {
+	moves := make([]model.Move, len(r.Positions))
+	for i, position := range r.Positions {
+		player := game.XPlayer
+		if i%2 == 1 {
+			player = game.OPlayer
+		}
+		moves[i] = model.Move{Player: player, Position: position}
+	}
+	return moves
+}
+
+func ResultFromStatus(status string) string {
+	switch status {
+	case model.GameStatusPlayer1Wins:
+		return ResultXWins
+	case model.GameStatusPlayer2Wins:
+		return ResultOWins
+	case model.GameStatusDraw:
+		return ResultDraw
+	default:
+		return ResultOngoing
+	}
+}
+
+func StatusFromResult(result string) string {
+	switch result {
+	case ResultXWins:
+		return model.GameStatusPlayer1Wins
+	case ResultOWins:
+		return model.GameStatusPlayer2Wins
+	case ResultDraw:
+		return model.GameStatusDraw
+	default:
+		return model.GameStatusOngoing
+	}
+}
+
+// FromGame builds the record of a server-held game.
+func FromGame(g model.GameRecord) *Record {
+	record := &Record{
+		Event:     "Tic Tac Toe",
+		Date:      g.CreatedAt,
+		X:         HumanName,
+		O:         HumanName,
+		BoardSize: g.BoardSize,
+		WinLength: g.BoardSize,
+		Result:    ResultFromStatus(g.GameStatus),
+		Positions: make([]int, len(g.Moves)),
+	}
+	if g.Mode == model.GameModeAI {
+		record.Difficulty = g.Difficulty
+		if g.AIPlayer == game.XPlayer {
+			record.X = AIName
+		} else {
+			record.O = AIName
+		}
+	}
+	for i, move := range g.Moves {
+		record.Positions[i] = move.Position
+	}
+
+	return record
+}
+
+func (r *Record) Write(w io.Writer) error {
+	var b bytes.Buffer
+
+	writeTag(&b, "Event", r.Event)
+	date := "????.??.??"
+	if !r.Date.IsZero() {
+		date = r.Date.Format(dateLayout)
+	}
+	writeTag(&b, "Date", date)
+	writeTag(&b, "X", r.X)
+	writeTag(&b, "O", r.O)
+	writeTag(&b, "BoardSize", strconv.Itoa(r.BoardSize))
+	writeTag(&b, "WinLength", strconv.Itoa(r.WinLength))
+	if r.Difficulty != 0 {
+		writeTag(&b, "Difficulty", strconv.Itoa(r.Difficulty))
+	}
+	writeTag(&b, "Result", r.Result)
+	b.WriteString("\n")
+
+	for i, position := range r.Positions {
+		if i%2 == 0 {
+			fmt.Fprintf(&b, "%d. ", i/2+1)
+		}
+		fmt.Fprintf(&b, "%d ", position)
+	}
+	b.WriteString(r.Result)
+	b.WriteString("\n")
+
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+func (r *Record) String() string {
+	var b strings.Builder
+	r.Write(&b)
+	return b.String()
+}
+
+func writeTag(b *bytes.Buffer, name string, value string) {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	fmt.Fprintf(b, "[%s \"%s\"]\n", name, value)
+}
+
+// Parse reads a single record. Only the syntax is checked here; replaying the
+// moves through the game rules is up to the caller.
+func Parse(r io.Reader) (*Record, error) {
+	record := &Record{BoardSize: 3}
+	winLengthSet := false
+
+	scanner := bufio.NewScanner(r)
+	line := 0
+	inMoves := false
+	var movetext strings.Builder
+	movetextLine := 0
+
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+
+		if !inMoves && strings.HasPrefix(text, "[") {
+			name, value, err := parseTag(text)
+			if err != nil {
+				return nil, &SyntaxError{Line: line, Msg: err.Error()}
+			}
+			if err := record.setTag(name, value); err != nil {
+				return nil, &SyntaxError{Line: line, Msg: err.Error()}
+			}
+			if name == "WinLength" {
+				winLengthSet = true
+			}
+			continue
+		}
+		if text == "" {
+			continue
+		}
+
+		if !inMoves {
+			inMoves = true
+			movetextLine = line
+		}
+		movetext.WriteString(text)
+		movetext.WriteString(" ")
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if !winLengthSet {
+		record.WinLength = record.BoardSize
+	}
+
+	if err := record.parseMoves(movetext.String()); err != nil {
+		return nil, &SyntaxError{Line: movetextLine, Msg: err.Error()}
+	}
+	if record.Result == "" {
+		record.Result = ResultOngoing
+	}
+
+	return record, nil
+}
+
+func parseTag(text string) (string, string, error) {
+	if !strings.HasSuffix(text, "]") {
+		return "", "", fmt.Errorf("tag %s is missing the closing bracket", text)
+	}
+	inner := strings.TrimSpace(text[1 : len(text)-1])
+
+	name, quoted, ok := strings.Cut(inner, " ")
+	if !ok {
+		return "", "", fmt.Errorf("tag %s has no value", text)
+	}
+	value, err := strconv.Unquote(strings.TrimSpace(quoted))
+	if err != nil {
+		return "", "", fmt.Errorf("tag %s has a malformed value", name)
+	}
+
+	return name, value, nil
+}
+
+func (r *Record) setTag(name string, value string) error {
+	var err error
+
+	switch name {
+	case "Event":
+		r.Event = value
+	case "Date":
+		// PGN writes unknown date parts as question marks.
+		if !strings.Contains(value, "?") {
+			r.Date, err = time.Parse(dateLayout, value)
+		}
+	case "X":
+		r.X = value
+	case "O":
+		r.O = value
+	case "BoardSize":
+		r.BoardSize, err = strconv.Atoi(value)
+	case "WinLength":
+		r.WinLength, err = strconv.Atoi(value)
+	case "Difficulty":
+		r.Difficulty, err = strconv.Atoi(value)
+	case "Result":
+		switch value {
+		case ResultXWins, ResultOWins, ResultDraw, ResultOngoing:
+			r.Result = value
+		default:
+			return fmt.Errorf("unknown result %q", value)
+		}
+	default:
+		// Unknown tags are allowed and ignored.
+	}
+
+	if err != nil {
+		return fmt.Errorf("tag %s has an invalid value %q", name, value)
+	}
+	return nil
+}
+
+func (r *Record) parseMoves(movetext string) error {
+	// Drop brace comments.
+	var b strings.Builder
+	depth := 0
+	for _, c := range movetext {
+		switch {
+		case c == '{':
+			depth++
+		case c == '}' && depth > 0:
+			depth--
+		case depth == 0:
+			b.WriteRune(c)
+		}
+	}
+	if depth != 0 {
+		return fmt.Errorf("unterminated comment")
+	}
+
+	tokens := strings.Fields(b.String())
+	for i, token := range tokens {
+		switch token {
+		case ResultXWins, ResultOWins, ResultDraw, ResultOngoing:
+			if i != len(tokens)-1 {
+				return fmt.Errorf("result %s must end the move list", token)
+			}
+			if r.Result == "" {
+				r.Result = token
+			}
+			if token != r.Result {
+				return fmt.Errorf("result %s does not match the Result tag %s", token, r.Result)
+			}
+			continue
+		}
+
+		if strings.HasSuffix(token, ".") {
+			number, err := strconv.Atoi(strings.TrimSuffix(token, "."))
+			if err != nil || number != len(r.Positions)/2+1 || len(r.Positions)%2 != 0 {
+				return fmt.Errorf("unexpected move number %s", token)
+			}
+			continue
+		}
+
+		position, err := strconv.Atoi(token)
+		if err != nil {
+			return fmt.Errorf("invalid move %q", token)
+		}
+		r.Positions = append(r.Positions, position)
+	}
+
+	return nil
+}
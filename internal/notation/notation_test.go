@@ -0,0 +1,126 @@
+package notation
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/isavita/tictactoe_api/internal/model"
+)
+
+func TestWrite(t *testing.T) {
+	record := &Record{
+		Event:      "Tic Tac Toe",
+		Date:       time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC),
+		X:          "Human",
+		O:          "AI",
+		BoardSize:  3,
+		WinLength:  3,
+		Difficulty: 3,
+		Result:     ResultXWins,
+		Positions:  []int{1, 4, 2, 5, 3},
+	}
+
+	want := `[Event "Tic Tac Toe"]
+[Date "2026.10.19"]
+[X "Human"]
+[O "AI"]
+[BoardSize "3"]
+[WinLength "3"]
+[Difficulty "3"]
+[Result "1-0"]
+
+1. 1 4 2. 2 5 3. 3 1-0
+`
+
+	if got := record.String(); got != want {
+		t.Errorf("got %q want %q", got, want)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	want := &Record{
+		Event:     `The "quoted" \ event`,
+		X:         "Alice",
+		O:         "Bob",
+		BoardSize: 4,
+		WinLength: 4,
+		Result:    ResultOngoing,
+		Positions: []int{1, 16, 6, 11},
+	}
+
+	got, err := Parse(strings.NewReader(want.String()))
+	assertNoError(t, err)
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v want %+v", got, want)
+	}
+}
+
+func TestParse(t *testing.T) {
+	t.Run("defaults, comments and result without a tag", func(t *testing.T) {
+		got, err := Parse(strings.NewReader("1. 5 {centre} 1\n2. 9 0-1\n"))
+		assertNoError(t, err)
+
+		want := &Record{BoardSize: 3, WinLength: 3, Result: ResultOWins, Positions: []int{5, 1, 9}}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %+v want %+v", got, want)
+		}
+
+		wantMoves := []model.Move{{Player: 1, Position: 5}, {Player: 2, Position: 1}, {Player: 1, Position: 9}}
+		if !reflect.DeepEqual(got.Moves(), wantMoves) {
+			t.Errorf("got %v want %v", got.Moves(), wantMoves)
+		}
+	})
+
+	errorCases := []struct {
+		name   string
+		record string
+		line   int
+	}{
+		{"malformed tag", "[Event Tic]\n", 1},
+		{"invalid board size", "[BoardSize \"big\"]\n", 1},
+		{"unknown result", "[Result \"2-0\"]\n", 1},
+		{"wrong move number", "[X \"Human\"]\n\n1. 5 1 3. 9\n", 3},
+		{"result mismatch", "[Result \"1-0\"]\n1. 5 1 0-1\n", 2},
+		{"invalid move", "1. 5 b2\n", 1},
+		{"unterminated comment", "1. 5 {oops\n", 1},
+	}
+	for _, tc := range errorCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(tc.record))
+
+			var syntaxErr *SyntaxError
+			if !errors.As(err, &syntaxErr) || syntaxErr.Line != tc.line {
+				t.Errorf("got %v want a syntax error on line %d", err, tc.line)
+			}
+		})
+	}
+}
+
+func TestFromGame(t *testing.T) {
+	got := FromGame(model.GameRecord{
+		Mode:       model.GameModeAI,
+		Difficulty: 2,
+		AIPlayer:   1,
+		BoardSize:  3,
+		GameStatus: model.GameStatusDraw,
+		Moves:      []model.Move{{Player: 1, Position: 5}, {Player: 2, Position: 1}},
+	})
+
+	if got.X != AIName || got.O != HumanName || got.Difficulty != 2 || got.Result != ResultDraw {
+		t.Errorf("got %+v", got)
+	}
+	if !reflect.DeepEqual(got.Positions, []int{5, 1}) {
+		t.Errorf("got %v want [5 1]", got.Positions)
+	}
+}
+
+func assertNoError(t testing.TB, err error) {
+	t.Helper()
+	if err != nil {
+		t.Errorf("got error %v when no error was expected", err)
+	}
+}
@@ -0,0 +1,358 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// GeneratedComment heads the generated schemas section of the document.
+const GeneratedComment = "# Generated from internal/model by cmd/openapigen. DO NOT EDIT."
+
+// ModelSchemas are the model types published in .well-known/openapi.yaml.
+var ModelSchemas = []string{"MoveRequest", "MoveResponse", "ErrorResponse"}
+
+// GenerateSchemas returns the components.schemas section of an OpenAPI
+// document, without its "schemas:" key, for the named struct types of the
+// Go package in dir and every struct type they use.
+//
+// Field names follow the json struct tags and fields without omitempty are
+// required. The descriptions are the doc comments of the types and fields,
+// with a leading "Name is" dropped. The example, default and enums struct
+// tags fill in the matching schema keywords; enums and array examples are
+// comma-separated.
+func GenerateSchemas(dir string, names ...string) ([]byte, error) {
+	types, err := parseTypes(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &generator{types: types, schemas: map[string]*schema{}}
+	for _, name := range names {
+		if err := g.addType(name); err != nil {
+			return nil, err
+		}
+	}
+
+	var b bytes.Buffer
+	b.WriteString("    " + GeneratedComment + "\n")
+	generated := make([]string, 0, len(g.schemas))
+	for name := range g.schemas {
+		generated = append(generated, name)
+	}
+	sort.Strings(generated)
+	for _, name := range generated {
+		b.WriteString("    " + name + ":\n")
+		g.schemas[name].write(&b, 6)
+	}
+	return b.Bytes(), nil
+}
+
+// ReplaceSchemas replaces the components.schemas section of an OpenAPI
+// document.
+func ReplaceSchemas(doc []byte, schemas []byte) ([]byte, error) {
+	lines := strings.SplitAfter(string(doc), "\n")
+	start := -1
+	inComponents := false
+	for i, line := range lines {
+		switch {
+		case strings.TrimRight(line, "\n") == "components:":
+			inComponents = true
+		case inComponents && strings.TrimRight(line, "\n") == "  schemas:":
+			start = i + 1
+		case !strings.HasPrefix(line, " ") && strings.TrimSpace(line) != "":
+			inComponents = false
+		}
+		if start != -1 {
+			break
+		}
+	}
+	if start == -1 {
+		return nil, fmt.Errorf("openapi: the document has no components.schemas section")
+	}
+
+	end := start
+	for end < len(lines) && (strings.HasPrefix(lines[end], "    ") || strings.TrimSpace(lines[end]) == "") {
+		end++
+	}
+
+	var b bytes.Buffer
+	b.WriteString(strings.Join(lines[:start], ""))
+	b.Write(schemas)
+	b.WriteString(strings.Join(lines[end:], ""))
+	return b.Bytes(), nil
+}
+
+type typeDecl struct {
+	spec *ast.TypeSpec
+	doc  *ast.CommentGroup
+}
+
+// parseTypes returns the type declarations of the Go package in dir.
+func parseTypes(dir string) (map[string]typeDecl, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+
+	types := map[string]typeDecl{}
+	fset := token.NewFileSet()
+	for _, path := range paths {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		file, err := parser.ParseFile(fset, path, src, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		for _, decl := range file.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok || genDecl.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range genDecl.Specs {
+				typeSpec := spec.(*ast.TypeSpec)
+				doc := typeSpec.Doc
+				if doc == nil && len(genDecl.Specs) == 1 {
+					doc = genDecl.Doc
+				}
+				types[typeSpec.Name.Name] = typeDecl{typeSpec, doc}
+			}
+		}
+	}
+	return types, nil
+}
+
+type generator struct {
+	types   map[string]typeDecl
+	schemas map[string]*schema
+}
+
+type schema struct {
+	ref         string
+	typ         string
+	format      string
+	description string
+	items       *schema
+	properties  []property
+	required    []string
+	enum        string
+	def         string
+	example     string
+}
+
+type property struct {
+	name   string
+	schema *schema
+}
+
+// addType generates the schema of a named struct type unless it already
+// has one.
+func (g *generator) addType(name string) error {
+	if _, ok := g.schemas[name]; ok {
+		return nil
+	}
+	decl, ok := g.types[name]
+	if !ok {
+		return fmt.Errorf("openapi: unknown type %s", name)
+	}
+	structType, ok := decl.spec.Type.(*ast.StructType)
+	if !ok {
+		return fmt.Errorf("openapi: %s is not a struct", name)
+	}
+
+	s := &schema{typ: "object", description: description(name, decl.doc)}
+	g.schemas[name] = s
+	for _, field := range structType.Fields.List {
+		if len(field.Names) == 0 {
+			return fmt.Errorf("openapi: embedded fields are not supported in %s", name)
+		}
+		var tag reflect.StructTag
+		if field.Tag != nil {
+			tag = reflect.StructTag(strings.Trim(field.Tag.Value, "`"))
+		}
+		jsonName, options, _ := strings.Cut(tag.Get("json"), ",")
+		if jsonName == "-" {
+			continue
+		}
+
+		for _, fieldName := range field.Names {
+			if !fieldName.IsExported() {
+				continue
+			}
+			fieldSchema, err := g.schemaFor(field.Type)
+			if err != nil {
+				return fmt.Errorf("openapi: %s.%s: %w", name, fieldName.Name, err)
+			}
+			if fieldSchema.ref == "" {
+				fieldSchema.description = description(fieldName.Name, field.Doc)
+				fieldSchema.enum = tag.Get("enums")
+				fieldSchema.def = tag.Get("default")
+				fieldSchema.example = tag.Get("example")
+			}
+
+			propertyName := jsonName
+			if propertyName == "" {
+				propertyName = fieldName.Name
+			}
+			s.properties = append(s.properties, property{propertyName, fieldSchema})
+			if !strings.Contains(options, "omitempty") {
+				s.required = append(s.required, propertyName)
+			}
+		}
+	}
+	return nil
+}
+
+func (g *generator) schemaFor(expr ast.Expr) (*schema, error) {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return g.schemaFor(t.X)
+	case *ast.ArrayType:
+		items, err := g.schemaFor(t.Elt)
+		if err != nil {
+			return nil, err
+		}
+		return &schema{typ: "array", items: items}, nil
+	case *ast.SelectorExpr:
+		if pkg, ok := t.X.(*ast.Ident); ok && pkg.Name == "time" && t.Sel.Name == "Time" {
+			return &schema{typ: "string", format: "date-time"}, nil
+		}
+	case *ast.Ident:
+		switch t.Name {
+		case "string":
+			return &schema{typ: "string"}, nil
+		case "bool":
+			return &schema{typ: "boolean"}, nil
+		case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64":
+			return &schema{typ: "integer"}, nil
+		case "float32", "float64":
+			return &schema{typ: "number"}, nil
+		}
+		if err := g.addType(t.Name); err != nil {
+			return nil, err
+		}
+		return &schema{ref: "#/components/schemas/" + t.Name}, nil
+	}
+	return nil, fmt.Errorf("unsupported type %T", expr)
+}
+
+// description turns a doc comment into a description: lines are joined into
+// paragraphs and a leading "Name is" is dropped.
+func description(name string, doc *ast.CommentGroup) string {
+	if doc == nil {
+		return ""
+	}
+	var paragraphs []string
+	for _, paragraph := range strings.Split(strings.TrimSpace(doc.Text()), "\n\n") {
+		paragraphs = append(paragraphs, strings.Join(strings.Fields(paragraph), " "))
+	}
+	text := strings.Join(paragraphs, "\n")
+
+	if rest := strings.TrimPrefix(text, name+" is "); rest != text {
+		r, size := utf8.DecodeRuneInString(rest)
+		text = string(unicode.ToUpper(r)) + rest[size:]
+	}
+	return text
+}
+
+func (s *schema) write(b *bytes.Buffer, indent int) {
+	pad := strings.Repeat(" ", indent)
+	if s.ref != "" {
+		fmt.Fprintf(b, "%s$ref: '%s'\n", pad, s.ref)
+		return
+	}
+
+	fmt.Fprintf(b, "%stype: %s\n", pad, s.typ)
+	if s.format != "" {
+		fmt.Fprintf(b, "%sformat: %s\n", pad, s.format)
+	}
+	if s.description != "" {
+		if strings.Contains(s.description, "\n") {
+			fmt.Fprintf(b, "%sdescription: |\n", pad)
+			for _, line := range strings.Split(s.description, "\n") {
+				fmt.Fprintf(b, "%s  %s\n", pad, line)
+			}
+		} else {
+			fmt.Fprintf(b, "%sdescription: %s\n", pad, yamlString(s.description))
+		}
+	}
+	if s.items != nil {
+		fmt.Fprintf(b, "%sitems:\n", pad)
+		s.items.write(b, indent+2)
+	}
+	if len(s.properties) > 0 {
+		fmt.Fprintf(b, "%sproperties:\n", pad)
+		for _, p := range s.properties {
+			fmt.Fprintf(b, "%s  %s:\n", pad, p.name)
+			p.schema.write(b, indent+4)
+		}
+	}
+	if len(s.required) > 0 {
+		fmt.Fprintf(b, "%srequired: [%s]\n", pad, strings.Join(s.required, ", "))
+	}
+	if s.enum != "" {
+		fmt.Fprintf(b, "%senum: %s\n", pad, flowSequence(s, s.enum))
+	}
+	if s.def != "" {
+		fmt.Fprintf(b, "%sdefault: %s\n", pad, s.literal(s.def))
+	}
+	if s.example != "" {
+		fmt.Fprintf(b, "%sexample: %s\n", pad, s.literal(s.example))
+	}
+}
+
+// literal writes a struct tag value as a YAML value of the schema's type.
+func (s *schema) literal(value string) string {
+	switch s.typ {
+	case "array":
+		return flowSequence(s.items, value)
+	case "string":
+		return yamlString(value)
+	default:
+		return value
+	}
+}
+
+func flowSequence(items *schema, values string) string {
+	var literals []string
+	for _, value := range strings.Split(values, ",") {
+		literals = append(literals, items.literal(strings.TrimSpace(value)))
+	}
+	return "[" + strings.Join(literals, ", ") + "]"
+}
+
+var plainString = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9 '.,()/_+-]*$`)
+
+// yamlString quotes a string unless it reads back unchanged as a plain
+// scalar.
+func yamlString(s string) string {
+	switch s {
+	case "true", "false", "null", "yes", "no", "on", "off":
+		return `"` + s + `"`
+	}
+	if plainString.MatchString(s) && !strings.HasSuffix(s, " ") {
+		return s
+	}
+
+	var b bytes.Buffer
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(s)
+	return strings.TrimSuffix(b.String(), "\n")
+}
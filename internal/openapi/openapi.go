@@ -0,0 +1,297 @@
+// Package openapi checks HTTP traffic against the OpenAPI document in
+// .well-known/openapi.yaml and generates the document's schemas from Go
+// types, so that the published contract cannot drift from the code.
+package openapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"mime"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/isavita/tictactoe_api/internal/yaml"
+)
+
+// Spec is a parsed OpenAPI 3.0 document.
+type Spec struct {
+	doc      map[string]any
+	basePath string
+}
+
+// Parse reads an OpenAPI document. Paths are matched below the path of its
+// first server URL.
+func Parse(data []byte) (*Spec, error) {
+	value, err := yaml.Unmarshal(data)
+	if err != nil {
+		return nil, err
+	}
+	doc, ok := value.(map[string]any)
+	if !ok {
+		return nil, errors.New("openapi: the document is not a mapping")
+	}
+	if _, ok := doc["paths"].(map[string]any); !ok {
+		return nil, errors.New("openapi: the document has no paths")
+	}
+
+	spec := &Spec{doc: doc}
+	if servers, ok := doc["servers"].([]any); ok && len(servers) > 0 {
+		server, _ := servers[0].(map[string]any)
+		serverURL, _ := server["url"].(string)
+		u, err := url.Parse(serverURL)
+		if err != nil {
+			return nil, fmt.Errorf("openapi: invalid server url: %w", err)
+		}
+		spec.basePath = strings.TrimSuffix(u.Path, "/")
+	}
+	return spec, nil
+}
+
+// Schema returns the named schema of the components section.
+func (s *Spec) Schema(name string) (map[string]any, bool) {
+	schema, ok := lookup(s.doc, "components", "schemas", name).(map[string]any)
+	return schema, ok
+}
+
+// ValidateRequest checks a request body against the request body schema of
+// the operation for its media type.
+func (s *Spec) ValidateRequest(method string, path string, contentType string, body []byte) error {
+	operation, err := s.operation(method, path)
+	if err != nil {
+		return err
+	}
+	requestBody, _ := operation["requestBody"].(map[string]any)
+	if requestBody == nil {
+		if len(body) == 0 {
+			return nil
+		}
+		return fmt.Errorf("openapi: %s %s has no documented request body", method, path)
+	}
+	if len(body) == 0 {
+		if required, _ := requestBody["required"].(bool); required {
+			return fmt.Errorf("openapi: %s %s requires a request body", method, path)
+		}
+		return nil
+	}
+	return s.validateContent(requestBody, "request", contentType, body)
+}
+
+// ValidateResponse checks a response body against the response schema of
+// the operation for its status code and media type. Statuses without their
+// own entry fall back to the default response.
+func (s *Spec) ValidateResponse(method string, path string, status int, contentType string, body []byte) error {
+	operation, err := s.operation(method, path)
+	if err != nil {
+		return err
+	}
+	responses, _ := operation["responses"].(map[string]any)
+	response, ok := responses[strconv.Itoa(status)].(map[string]any)
+	if !ok {
+		if response, ok = responses["default"].(map[string]any); !ok {
+			return fmt.Errorf("openapi: %s %s has no documented %d response", method, path, status)
+		}
+	}
+	if len(body) == 0 && response["content"] == nil {
+		return nil
+	}
+	return s.validateContent(response, "response", contentType, body)
+}
+
+// operation finds the operation for the method and a request path that
+// includes the base path of the server.
+func (s *Spec) operation(method string, path string) (map[string]any, error) {
+	if !strings.HasPrefix(path, s.basePath+"/") {
+		return nil, fmt.Errorf("openapi: %s is outside the server path %s", path, s.basePath)
+	}
+	path = strings.TrimPrefix(path, s.basePath)
+
+	paths := s.doc["paths"].(map[string]any)
+	for _, template := range sortedKeys(paths) {
+		if !matchPath(template, path) {
+			continue
+		}
+		item, _ := paths[template].(map[string]any)
+		operation, ok := item[strings.ToLower(method)].(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("openapi: %s %s is not documented", method, template)
+		}
+		return operation, nil
+	}
+	return nil, fmt.Errorf("openapi: %s is not documented", path)
+}
+
+// matchPath reports whether path matches a path template such as
+// /games/{id}.
+func matchPath(template string, path string) bool {
+	templateParts := strings.Split(template, "/")
+	pathParts := strings.Split(path, "/")
+	if len(templateParts) != len(pathParts) {
+		return false
+	}
+	for i, part := range templateParts {
+		isParam := strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}")
+		if !(isParam && pathParts[i] != "") && part != pathParts[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// validateContent checks a body against the schema of its media type in a
+// request body or response object. JSON bodies are decoded, other bodies are
+// checked as strings.
+func (s *Spec) validateContent(object map[string]any, name string, contentType string, body []byte) error {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return fmt.Errorf("openapi: invalid %s content type %q", name, contentType)
+	}
+	content, _ := object["content"].(map[string]any)
+	mediaObject, ok := content[mediaType].(map[string]any)
+	if !ok {
+		return fmt.Errorf("openapi: %s media type %s is not documented", name, mediaType)
+	}
+	schema, ok := mediaObject["schema"].(map[string]any)
+	if !ok {
+		return nil
+	}
+
+	var value any = string(body)
+	if mediaType == "application/json" || strings.HasSuffix(mediaType, "+json") {
+		if err := json.Unmarshal(body, &value); err != nil {
+			return fmt.Errorf("openapi: %s body is not JSON: %w", name, err)
+		}
+	}
+	return s.Validate(schema, value, name)
+}
+
+// Validate checks a value decoded from JSON against a schema. Properties that
+// the schema does not list are errors, so that undocumented fields are
+// caught. The errors name the offending value by its path from name.
+func (s *Spec) Validate(schema map[string]any, value any, name string) error {
+	var errs []error
+	s.validate(schema, value, name, &errs, 0)
+	return errors.Join(errs...)
+}
+
+func (s *Spec) validate(schema map[string]any, value any, path string, errs *[]error, depth int) {
+	if depth > 32 {
+		*errs = append(*errs, fmt.Errorf("%s: schema references are too deep", path))
+		return
+	}
+	if ref, ok := schema["$ref"].(string); ok {
+		name := strings.TrimPrefix(ref, "#/components/schemas/")
+		target, found := s.Schema(name)
+		if name == ref || !found {
+			*errs = append(*errs, fmt.Errorf("%s: unknown schema %s", path, ref))
+			return
+		}
+		s.validate(target, value, path, errs, depth+1)
+		return
+	}
+
+	if value == nil {
+		if nullable, _ := schema["nullable"].(bool); !nullable {
+			*errs = append(*errs, fmt.Errorf("%s: got null", path))
+		}
+		return
+	}
+	if enum, ok := schema["enum"].([]any); ok && !containsValue(enum, value) {
+		*errs = append(*errs, fmt.Errorf("%s: got %v want one of %v", path, value, enum))
+	}
+
+	switch want, _ := schema["type"].(string); want {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			*errs = append(*errs, fmt.Errorf("%s: got %s want object", path, typeName(value)))
+			return
+		}
+		properties, _ := schema["properties"].(map[string]any)
+		required, _ := schema["required"].([]any)
+		for _, name := range required {
+			if _, ok := object[fmt.Sprint(name)]; !ok {
+				*errs = append(*errs, fmt.Errorf("%s: missing required property %v", path, name))
+			}
+		}
+		for _, name := range sortedKeys(object) {
+			property, ok := properties[name].(map[string]any)
+			if !ok {
+				*errs = append(*errs, fmt.Errorf("%s: undocumented property %s", path, name))
+				continue
+			}
+			s.validate(property, object[name], path+"."+name, errs, depth)
+		}
+	case "array":
+		array, ok := value.([]any)
+		if !ok {
+			*errs = append(*errs, fmt.Errorf("%s: got %s want array", path, typeName(value)))
+			return
+		}
+		items, _ := schema["items"].(map[string]any)
+		for i, item := range array {
+			s.validate(items, item, path+"["+strconv.Itoa(i)+"]", errs, depth)
+		}
+	case "integer":
+		if f, ok := value.(float64); !ok || f != math.Trunc(f) {
+			*errs = append(*errs, fmt.Errorf("%s: got %s want integer", path, typeName(value)))
+		}
+	case "":
+		// Any value is allowed
+	default:
+		if got := typeName(value); got != want {
+			*errs = append(*errs, fmt.Errorf("%s: got %s want %s", path, got, want))
+		}
+	}
+}
+
+func typeName(value any) string {
+	switch value.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	default:
+		return "null"
+	}
+}
+
+func containsValue(values []any, value any) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// lookup follows keys through nested mappings and returns nil when one is
+// missing.
+func lookup(value any, keys ...string) any {
+	for _, key := range keys {
+		mapping, ok := value.(map[string]any)
+		if !ok {
+			return nil
+		}
+		value = mapping[key]
+	}
+	return value
+}
+
+func sortedKeys(mapping map[string]any) []string {
+	keys := make([]string, 0, len(mapping))
+	for key := range mapping {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
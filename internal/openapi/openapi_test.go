@@ -0,0 +1,163 @@
+package openapi
+
+import (
+	"strings"
+	"testing"
+)
+
+const testSpec = `openapi: 3.0.0
+servers:
+  - url: https://example.com/v1
+paths:
+  /games/{id}:
+    post:
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/Game'
+      responses:
+        '200':
+          description: OK
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Game'
+            text/plain:
+              schema:
+                type: string
+        default:
+          description: Unexpected error
+components:
+  schemas:
+    Game:
+      type: object
+      properties:
+        id:
+          type: string
+        size:
+          type: integer
+          enum: [3, 4]
+        players:
+          type: array
+          items:
+            type: integer
+        note:
+          type: string
+          nullable: true
+      required: [id]
+`
+
+func TestValidate(t *testing.T) {
+	spec, err := Parse([]byte(testSpec))
+	assertNoError(t, err)
+
+	valid := `{"id": "g1", "size": 3, "players": [1, 2], "note": null}`
+	assertNoError(t, spec.ValidateRequest("POST", "/v1/games/g1", "application/json", []byte(valid)))
+	assertNoError(t, spec.ValidateResponse("POST", "/v1/games/g1", 200, "application/json; charset=utf-8", []byte(valid)))
+	assertNoError(t, spec.ValidateResponse("POST", "/v1/games/g1", 200, "text/plain", []byte("ok")))
+	assertNoError(t, spec.ValidateResponse("POST", "/v1/games/g1", 500, "", nil))
+
+	cases := []struct {
+		name        string
+		method      string
+		path        string
+		status      int
+		contentType string
+		body        string
+		wantErr     string
+	}{
+		{"undocumented property", "POST", "/v1/games/g1", 200, "application/json", `{"id": "g1", "extra": 1}`, "response: undocumented property extra"},
+		{"missing required property", "POST", "/v1/games/g1", 200, "application/json", `{"size": 3}`, "response: missing required property id"},
+		{"wrong type", "POST", "/v1/games/g1", 200, "application/json", `{"id": 1}`, "response.id: got number want string"},
+		{"not an integer", "POST", "/v1/games/g1", 200, "application/json", `{"id": "g1", "players": [1.5]}`, "response.players[0]: got number want integer"},
+		{"not in the enum", "POST", "/v1/games/g1", 200, "application/json", `{"id": "g1", "size": 5}`, "response.size: got 5 want one of [3 4]"},
+		{"null", "POST", "/v1/games/g1", 200, "application/json", `{"id": null}`, "response.id: got null"},
+		{"undocumented media type", "POST", "/v1/games/g1", 200, "image/png", `png`, "response media type image/png is not documented"},
+		{"undocumented method", "GET", "/v1/games/g1", 200, "application/json", `{}`, "GET /games/{id} is not documented"},
+		{"undocumented path", "POST", "/v1/games", 200, "application/json", `{}`, "/games is not documented"},
+		{"outside the server", "POST", "/v2/games/g1", 200, "application/json", `{}`, "outside the server path /v1"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := spec.ValidateResponse(tc.method, tc.path, tc.status, tc.contentType, []byte(tc.body))
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("got error %v want %q", err, tc.wantErr)
+			}
+		})
+	}
+
+	if err := spec.ValidateRequest("POST", "/v1/games/g1", "application/json", nil); err == nil {
+		t.Errorf("got no error for a missing required request body")
+	}
+}
+
+func TestGenerateSchemas(t *testing.T) {
+	got, err := GenerateSchemas("testdata/model", "Game")
+	assertNoError(t, err)
+
+	want := `    # Generated from internal/model by cmd/openapigen. DO NOT EDIT.
+    Game:
+      type: object
+      description: A game with its players.
+      properties:
+        id:
+          type: string
+          description: The id of the game.
+          example: g1
+        size:
+          type: integer
+          enum: [3, 4]
+          default: 3
+        players:
+          type: array
+          items:
+            $ref: '#/components/schemas/Player'
+        started:
+          type: string
+          format: date-time
+      required: [id, players, started]
+    Player:
+      type: object
+      description: |
+        A Player of a game.
+        Players are numbered from 1.
+      properties:
+        number:
+          type: integer
+        tags:
+          type: array
+          items:
+            type: string
+          example: ["a: b", c]
+      required: [number]
+`
+	if string(got) != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	// The generated section must read back as valid schemas
+	doc, err := ReplaceSchemas([]byte(testSpec), got)
+	assertNoError(t, err)
+	spec, err := Parse(doc)
+	assertNoError(t, err)
+	game, ok := spec.Schema("Game")
+	if !ok {
+		t.Fatalf("got no Game schema in\n%s", doc)
+	}
+	value := map[string]any{"id": "g1", "players": []any{map[string]any{"number": 1.0}}, "started": "2026-10-19T00:00:00Z"}
+	assertNoError(t, spec.Validate(game, value, "game"))
+
+	if _, err := GenerateSchemas("testdata/model", "Missing"); err == nil {
+		t.Errorf("got no error for an unknown type")
+	}
+}
+
+func assertNoError(t testing.TB, err error) {
+	t.Helper()
+	if err != nil {
+		t.Errorf("got error %v when no error was expected", err)
+	}
+}
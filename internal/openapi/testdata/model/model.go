@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// Game is a game with its players.
+type Game struct {
+	// ID is the id of the game.
+	ID      string    `json:"id" example:"g1"`
+	Size    int       `json:"size,omitempty" enums:"3,4" default:"3"`
+	Players []*Player `json:"players"`
+	Started time.Time `json:"started"`
+	secret  string
+	Ignored string `json:"-"`
+}
+
+// A Player of a game.
+//
+// Players are numbered from 1.
+type Player struct {
+	Number int      `json:"number"`
+	Tags   []string `json:"tags,omitempty" example:"a: b,c"`
+}
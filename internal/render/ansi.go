@@ -0,0 +1,59 @@
+package render
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/isavita/tictactoe_api/internal/game"
+)
+
+// ANSI escape sequences used to colour the text board.
+const (
+	ansiReset    = "\x1b[0m"
+	ansiX        = "\x1b[1;31m"
+	ansiO        = "\x1b[1;34m"
+	ansiNumber   = "\x1b[2m"
+	ansiLastMove = "\x1b[7m"
+)
+
+// ANSI draws the board for a terminal: a box-drawing grid with X in red, O in
+// blue, the numbers of empty cells dimmed and the last move in reverse video.
+// Only options.LastMove is used.
+func ANSI(board []int, boardSize int, options Options) string {
+	width := len(strconv.Itoa(len(board)))
+	border := func(left, middle, right string) string {
+		return left + strings.Repeat(strings.Repeat("─", width+2)+middle, boardSize-1) + strings.Repeat("─", width+2) + right + "\n"
+	}
+
+	var b strings.Builder
+	b.WriteString(border("┌", "┬", "┐"))
+	for row := 0; row < boardSize; row++ {
+		if row > 0 {
+			b.WriteString(border("├", "┼", "┤"))
+		}
+		for col := 0; col < boardSize; col++ {
+			i := row*boardSize + col
+
+			var label, colour string
+			switch board[i] {
+			case game.XPlayer:
+				label, colour = "X", ansiX
+			case game.OPlayer:
+				label, colour = "O", ansiO
+			default:
+				label, colour = strconv.Itoa(i+1), ansiNumber
+			}
+			cell := " " + strings.Repeat(" ", width-len(label)) + label + " "
+
+			b.WriteString("│")
+			if i == options.LastMove {
+				b.WriteString(ansiLastMove)
+			}
+			b.WriteString(colour + cell + ansiReset)
+		}
+		b.WriteString("│\n")
+	}
+	b.WriteString(border("└", "┴", "┘"))
+
+	return b.String()
+}
@@ -0,0 +1,40 @@
+package render
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var ansiEscape = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+func TestANSI(t *testing.T) {
+	board := []int{1, 0, 2, 0, 1, 0, 0, 0, 2}
+
+	got := ANSI(board, 3, Options{LastMove: 8})
+
+	want := "┌───┬───┬───┐\n│ X │ 2 │ O │\n├───┼───┼───┤\n│ 4 │ X │ 6 │\n├───┼───┼───┤\n│ 7 │ 8 │ O │\n└───┴───┴───┘\n"
+	if plain := ansiEscape.ReplaceAllString(got, ""); plain != want {
+		t.Errorf("got\n%s\nwant\n%s", plain, want)
+	}
+
+	for _, colouredCell := range []string{ansiX + " X ", ansiO + " O ", ansiNumber + " 2 ", ansiLastMove + ansiO + " O "} {
+		if !strings.Contains(got, colouredCell) {
+			t.Errorf("got %q without %q", got, colouredCell)
+		}
+	}
+	if strings.Count(got, ansiLastMove) != 1 {
+		t.Errorf("got %d highlighted cells want 1", strings.Count(got, ansiLastMove))
+	}
+}
+
+func TestANSIAlignsBigBoards(t *testing.T) {
+	got := ansiEscape.ReplaceAllString(ANSI(make([]int, 16), 4, Options{LastMove: -1}), "")
+
+	lines := strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+	for _, line := range lines {
+		if n := len([]rune(line)); n != len([]rune(lines[0])) {
+			t.Errorf("line %q has %d characters want %d", line, n, len([]rune(lines[0])))
+		}
+	}
+}
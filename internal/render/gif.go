@@ -0,0 +1,104 @@
+package render
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"io"
+	"time"
+
+	"github.com/isavita/tictactoe_api/internal/game"
+)
+
+const (
+	DefaultFrameDelay = 800 * time.Millisecond
+	MinFrameDelay     = 100 * time.Millisecond
+	MaxFrameDelay     = 5 * time.Second
+
+	// MaxGIFPixels bounds the pixels of all the frames of a GIF together, as
+	// drawing and encoding take time in proportion to them. It allows every
+	// move of a 6x6 game at the default cell size.
+	MaxGIFPixels = 16 << 20
+)
+
+// finalFrameHold is how many frame delays the last frame stays on screen
+// before the animation loops.
+const finalFrameHold = 3
+
+// GIFPixels returns the pixels of all the frames of the GIF of moves, which
+// GIF draws one by one.
+func GIFPixels(moves int, boardSize int, options Options) int {
+	side := boardSize * options.cellSize()
+	return (moves + 1) * side * side
+}
+
+// GIF renders an animated replay of a game: the empty board followed by the
+// board after each move, with the move highlighted. Moves are cell indexes
+// played alternately by X and O, and are not checked against the rules. The
+// winning line is drawn on the final frame. options.LastMove is ignored.
+func GIF(moves []int, boardSize int, options Options, delay time.Duration) ([]byte, error) {
+	return GIFContext(context.Background(), moves, boardSize, options, delay)
+}
+
+// GIFContext is GIF that stops once ctx is done, returning ctx.Err().
+func GIFContext(ctx context.Context, moves []int, boardSize int, options Options, delay time.Duration) ([]byte, error) {
+	if delay <= 0 {
+		delay = DefaultFrameDelay
+	}
+	// GIF delays are in hundredths of a second.
+	centiseconds := int(delay / (10 * time.Millisecond))
+	palette := options.theme().palette()
+
+	board := make([]int, boardSize*boardSize)
+	animation := &gif.GIF{}
+	for i := 0; i <= len(moves); i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		options.LastMove = -1
+		if i > 0 {
+			player := game.XPlayer
+			if i%2 == 0 {
+				player = game.OPlayer
+			}
+			board[moves[i-1]] = player
+			options.LastMove = moves[i-1]
+		}
+
+		frame := Image(board, boardSize, options)
+		paletted := image.NewPaletted(frame.Bounds(), palette)
+		draw.Draw(paletted, paletted.Bounds(), frame, image.Point{}, draw.Src)
+
+		animation.Image = append(animation.Image, paletted)
+		animation.Delay = append(animation.Delay, centiseconds)
+	}
+	animation.Delay[len(animation.Delay)-1] *= finalFrameHold
+
+	var b bytes.Buffer
+	if err := gif.EncodeAll(contextWriter{ctx, &b}, animation); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// contextWriter fails its writes once ctx is done, which stops the encoder.
+type contextWriter struct {
+	ctx context.Context
+	w   io.Writer
+}
+
+func (w contextWriter) Write(p []byte) (int, error) {
+	if err := w.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return w.w.Write(p)
+}
+
+// palette lists the theme colours. Image only paints with these, so frames
+// convert to it without any loss.
+func (t Theme) palette() color.Palette {
+	return color.Palette{t.Background, t.Grid, t.Number, t.X, t.O, t.Highlight, t.Strike}
+}
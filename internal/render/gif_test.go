@@ -0,0 +1,69 @@
+package render
+
+import (
+	"bytes"
+	"context"
+	"image/gif"
+	"testing"
+	"time"
+)
+
+func TestGIF(t *testing.T) {
+	light, _ := LookupTheme("light")
+
+	t.Run("has a frame for the empty board and every move", func(t *testing.T) {
+		data, err := GIF([]int{0, 3, 1, 4, 2}, 3, Options{CellSize: 20}, 500*time.Millisecond)
+		if err != nil {
+			t.Fatalf("got error %v when no error was expected", err)
+		}
+
+		animation, err := gif.DecodeAll(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("got error %v when no error was expected", err)
+		}
+
+		if len(animation.Image) != 6 {
+			t.Fatalf("got %d frames, want 6", len(animation.Image))
+		}
+		wantDelay := []int{50, 50, 50, 50, 50, 150}
+		for i, delay := range animation.Delay {
+			if delay != wantDelay[i] {
+				t.Errorf("frame %d: got delay %d, want %d", i, delay, wantDelay[i])
+			}
+		}
+
+		// The winning line runs through the middle of the top row, which is
+		// the centre of cell 2 at (2+20+10, 2+10).
+		if got := animation.Image[5].At(32, 12); got != light.Strike {
+			t.Errorf("final frame: got %v at the winning line, want %v", got, light.Strike)
+		}
+		if got := animation.Image[4].At(32, 12); got == light.Strike {
+			t.Errorf("frame 4: the winning line is drawn before the game is won")
+		}
+	})
+
+	t.Run("matches the PNG of the final board", func(t *testing.T) {
+		data, err := GIF([]int{5, 0}, 4, Options{CellSize: 24}, 0)
+		if err != nil {
+			t.Fatalf("got error %v when no error was expected", err)
+		}
+		animation, err := gif.DecodeAll(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("got error %v when no error was expected", err)
+		}
+
+		want := Image([]int{2, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}, 4, Options{LastMove: 0, CellSize: 24})
+		assertSameImages(t, animation.Image[len(animation.Image)-1], want)
+		if animation.Delay[0] != int(DefaultFrameDelay/(10*time.Millisecond)) {
+			t.Errorf("got delay %d, want the default", animation.Delay[0])
+		}
+	})
+
+	t.Run("stops once the context is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := GIFContext(ctx, []int{0, 3, 1}, 3, Options{}, 0); err != context.Canceled {
+			t.Errorf("got error %v want %v", err, context.Canceled)
+		}
+	})
+}
@@ -0,0 +1,140 @@
+package render
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"strconv"
+
+	"github.com/isavita/tictactoe_api/internal/game"
+)
+
+// PNG renders the board as a PNG image.
+func PNG(board []int, boardSize int, options Options) ([]byte, error) {
+	var b bytes.Buffer
+	if err := png.Encode(&b, Image(board, boardSize, options)); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// Image draws the board with the same layout as SVG.
+func Image(board []int, boardSize int, options Options) *image.RGBA {
+	cell := options.cellSize()
+	theme := options.theme()
+	padding := cell / 10
+	size := boardSize*cell + 2*padding
+	stroke := maxInt(cell/25, 1)
+
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(img, img.Bounds(), image.NewUniform(theme.Background), image.Point{}, draw.Src)
+
+	if options.LastMove >= 0 && options.LastMove < len(board) {
+		x, y := cellOrigin(options.LastMove, boardSize, cell, padding)
+		draw.Draw(img, image.Rect(x, y, x+cell, y+cell), image.NewUniform(theme.Highlight), image.Point{}, draw.Src)
+	}
+
+	for i := 1; i < boardSize; i++ {
+		offset := float64(padding + i*cell)
+		drawLine(img, offset, float64(padding), offset, float64(size-padding), float64(stroke), theme.Grid)
+		drawLine(img, float64(padding), offset, float64(size-padding), offset, float64(stroke), theme.Grid)
+	}
+
+	scale := maxInt(cell/40, 1)
+	inset := cell / 5
+	for i, value := range board {
+		x, y := cellOrigin(i, boardSize, cell, padding)
+		drawNumber(img, i+1, x+cell/12, y+cell/12, scale, theme.Number)
+
+		switch value {
+		case game.XPlayer:
+			drawLine(img, float64(x+inset), float64(y+inset), float64(x+cell-inset), float64(y+cell-inset), float64(2*stroke), theme.X)
+			drawLine(img, float64(x+cell-inset), float64(y+inset), float64(x+inset), float64(y+cell-inset), float64(2*stroke), theme.X)
+		case game.OPlayer:
+			drawRing(img, float64(x+cell/2), float64(y+cell/2), float64(cell/2-inset), float64(2*stroke), theme.O)
+		}
+	}
+
+	if line := game.WinningLine(board, boardSize); line != nil {
+		x1, y1 := cellOrigin(line[0], boardSize, cell, padding)
+		x2, y2 := cellOrigin(line[len(line)-1], boardSize, cell, padding)
+		drawLine(img, float64(x1+cell/2), float64(y1+cell/2), float64(x2+cell/2), float64(y2+cell/2), float64(3*stroke), theme.Strike)
+	}
+
+	return img
+}
+
+// drawLine paints a segment with round caps by testing the distance of every
+// pixel in its bounding box.
+func drawLine(img *image.RGBA, x1, y1, x2, y2, width float64, c color.RGBA) {
+	half := width / 2
+	bounds := image.Rect(
+		int(math.Floor(math.Min(x1, x2)-half)), int(math.Floor(math.Min(y1, y2)-half)),
+		int(math.Ceil(math.Max(x1, x2)+half))+1, int(math.Ceil(math.Max(y1, y2)+half))+1,
+	).Intersect(img.Bounds())
+
+	dx, dy := x2-x1, y2-y1
+	lengthSquared := dx*dx + dy*dy
+	for py := bounds.Min.Y; py < bounds.Max.Y; py++ {
+		for px := bounds.Min.X; px < bounds.Max.X; px++ {
+			cx, cy := float64(px)+0.5, float64(py)+0.5
+			t := 0.0
+			if lengthSquared > 0 {
+				t = math.Max(0, math.Min(1, ((cx-x1)*dx+(cy-y1)*dy)/lengthSquared))
+			}
+			if math.Hypot(cx-(x1+t*dx), cy-(y1+t*dy)) <= half {
+				img.SetRGBA(px, py, c)
+			}
+		}
+	}
+}
+
+func drawRing(img *image.RGBA, cx, cy, radius, width float64, c color.RGBA) {
+	half := width / 2
+	bounds := image.Rect(
+		int(cx-radius-half), int(cy-radius-half),
+		int(cx+radius+half)+1, int(cy+radius+half)+1,
+	).Intersect(img.Bounds())
+
+	for py := bounds.Min.Y; py < bounds.Max.Y; py++ {
+		for px := bounds.Min.X; px < bounds.Max.X; px++ {
+			d := math.Hypot(float64(px)+0.5-cx, float64(py)+0.5-cy)
+			if math.Abs(d-radius) <= half {
+				img.SetRGBA(px, py, c)
+			}
+		}
+	}
+}
+
+// digits is a 3x5 bitmap font; each row is three bits, most significant first.
+var digits = [10][5]uint8{
+	{7, 5, 5, 5, 7},
+	{2, 6, 2, 2, 7},
+	{7, 1, 7, 4, 7},
+	{7, 1, 7, 1, 7},
+	{5, 5, 7, 1, 1},
+	{7, 4, 7, 1, 7},
+	{7, 4, 7, 5, 7},
+	{7, 1, 1, 1, 1},
+	{7, 5, 7, 5, 7},
+	{7, 5, 7, 1, 7},
+}
+
+func drawNumber(img *image.RGBA, n int, x, y, scale int, c color.RGBA) {
+	uniform := image.NewUniform(c)
+	for _, d := range strconv.Itoa(n) {
+		glyph := digits[d-'0']
+		for row, bits := range glyph {
+			for col := 0; col < 3; col++ {
+				if bits&(4>>col) != 0 {
+					px, py := x+col*scale, y+row*scale
+					draw.Draw(img, image.Rect(px, py, px+scale, py+scale), uniform, image.Point{}, draw.Src)
+				}
+			}
+		}
+		x += 4 * scale
+	}
+}
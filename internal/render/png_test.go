@@ -0,0 +1,99 @@
+package render
+
+import (
+	"bytes"
+	"flag"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden images in testdata")
+
+func TestPNGGolden(t *testing.T) {
+	dark, _ := LookupTheme("dark")
+
+	cases := []struct {
+		name      string
+		board     []int
+		boardSize int
+		options   Options
+	}{
+		{"3x3_win", []int{1, 1, 1, 2, 2, 0, 0, 0, 0}, 3, Options{LastMove: 2}},
+		{"4x4_ongoing", []int{1, 0, 0, 0, 0, 2, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0}, 4, Options{LastMove: 10, CellSize: 48}},
+		{"5x5_dark", []int{2, 0, 0, 0, 1, 0, 2, 0, 1, 0, 0, 0, 2, 1, 0, 0, 1, 0, 2, 0, 1, 0, 0, 0, 2}, 5, Options{LastMove: 24, CellSize: 40, Theme: &dark}},
+		{"6x6_empty", make([]int, 36), 6, Options{LastMove: -1, CellSize: 32}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := PNG(tc.board, tc.boardSize, tc.options)
+			if err != nil {
+				t.Fatalf("got error %v when no error was expected", err)
+			}
+
+			golden := filepath.Join("testdata", tc.name+".png")
+			if *update {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("missing golden image, run the tests with -update: %v", err)
+			}
+			assertSameImage(t, got, want)
+		})
+	}
+}
+
+func TestImageSize(t *testing.T) {
+	for boardSize := 3; boardSize <= 6; boardSize++ {
+		t.Run(strconv.Itoa(boardSize), func(t *testing.T) {
+			img := Image(make([]int, boardSize*boardSize), boardSize, Options{LastMove: -1, CellSize: 20})
+
+			want := boardSize*20 + 2*2
+			if img.Bounds().Dx() != want || img.Bounds().Dy() != want {
+				t.Errorf("got %v want %dx%d", img.Bounds(), want, want)
+			}
+		})
+	}
+}
+
+func assertSameImage(t testing.TB, got []byte, want []byte) {
+	t.Helper()
+	gotImg, err := png.Decode(bytes.NewReader(got))
+	if err != nil {
+		t.Fatalf("got invalid PNG: %v", err)
+	}
+	wantImg, err := png.Decode(bytes.NewReader(want))
+	if err != nil {
+		t.Fatalf("got invalid golden PNG: %v", err)
+	}
+	assertSameImages(t, gotImg, wantImg)
+}
+
+func assertSameImages(t testing.TB, gotImg image.Image, wantImg image.Image) {
+	t.Helper()
+	if gotImg.Bounds() != wantImg.Bounds() {
+		t.Fatalf("got bounds %v want %v", gotImg.Bounds(), wantImg.Bounds())
+	}
+	bounds := gotImg.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if !sameColor(gotImg, wantImg, image.Pt(x, y)) {
+				t.Fatalf("images differ at %d,%d", x, y)
+			}
+		}
+	}
+}
+
+func sameColor(a image.Image, b image.Image, p image.Point) bool {
+	r1, g1, b1, a1 := a.At(p.X, p.Y).RGBA()
+	r2, g2, b2, a2 := b.At(p.X, p.Y).RGBA()
+	return r1 == r2 && g1 == g2 && b1 == b2 && a1 == a2
+}
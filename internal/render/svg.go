@@ -0,0 +1,109 @@
+// Package render draws boards as images for clients that can't show the
+// text boardDisplay.
+package render
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/isavita/tictactoe_api/internal/game"
+)
+
+// Options control how the board is drawn. LastMove is the index of the
+// highlighted cell or -1 for none. A zero CellSize or Theme uses the defaults.
+type Options struct {
+	LastMove int
+	CellSize int
+	Theme    *Theme
+}
+
+const (
+	DefaultCellSize = 100
+	MinCellSize     = 16
+	MaxCellSize     = 256
+)
+
+func (o Options) cellSize() int {
+	if o.CellSize <= 0 {
+		return DefaultCellSize
+	}
+	return o.CellSize
+}
+
+func (o Options) theme() Theme {
+	if o.Theme == nil {
+		theme, _ := LookupTheme(DefaultTheme)
+		return theme
+	}
+	return *o.Theme
+}
+
+// SVG renders the board as a standalone SVG document.
+func SVG(board []int, boardSize int, options Options) []byte {
+	cell := options.cellSize()
+	theme := options.theme()
+	padding := cell / 10
+	size := boardSize*cell + 2*padding
+	stroke := maxInt(cell/25, 1)
+
+	var svg strings.Builder
+	fmt.Fprintf(&svg, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" role="img" aria-label="%s">`+"\n",
+		size, size, size, size, ariaLabel(board, boardSize))
+	fmt.Fprintf(&svg, `<rect width="%d" height="%d" fill="%s"/>`+"\n", size, size, hex(theme.Background))
+
+	if options.LastMove >= 0 && options.LastMove < len(board) {
+		x, y := cellOrigin(options.LastMove, boardSize, cell, padding)
+		fmt.Fprintf(&svg, `<rect class="last-move" x="%d" y="%d" width="%d" height="%d" fill="%s"/>`+"\n", x, y, cell, cell, hex(theme.Highlight))
+	}
+
+	for i := 1; i < boardSize; i++ {
+		offset := padding + i*cell
+		fmt.Fprintf(&svg, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="%s" stroke-width="%d" stroke-linecap="round"/>`+"\n",
+			offset, padding, offset, size-padding, hex(theme.Grid), stroke)
+		fmt.Fprintf(&svg, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="%s" stroke-width="%d" stroke-linecap="round"/>`+"\n",
+			padding, offset, size-padding, offset, hex(theme.Grid), stroke)
+	}
+
+	fontSize := maxInt(cell/6, 8)
+	inset := cell / 5
+	for i, value := range board {
+		x, y := cellOrigin(i, boardSize, cell, padding)
+		fmt.Fprintf(&svg, `<text x="%d" y="%d" font-family="sans-serif" font-size="%d" fill="%s">%d</text>`+"\n",
+			x+cell/12, y+cell/12+fontSize, fontSize, hex(theme.Number), i+1)
+
+		switch value {
+		case game.XPlayer:
+			fmt.Fprintf(&svg, `<path class="x" d="M%d %dL%d %dM%d %dL%d %d" stroke="%s" stroke-width="%d" stroke-linecap="round"/>`+"\n",
+				x+inset, y+inset, x+cell-inset, y+cell-inset, x+cell-inset, y+inset, x+inset, y+cell-inset, hex(theme.X), 2*stroke)
+		case game.OPlayer:
+			fmt.Fprintf(&svg, `<circle class="o" cx="%d" cy="%d" r="%d" fill="none" stroke="%s" stroke-width="%d"/>`+"\n",
+				x+cell/2, y+cell/2, cell/2-inset, hex(theme.O), 2*stroke)
+		}
+	}
+
+	if line := game.WinningLine(board, boardSize); line != nil {
+		x1, y1 := cellOrigin(line[0], boardSize, cell, padding)
+		x2, y2 := cellOrigin(line[len(line)-1], boardSize, cell, padding)
+		fmt.Fprintf(&svg, `<line class="winning-line" x1="%d" y1="%d" x2="%d" y2="%d" stroke="%s" stroke-width="%d" stroke-linecap="round" opacity="0.75"/>`+"\n",
+			x1+cell/2, y1+cell/2, x2+cell/2, y2+cell/2, hex(theme.Strike), 3*stroke)
+	}
+
+	svg.WriteString("</svg>\n")
+	return []byte(svg.String())
+}
+
+func cellOrigin(index int, boardSize int, cell int, padding int) (int, int) {
+	return padding + (index%boardSize)*cell, padding + (index/boardSize)*cell
+}
+
+func ariaLabel(board []int, boardSize int) string {
+	rows := strings.Fields(game.FormatPosition(board, boardSize, 0))[0]
+	return fmt.Sprintf("Tic Tac Toe board %dx%d: %s", boardSize, boardSize, rows)
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,66 @@
+package render
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestSVG(t *testing.T) {
+	t.Run("is well-formed for every board size", func(t *testing.T) {
+		for boardSize := 3; boardSize <= 6; boardSize++ {
+			board := make([]int, boardSize*boardSize)
+			board[0] = 1
+			board[len(board)-1] = 2
+
+			got := SVG(board, boardSize, Options{LastMove: 0})
+			assertWellFormed(t, got)
+
+			if n := strings.Count(string(got), "<text "); n != boardSize*boardSize {
+				t.Errorf("got %d cell numbers want %d", n, boardSize*boardSize)
+			}
+		}
+	})
+
+	t.Run("draws glyphs, the last move and the winning line", func(t *testing.T) {
+		board := []int{1, 1, 1, 2, 2, 0, 0, 0, 0}
+
+		got := string(SVG(board, 3, Options{LastMove: 2}))
+
+		if n := strings.Count(got, `class="x"`); n != 3 {
+			t.Errorf("got %d X glyphs want 3", n)
+		}
+		if n := strings.Count(got, `class="o"`); n != 2 {
+			t.Errorf("got %d O glyphs want 2", n)
+		}
+		if !strings.Contains(got, `<rect class="last-move" x="210" y="10"`) {
+			t.Errorf("missing last move highlight in %s", got)
+		}
+		if !strings.Contains(got, `<line class="winning-line" x1="60" y1="60" x2="260" y2="60"`) {
+			t.Errorf("missing winning line in %s", got)
+		}
+	})
+
+	t.Run("leaves out the highlight and strike-through when not needed", func(t *testing.T) {
+		got := string(SVG(make([]int, 16), 4, Options{LastMove: -1}))
+
+		if strings.Contains(got, "last-move") || strings.Contains(got, "winning-line") {
+			t.Errorf("got unexpected decorations in %s", got)
+		}
+	})
+}
+
+func assertWellFormed(t testing.TB, svg []byte) {
+	t.Helper()
+	decoder := xml.NewDecoder(strings.NewReader(string(svg)))
+	for {
+		_, err := decoder.Token()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			t.Fatalf("got invalid XML: %v", err)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package render
+
+import (
+	"fmt"
+	"image/color"
+	"sort"
+)
+
+// Theme holds the colours used to draw a board.
+type Theme struct {
+	Background color.RGBA
+	Grid       color.RGBA
+	Number     color.RGBA
+	X          color.RGBA
+	O          color.RGBA
+	Highlight  color.RGBA
+	Strike     color.RGBA
+}
+
+const DefaultTheme = "light"
+
+var themes = map[string]Theme{
+	"light": {
+		Background: color.RGBA{0xff, 0xff, 0xff, 0xff},
+		Grid:       color.RGBA{0x33, 0x33, 0x33, 0xff},
+		Number:     color.RGBA{0x9a, 0x9a, 0x9a, 0xff},
+		X:          color.RGBA{0xd6, 0x45, 0x41, 0xff},
+		O:          color.RGBA{0x2c, 0x6f, 0xbb, 0xff},
+		Highlight:  color.RGBA{0xff, 0xf1, 0xa8, 0xff},
+		Strike:     color.RGBA{0x2e, 0x2e, 0x2e, 0xff},
+	},
+	"dark": {
+		Background: color.RGBA{0x1e, 0x1e, 0x24, 0xff},
+		Grid:       color.RGBA{0xc8, 0xc8, 0xd0, 0xff},
+		Number:     color.RGBA{0x6a, 0x6a, 0x78, 0xff},
+		X:          color.RGBA{0xff, 0x6b, 0x6b, 0xff},
+		O:          color.RGBA{0x5c, 0xc8, 0xff, 0xff},
+		Highlight:  color.RGBA{0x4a, 0x45, 0x20, 0xff},
+		Strike:     color.RGBA{0xf0, 0xf0, 0xf0, 0xff},
+	},
+	"high-contrast": {
+		Background: color.RGBA{0xff, 0xff, 0xff, 0xff},
+		Grid:       color.RGBA{0x00, 0x00, 0x00, 0xff},
+		Number:     color.RGBA{0x55, 0x55, 0x55, 0xff},
+		X:          color.RGBA{0x00, 0x00, 0x00, 0xff},
+		O:          color.RGBA{0x00, 0x00, 0x00, 0xff},
+		Highlight:  color.RGBA{0xff, 0xe0, 0x00, 0xff},
+		Strike:     color.RGBA{0xe0, 0x00, 0x00, 0xff},
+	},
+}
+
+// LookupTheme returns the named theme; an empty name is the default theme.
+func LookupTheme(name string) (Theme, bool) {
+	if name == "" {
+		name = DefaultTheme
+	}
+	theme, ok := themes[name]
+	return theme, ok
+}
+
+// ThemeNames lists the available themes in alphabetical order.
+func ThemeNames() []string {
+	names := make([]string, 0, len(themes))
+	for name := range themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func hex(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
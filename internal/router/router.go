@@ -0,0 +1,181 @@
+// Package router dispatches HTTP requests by method and path. A pattern is a
+// path whose segments are either literal or a {name} parameter matching one
+// non-empty segment, e.g. /v1/games/{id}/moves; handlers read parameters
+// with Param.
+//
+// Literal segments win over parameters, so /v1/games/import is routed before
+// /v1/games/{id}. HEAD requests are served by the GET handler, OPTIONS
+// requests are answered with the Allow header, and other methods without a
+// route get 405 Method Not Allowed with the Allow header set.
+package router
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+type Router struct {
+	// NotFound handles requests for paths without routes.
+	NotFound http.Handler
+	// MethodNotAllowed handles requests whose path has routes but none for
+	// the method. The Allow header is set when it is called.
+	MethodNotAllowed http.Handler
+
+	routes []*route
+}
+
+type route struct {
+	segments []string
+	handlers map[string]http.Handler
+}
+
+func New() *Router {
+	return &Router{
+		NotFound: http.HandlerFunc(http.NotFound),
+		MethodNotAllowed: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		}),
+	}
+}
+
+// Handle registers the handler for the method and pattern. It panics if the
+// pattern is malformed or already has a handler for the method.
+func (rt *Router) Handle(method string, pattern string, handler http.Handler) {
+	if !strings.HasPrefix(pattern, "/") {
+		panic("router: pattern " + pattern + " does not start with /")
+	}
+	segments := strings.Split(pattern, "/")[1:]
+	for _, segment := range segments {
+		if isParam(segment) != (strings.ContainsAny(segment, "{}")) || segment == "{}" {
+			panic("router: invalid segment " + segment + " in pattern " + pattern)
+		}
+	}
+
+	for _, r := range rt.routes {
+		if shape(r.segments) != shape(segments) {
+			continue
+		}
+		if _, ok := r.handlers[method]; ok {
+			panic("router: " + method + " " + pattern + " is already registered")
+		}
+		r.handlers[method] = handler
+		return
+	}
+	rt.routes = append(rt.routes, &route{segments, map[string]http.Handler{method: handler}})
+}
+
+func (rt *Router) HandleFunc(method string, pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	rt.Handle(method, pattern, http.HandlerFunc(handler))
+}
+
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	segments := strings.Split(r.URL.Path, "/")[1:]
+
+	var best *route
+	var params map[string]string
+	for _, route := range rt.routes {
+		if routeParams, ok := route.match(segments); ok && (best == nil || route.moreSpecific(best)) {
+			best, params = route, routeParams
+		}
+	}
+	if best == nil {
+		rt.NotFound.ServeHTTP(w, r)
+		return
+	}
+
+	if len(params) > 0 {
+		r = r.WithContext(context.WithValue(r.Context(), paramsKey{}, params))
+	}
+	if handler, ok := best.handlers[r.Method]; ok {
+		handler.ServeHTTP(w, r)
+		return
+	}
+	if handler, ok := best.handlers[http.MethodGet]; ok && r.Method == http.MethodHead {
+		handler.ServeHTTP(w, r)
+		return
+	}
+
+	w.Header().Set("Allow", best.allow())
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	rt.MethodNotAllowed.ServeHTTP(w, r)
+}
+
+func (r *route) match(segments []string) (map[string]string, bool) {
+	if len(segments) != len(r.segments) {
+		return nil, false
+	}
+	var params map[string]string
+	for i, segment := range r.segments {
+		switch {
+		case isParam(segment) && segments[i] != "":
+			if params == nil {
+				params = map[string]string{}
+			}
+			params[segment[1:len(segment)-1]] = segments[i]
+		case segment != segments[i]:
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// moreSpecific reports whether r has a literal segment where other has a
+// parameter, comparing from the left.
+func (r *route) moreSpecific(other *route) bool {
+	for i := range r.segments {
+		if param, otherParam := isParam(r.segments[i]), isParam(other.segments[i]); param != otherParam {
+			return otherParam
+		}
+	}
+	return false
+}
+
+// allow lists the methods of the route for the Allow header.
+func (r *route) allow() string {
+	var methods []string
+	for method := range r.handlers {
+		methods = append(methods, method)
+	}
+	if _, ok := r.handlers[http.MethodOptions]; !ok {
+		methods = append(methods, http.MethodOptions)
+	}
+	if _, ok := r.handlers[http.MethodGet]; ok {
+		if _, ok := r.handlers[http.MethodHead]; !ok {
+			methods = append(methods, http.MethodHead)
+		}
+	}
+	sort.Strings(methods)
+	return strings.Join(methods, ", ")
+}
+
+// shape is the pattern of segments with the parameter names left out, so
+// that /games/{id} and /games/{name} are the same route.
+func shape(segments []string) string {
+	var b strings.Builder
+	for _, segment := range segments {
+		b.WriteString("/")
+		if isParam(segment) {
+			segment = "{}"
+		}
+		b.WriteString(segment)
+	}
+	return b.String()
+}
+
+func isParam(segment string) bool {
+	return strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}")
+}
+
+type paramsKey struct{}
+
+// Param returns the value of the named path parameter, or "" if the route of
+// the request has no such parameter.
+func Param(r *http.Request, name string) string {
+	params, _ := r.Context().Value(paramsKey{}).(map[string]string)
+	return params[name]
+}
@@ -0,0 +1,86 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRouter(t *testing.T) {
+	rt := New()
+	handler := func(name string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(name + " " + Param(r, "id") + Param(r, "name")))
+		}
+	}
+	rt.HandleFunc(http.MethodPost, "/games", handler("create"))
+	rt.HandleFunc(http.MethodGet, "/games/{id}", handler("get"))
+	rt.HandleFunc(http.MethodDelete, "/games/{id}", handler("delete"))
+	rt.HandleFunc(http.MethodPost, "/games/import", handler("import"))
+	rt.HandleFunc(http.MethodGet, "/games/{id}/moves/{name}", handler("move"))
+	rt.HandleFunc(http.MethodGet, "/", handler("root"))
+
+	cases := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+		wantBody   string
+		wantAllow  string
+	}{
+		{"literal route", http.MethodPost, "/games", http.StatusOK, "create ", ""},
+		{"path parameter", http.MethodGet, "/games/abc", http.StatusOK, "get abc", ""},
+		{"method of the same pattern", http.MethodDelete, "/games/abc", http.StatusOK, "delete abc", ""},
+		{"literal wins over parameter", http.MethodPost, "/games/import", http.StatusOK, "import ", ""},
+		{"two parameters", http.MethodGet, "/games/abc/moves/5", http.StatusOK, "move abc5", ""},
+		{"root", http.MethodGet, "/", http.StatusOK, "root ", ""},
+		{"head served by get", http.MethodHead, "/games/abc", http.StatusOK, "", ""},
+		{"options", http.MethodOptions, "/games/abc", http.StatusNoContent, "", "DELETE, GET, HEAD, OPTIONS"},
+		{"method not allowed", http.MethodPut, "/games/abc", http.StatusMethodNotAllowed, "Method Not Allowed\n", "DELETE, GET, HEAD, OPTIONS"},
+		{"method of the more specific pattern only", http.MethodGet, "/games/import", http.StatusMethodNotAllowed, "Method Not Allowed\n", "OPTIONS, POST"},
+		{"unknown path", http.MethodGet, "/players", http.StatusNotFound, "404 page not found\n", ""},
+		{"empty parameter", http.MethodGet, "/games/", http.StatusNotFound, "404 page not found\n", ""},
+		{"extra segment", http.MethodGet, "/games/abc/moves", http.StatusNotFound, "404 page not found\n", ""},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := httptest.NewServer(rt)
+			defer s.Close()
+			req, _ := http.NewRequest(tc.method, s.URL+tc.path, nil)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("got error %v when no error was expected", err)
+			}
+			defer resp.Body.Close()
+
+			body := make([]byte, 64)
+			n, _ := resp.Body.Read(body)
+			if resp.StatusCode != tc.wantStatus || string(body[:n]) != tc.wantBody || resp.Header.Get("Allow") != tc.wantAllow {
+				t.Errorf("got %d %q with Allow %q want %d %q with Allow %q", resp.StatusCode, body[:n], resp.Header.Get("Allow"), tc.wantStatus, tc.wantBody, tc.wantAllow)
+			}
+		})
+	}
+}
+
+func TestHandlePanics(t *testing.T) {
+	for _, pattern := range []string{"games", "/games/{}", "/games/{id", "/games/a{id}"} {
+		t.Run(pattern, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("got no panic for pattern %q", pattern)
+				}
+			}()
+			New().HandleFunc(http.MethodGet, pattern, http.NotFound)
+		})
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("got no panic for a duplicate route")
+		}
+	}()
+	rt := New()
+	rt.HandleFunc(http.MethodGet, "/games/{id}", http.NotFound)
+	rt.HandleFunc(http.MethodGet, "/games/{name}", http.NotFound)
+}
@@ -0,0 +1,590 @@
+// Package session keeps server-held games: the board, the move log, the seated
+// players and the subscribers that are notified about every change.
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/isavita/tictactoe_api/internal/game"
+	"github.com/isavita/tictactoe_api/internal/model"
+)
+
+var (
+	ErrGameFull          = errors.New("both players have already joined the game")
+	ErrWaitingOpponent   = errors.New("waiting for the second player to join")
+	ErrGameOver          = errors.New("the game is already over")
+	ErrNotYourTurn       = errors.New("it's not your turn")
+	ErrInvalidPosition   = errors.New("invalid position: choose an empty cell numbered as in boardDisplay")
+	ErrHumanGame         = errors.New("the game is played between two humans over WebSocket")
+	ErrAIGame            = errors.New("the game is played against the AI")
+	ErrTakebacksDisabled = errors.New("takebacks are not allowed in this game")
+	ErrTakebackLimit     = errors.New("no takebacks left in this game")
+	ErrNothingToUndo     = errors.New("there is no move to take back")
+	ErrResultMismatch    = errors.New("the result does not match the moves")
+	ErrTooManyGames      = errors.New("the server holds too many games")
+)
+
+// subscriberBuffer is how many events a slow subscriber may lag behind
+// before it is dropped.
+const subscriberBuffer = 32
+
+// Event describes a change to a game. Moves and takebacks are numbered by ID
+// in the order they happened, starting from 1, and are kept in the game's
+// event log; an ID is never reused, even after moves are taken back. Move is
+// the number of moves on the board after the event. Other events have no ID.
+type Event struct {
+	ID       int
+	Type     string
+	Move     int
+	Player   int
+	Position int
+	Game     model.GameRecord
+}
+
+// Options configure a new game. Difficulty is one of the game.Difficulty
+// constants and AIPlayer the side the AI plays; both only apply to AI games.
+// MaxTakebacks of 0 means unlimited when takebacks are allowed.
+type Options struct {
+	BoardSize      int
+	Mode           string
+	Difficulty     int
+	AIPlayer       int
+	AllowTakebacks bool
+	MaxTakebacks   int
+	// DisplayStyle selects the boardDisplay renderer, see game.LookupRenderer.
+	DisplayStyle string
+	// SearchDepth is how many moves ahead the AI looks, see
+	// game.GameState.SetSearchDepth.
+	SearchDepth int
+}
+
+type Game struct {
+	// turn is held by the actions of AI games for as long as the AI searches,
+	// so that mu only guards the state and readers are not held up.
+	turn    sync.Mutex
+	mu      sync.Mutex
+	id      string
+	options Options
+	state   *game.GameState
+	moves   []model.Move
+	// events is the log of the moves and takebacks, without records.
+	events    []Event
+	takebacks int
+	createdAt time.Time
+	// updatedAt is when the game last changed, by the clock now.
+	updatedAt   time.Time
+	now         func() time.Time
+	seats       map[int]bool
+	subscribers map[chan Event]struct{}
+}
+
+func newGame(id string, options Options, createdAt time.Time, now func() time.Time) *Game {
+	g := &Game{
+		id:          id,
+		options:     options,
+		createdAt:   createdAt,
+		updatedAt:   now(),
+		now:         now,
+		seats:       make(map[int]bool),
+		subscribers: make(map[chan Event]struct{}),
+	}
+	g.rebuild()
+
+	return g
+}
+
+func (g *Game) ID() string {
+	return g.id
+}
+
+// Join seats a new player, X first and then O.
+func (g *Game) Join() (int, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.options.Mode == model.GameModeAI {
+		return 0, ErrAIGame
+	}
+
+	for _, player := range []int{game.XPlayer, game.OPlayer} {
+		if !g.seats[player] {
+			g.seats[player] = true
+			g.publish(Event{Type: model.MessageTypePlayerJoined, Player: player})
+			return player, nil
+		}
+	}
+
+	return 0, ErrGameFull
+}
+
+// Leave frees the player's seat so they can reconnect.
+func (g *Game) Leave(player int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if !g.seats[player] {
+		return
+	}
+	delete(g.seats, player)
+	g.publish(Event{Type: model.MessageTypePlayerLeft, Player: player})
+}
+
+// Play validates and applies a move for the player. Position is numbered from
+// 1 to n as in boardDisplay.
+func (g *Game) Play(player int, position int) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.options.Mode == model.GameModeAI {
+		return ErrAIGame
+	}
+	if g.state.Status() != model.GameStatusOngoing {
+		return ErrGameOver
+	}
+	if len(g.seats) < 2 {
+		return ErrWaitingOpponent
+	}
+
+	return g.play(player, position)
+}
+
+// PlayAgainstAI applies the human move and the AI's reply. The AI searches
+// until ctx is done and then plays the best move it has found; when it has
+// none, neither move is played and ctx.Err() is returned.
+func (g *Game) PlayAgainstAI(ctx context.Context, position int) error {
+	g.turn.Lock()
+	defer g.turn.Unlock()
+
+	g.mu.Lock()
+	human := game.GetOponent(g.options.AIPlayer)
+	state, err := g.stateAfter(human, position)
+	g.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	move, err := g.aiMove(ctx, state)
+	if err != nil {
+		return err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.play(human, position)
+	if move != -1 {
+		g.play(g.options.AIPlayer, move+1)
+	}
+
+	return nil
+}
+
+// Undo takes back the last human move together with the AI's reply to it.
+func (g *Game) Undo() error {
+	g.turn.Lock()
+	defer g.turn.Unlock()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.options.Mode != model.GameModeAI {
+		return ErrHumanGame
+	}
+	if !g.options.AllowTakebacks {
+		return ErrTakebacksDisabled
+	}
+	if g.options.MaxTakebacks > 0 && g.takebacks >= g.options.MaxTakebacks {
+		return ErrTakebackLimit
+	}
+
+	human := game.GetOponent(g.options.AIPlayer)
+	last := -1
+	for i, move := range g.moves {
+		if move.Player == human {
+			last = i
+		}
+	}
+	if last == -1 {
+		return ErrNothingToUndo
+	}
+
+	g.moves = g.moves[:last]
+	g.takebacks++
+	g.rebuild()
+	g.publish(Event{Type: model.MessageTypeUndo, Move: len(g.moves)})
+
+	return nil
+}
+
+// play must be called with g.mu held.
+func (g *Game) play(player int, position int) error {
+	if g.state.CurrentPlayer() != player {
+		return ErrNotYourTurn
+	}
+	if !g.state.Play(position - 1) {
+		return ErrInvalidPosition
+	}
+
+	g.moves = append(g.moves, model.Move{Player: player, Position: position})
+	if g.state.Status() == model.GameStatusOngoing {
+		g.state.NextTurn()
+	}
+	g.publish(Event{Type: model.MessageTypeMove, Move: len(g.moves), Player: player, Position: position})
+
+	return nil
+}
+
+// stateAfter returns a copy of the state with the move of the player played
+// on it, for the AI to search without g.mu. It must be called with g.mu held.
+func (g *Game) stateAfter(player int, position int) (*game.GameState, error) {
+	if g.options.Mode != model.GameModeAI {
+		return nil, ErrHumanGame
+	}
+	if g.state.Status() != model.GameStatusOngoing {
+		return nil, ErrGameOver
+	}
+	if g.state.CurrentPlayer() != player {
+		return nil, ErrNotYourTurn
+	}
+
+	state := game.NewGameState(append([]int(nil), g.state.Board()...), g.state.BoardSize(), player)
+	if !state.Play(position - 1) {
+		return nil, ErrInvalidPosition
+	}
+	if state.Status() == model.GameStatusOngoing {
+		state.NextTurn()
+	}
+
+	return state, nil
+}
+
+// aiMove finds the AI's move in the state, or returns -1 when it is not the
+// AI's turn. The search stops once ctx is done, and ctx.Err() is returned
+// only when no move was found by then.
+func (g *Game) aiMove(ctx context.Context, state *game.GameState) (int, error) {
+	if g.options.Mode != model.GameModeAI || state.Status() != model.GameStatusOngoing || state.CurrentPlayer() != g.options.AIPlayer {
+		return -1, nil
+	}
+
+	state.SetSearchDepth(g.options.SearchDepth)
+	move, err := state.FindMoveContext(ctx, g.options.Difficulty)
+	if move == -1 {
+		return -1, err
+	}
+	return move, nil
+}
+
+// rebuild reconstructs the board from the move log. It must be called with
+// g.mu held.
+func (g *Game) rebuild() {
+	size := g.options.BoardSize
+	board := make([]int, size*size)
+	currentPlayer := game.XPlayer
+	for _, move := range g.moves {
+		board[move.Position-1] = move.Player
+		currentPlayer = game.GetOponent(move.Player)
+	}
+
+	g.state = game.NewGameState(board, size, currentPlayer)
+}
+
+func (g *Game) Record() model.GameRecord {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.record()
+}
+
+func (g *Game) record() model.GameRecord {
+	board := append([]int(nil), g.state.Board()...)
+	status := g.state.Status()
+	nextPlayer := -1
+	if status == model.GameStatusOngoing {
+		nextPlayer = g.state.CurrentPlayer()
+	}
+
+	players := make([]int, 0, 2)
+	for _, player := range []int{game.XPlayer, game.OPlayer} {
+		if g.seats[player] {
+			players = append(players, player)
+		}
+	}
+
+	record := model.GameRecord{
+		ID:             g.id,
+		Mode:           g.options.Mode,
+		Board:          board,
+		BoardSize:      g.state.BoardSize(),
+		BoardDisplay:   game.LookupRendererOrDefault(g.options.DisplayStyle).Render(board, g.state.BoardSize()),
+		DisplayStyle:   g.options.DisplayStyle,
+		GameStatus:     status,
+		NextPlayer:     nextPlayer,
+		Players:        players,
+		Moves:          append([]model.Move{}, g.moves...),
+		AllowTakebacks: g.options.AllowTakebacks,
+		MaxTakebacks:   g.options.MaxTakebacks,
+		Takebacks:      g.takebacks,
+		CreatedAt:      g.createdAt,
+	}
+	if g.options.Mode == model.GameModeAI {
+		record.Difficulty = game.DifficultyLevel(g.options.Difficulty)
+		record.AIPlayer = g.options.AIPlayer
+	}
+
+	return record
+}
+
+// idle returns when the game last changed and whether it is finished. It
+// reports false while players or spectators are connected to the game.
+func (g *Game) idle() (time.Time, bool, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.updatedAt, g.state.Status() != model.GameStatusOngoing, len(g.subscribers) == 0
+}
+
+// Subscribe registers for events about the game. The channel is closed when
+// the returned cancel function is called or the subscriber falls too far behind.
+func (g *Game) Subscribe() (<-chan Event, func()) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.subscribe()
+}
+
+// Watch returns the current record and event log together with a
+// subscription, so that every later event is delivered and none is missed in
+// between. The events of the log carry no record.
+func (g *Game) Watch() (model.GameRecord, []Event, <-chan Event, func()) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	events, cancel := g.subscribe()
+	return g.record(), append([]Event(nil), g.events...), events, cancel
+}
+
+// subscribe must be called with g.mu held.
+func (g *Game) subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+	g.subscribers[ch] = struct{}{}
+
+	cancel := func() {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		if _, ok := g.subscribers[ch]; ok {
+			delete(g.subscribers, ch)
+			close(ch)
+		}
+	}
+
+	return ch, cancel
+}
+
+// publish must be called with g.mu held.
+func (g *Game) publish(event Event) {
+	g.updatedAt = g.now()
+	if event.Type == model.MessageTypeMove || event.Type == model.MessageTypeUndo {
+		event.ID = len(g.events) + 1
+		g.events = append(g.events, event)
+	}
+	event.Game = g.record()
+	for ch := range g.subscribers {
+		select {
+		case ch <- event:
+		default:
+			delete(g.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// StoreOptions bound the games a store keeps. Games are dropped once nobody
+// has been connected to them for their timeout since they last changed.
+type StoreOptions struct {
+	// IdleTimeout is how long an unfinished game is kept. It defaults to
+	// DefaultIdleTimeout.
+	IdleTimeout time.Duration
+	// FinishedTimeout is how long a finished game is kept. It defaults to
+	// DefaultFinishedTimeout.
+	FinishedTimeout time.Duration
+	// MaxGames is the most games the store holds. When it is full, the game
+	// that has been idle the longest makes room for a new one. It defaults to
+	// DefaultMaxGames.
+	MaxGames int
+}
+
+const (
+	DefaultIdleTimeout     = 24 * time.Hour
+	DefaultFinishedTimeout = time.Hour
+	DefaultMaxGames        = 10000
+)
+
+// sweepInterval is how often the store drops the games that timed out.
+const sweepInterval = time.Minute
+
+type Store struct {
+	options StoreOptions
+	now     func() time.Time
+
+	mu        sync.RWMutex
+	games     map[string]*Game
+	lastSweep time.Time
+}
+
+func NewStore(options StoreOptions) *Store {
+	if options.IdleTimeout <= 0 {
+		options.IdleTimeout = DefaultIdleTimeout
+	}
+	if options.FinishedTimeout <= 0 {
+		options.FinishedTimeout = DefaultFinishedTimeout
+	}
+	if options.MaxGames <= 0 {
+		options.MaxGames = DefaultMaxGames
+	}
+	return &Store{
+		options: options,
+		now:     time.Now,
+		games:   make(map[string]*Game),
+	}
+}
+
+// Create starts a new game. Options must already be validated; an AI playing
+// X makes its first move straight away, searching until ctx is done.
+func (s *Store) Create(ctx context.Context, options Options) (*Game, error) {
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+
+	// The game is not stored yet, so the AI searches its state directly
+	g := newGame(id, options, s.now().UTC(), s.now)
+	move, err := g.aiMove(ctx, g.state)
+	if err != nil {
+		return nil, err
+	}
+	if move != -1 {
+		g.mu.Lock()
+		g.play(options.AIPlayer, move+1)
+		g.mu.Unlock()
+	}
+
+	if err := s.add(g); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// Import replays the moves of a recorded game through the game rules, checks
+// that they end in the given game status and stores the result as a new game.
+// In a game against the AI that is left on the AI's turn, the AI replies
+// straight away, searching until ctx is done.
+func (s *Store) Import(ctx context.Context, options Options, moves []model.Move, status string, createdAt time.Time) (*Game, error) {
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+
+	g := newGame(id, options, createdAt, s.now)
+	g.mu.Lock()
+	for i, move := range moves {
+		if g.state.Status() != model.GameStatusOngoing {
+			err = ErrGameOver
+		} else {
+			err = g.play(move.Player, move.Position)
+		}
+		if err != nil {
+			g.mu.Unlock()
+			return nil, fmt.Errorf("move %d: %w", i+1, err)
+		}
+	}
+	if g.state.Status() != status {
+		g.mu.Unlock()
+		return nil, ErrResultMismatch
+	}
+	g.mu.Unlock()
+
+	move, err := g.aiMove(ctx, g.state)
+	if err != nil {
+		return nil, err
+	}
+	if move != -1 {
+		g.mu.Lock()
+		g.play(options.AIPlayer, move+1)
+		g.mu.Unlock()
+	}
+
+	if err := s.add(g); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// add stores the game, dropping the games that timed out first. It fails
+// with ErrTooManyGames when the store is full of games that are in use.
+func (s *Store) add(g *Game) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.lastSweep) >= sweepInterval || len(s.games) >= s.options.MaxGames {
+		s.sweep(now)
+	}
+	if len(s.games) >= s.options.MaxGames && !s.evict() {
+		return ErrTooManyGames
+	}
+
+	s.games[g.id] = g
+	return nil
+}
+
+// sweep drops the games that timed out. It must be called with s.mu held.
+func (s *Store) sweep(now time.Time) {
+	for id, g := range s.games {
+		idleSince, finished, ok := g.idle()
+		timeout := s.options.IdleTimeout
+		if finished {
+			timeout = s.options.FinishedTimeout
+		}
+		if ok && now.Sub(idleSince) >= timeout {
+			delete(s.games, id)
+		}
+	}
+	s.lastSweep = now
+}
+
+// evict drops the game that has been idle the longest, and reports whether
+// there was one. It must be called with s.mu held.
+func (s *Store) evict() bool {
+	var oldestID string
+	var oldest time.Time
+	for id, g := range s.games {
+		if idleSince, _, ok := g.idle(); ok && (oldestID == "" || idleSince.Before(oldest)) {
+			oldestID, oldest = id, idleSince
+		}
+	}
+	if oldestID == "" {
+		return false
+	}
+	delete(s.games, oldestID)
+	return true
+}
+
+func (s *Store) Get(id string) (*Game, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	g, ok := s.games[id]
+	return g, ok
+}
+
+func newID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
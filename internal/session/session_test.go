@@ -0,0 +1,242 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/isavita/tictactoe_api/internal/game"
+	"github.com/isavita/tictactoe_api/internal/model"
+)
+
+func TestGamePlay(t *testing.T) {
+	store := NewStore(StoreOptions{})
+	g, err := store.Create(context.Background(), Options{BoardSize: 3, Mode: model.GameModeHuman})
+	assertNoError(t, err)
+
+	t.Run("waits for the second player", func(t *testing.T) {
+		player, err := g.Join()
+		assertNoError(t, err)
+		if player != game.XPlayer {
+			t.Fatalf("got player %d want %d", player, game.XPlayer)
+		}
+
+		assertError(t, g.Play(game.XPlayer, 1), ErrWaitingOpponent)
+	})
+
+	t.Run("validates the moves", func(t *testing.T) {
+		player, err := g.Join()
+		assertNoError(t, err)
+		if player != game.OPlayer {
+			t.Fatalf("got player %d want %d", player, game.OPlayer)
+		}
+
+		_, err = g.Join()
+		assertError(t, err, ErrGameFull)
+
+		assertError(t, g.Play(game.OPlayer, 1), ErrNotYourTurn)
+		assertError(t, g.Play(game.XPlayer, 10), ErrInvalidPosition)
+		assertNoError(t, g.Play(game.XPlayer, 1))
+		assertError(t, g.Play(game.OPlayer, 1), ErrInvalidPosition)
+	})
+
+	t.Run("finishes the game", func(t *testing.T) {
+		events, cancel := g.Subscribe()
+		defer cancel()
+
+		for _, move := range []model.Move{{Player: 2, Position: 4}, {Player: 1, Position: 2}, {Player: 2, Position: 5}, {Player: 1, Position: 3}} {
+			assertNoError(t, g.Play(move.Player, move.Position))
+		}
+		assertError(t, g.Play(game.OPlayer, 6), ErrGameOver)
+
+		record := g.Record()
+		if record.GameStatus != model.GameStatusPlayer1Wins || record.NextPlayer != -1 {
+			t.Errorf("got status %s next player %d", record.GameStatus, record.NextPlayer)
+		}
+		wantBoard := []int{1, 1, 1, 2, 2, 0, 0, 0, 0}
+		if !reflect.DeepEqual(record.Board, wantBoard) {
+			t.Errorf("got %v want %v", record.Board, wantBoard)
+		}
+		if len(record.Moves) != 5 {
+			t.Errorf("got %d moves want 5", len(record.Moves))
+		}
+
+		var last Event
+		for i := 0; i < 4; i++ {
+			last = <-events
+		}
+		if last.Type != model.MessageTypeMove || last.Position != 3 || last.Game.GameStatus != model.GameStatusPlayer1Wins {
+			t.Errorf("got last event %+v", last)
+		}
+	})
+}
+
+func TestGameUndo(t *testing.T) {
+	store := NewStore(StoreOptions{})
+
+	t.Run("takes back the human move and the AI reply", func(t *testing.T) {
+		g, err := store.Create(context.Background(), Options{BoardSize: 3, Mode: model.GameModeAI, Difficulty: game.DifficultyHard, AIPlayer: game.OPlayer, AllowTakebacks: true, MaxTakebacks: 1})
+		assertNoError(t, err)
+
+		assertError(t, g.Undo(), ErrNothingToUndo)
+		assertNoError(t, g.PlayAgainstAI(context.Background(), 1))
+		assertNoError(t, g.PlayAgainstAI(context.Background(), 9))
+		if got := len(g.Record().Moves); got != 4 {
+			t.Fatalf("got %d moves want 4", got)
+		}
+
+		assertNoError(t, g.Undo())
+		record := g.Record()
+		if len(record.Moves) != 2 || record.Takebacks != 1 || record.NextPlayer != game.XPlayer {
+			t.Errorf("got %d moves, %d takebacks and next player %d", len(record.Moves), record.Takebacks, record.NextPlayer)
+		}
+		if record.Board[8] != 0 {
+			t.Errorf("got board %v want position 9 to be empty", record.Board)
+		}
+
+		assertError(t, g.Undo(), ErrTakebackLimit)
+	})
+
+	t.Run("keeps the opening move of an AI playing X", func(t *testing.T) {
+		g, err := store.Create(context.Background(), Options{BoardSize: 3, Mode: model.GameModeAI, Difficulty: game.DifficultyEasy, AIPlayer: game.XPlayer, AllowTakebacks: true})
+		assertNoError(t, err)
+		if got := len(g.Record().Moves); got != 1 {
+			t.Fatalf("got %d moves want the AI opening", got)
+		}
+
+		assertError(t, g.Undo(), ErrNothingToUndo)
+	})
+
+	t.Run("respects disabled takebacks", func(t *testing.T) {
+		g, err := store.Create(context.Background(), Options{BoardSize: 3, Mode: model.GameModeAI, Difficulty: game.DifficultyHard, AIPlayer: game.OPlayer})
+		assertNoError(t, err)
+		assertNoError(t, g.PlayAgainstAI(context.Background(), 5))
+
+		assertError(t, g.Undo(), ErrTakebacksDisabled)
+	})
+}
+
+func TestGameSearchContext(t *testing.T) {
+	store := NewStore(StoreOptions{})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	t.Run("a stopped AI opening creates no game", func(t *testing.T) {
+		_, err := store.Create(ctx, Options{BoardSize: 6, Mode: model.GameModeAI, Difficulty: game.DifficultyHard, AIPlayer: game.XPlayer})
+		assertError(t, err, context.Canceled)
+	})
+
+	t.Run("a stopped AI reply plays neither move", func(t *testing.T) {
+		g, err := store.Create(ctx, Options{BoardSize: 6, Mode: model.GameModeAI, Difficulty: game.DifficultyHard, AIPlayer: game.OPlayer})
+		assertNoError(t, err)
+		events, unsubscribe := g.Subscribe()
+		defer unsubscribe()
+
+		assertError(t, g.PlayAgainstAI(ctx, 1), context.Canceled)
+		if record := g.Record(); len(record.Moves) != 0 || record.Board[0] != 0 {
+			t.Errorf("got moves %v and board %v", record.Moves, record.Board)
+		}
+		select {
+		case event := <-events:
+			t.Errorf("got event %+v", event)
+		default:
+		}
+	})
+}
+
+func TestStoreGet(t *testing.T) {
+	store := NewStore(StoreOptions{})
+	g, err := store.Create(context.Background(), Options{BoardSize: 4, Mode: model.GameModeHuman})
+	assertNoError(t, err)
+
+	got, ok := store.Get(g.ID())
+	if !ok || got != g {
+		t.Errorf("got %v, %v want the created game", got, ok)
+	}
+
+	if _, ok := store.Get("missing"); ok {
+		t.Errorf("got a game for an unknown id")
+	}
+}
+
+func TestStoreExpiry(t *testing.T) {
+	newStore := func(maxGames int) (*Store, *time.Time) {
+		store := NewStore(StoreOptions{IdleTimeout: time.Hour, FinishedTimeout: time.Minute, MaxGames: maxGames})
+		now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+		store.now = func() time.Time { return now }
+		return store, &now
+	}
+	create := func(t *testing.T, store *Store) *Game {
+		t.Helper()
+		g, err := store.Create(context.Background(), Options{BoardSize: 3, Mode: model.GameModeAI, Difficulty: game.DifficultyEasy, AIPlayer: game.OPlayer})
+		assertNoError(t, err)
+		return g
+	}
+	has := func(store *Store, g *Game) bool {
+		_, ok := store.Get(g.ID())
+		return ok
+	}
+
+	t.Run("drops idle and finished games", func(t *testing.T) {
+		store, now := newStore(DefaultMaxGames)
+		idle := create(t, store)
+		finished := create(t, store)
+		for _, position := range []int{1, 2, 3, 4, 5, 6, 7, 8, 9} {
+			if finished.Record().GameStatus != model.GameStatusOngoing {
+				break
+			}
+			finished.PlayAgainstAI(context.Background(), position)
+		}
+		watched := create(t, store)
+		_, unsubscribe := watched.Subscribe()
+		defer unsubscribe()
+
+		*now = now.Add(30 * time.Minute)
+		active := create(t, store)
+		if has(store, finished) || !has(store, idle) {
+			t.Errorf("got finished %v and idle %v after 30 minutes, want only the idle game", has(store, finished), has(store, idle))
+		}
+
+		*now = now.Add(45 * time.Minute)
+		create(t, store)
+		if has(store, idle) || !has(store, watched) || !has(store, active) {
+			t.Errorf("got idle %v, watched %v and active %v after 75 minutes, want the watched and the active games", has(store, idle), has(store, watched), has(store, active))
+		}
+	})
+
+	t.Run("makes room for a new game when full", func(t *testing.T) {
+		store, now := newStore(2)
+		oldest := create(t, store)
+		*now = now.Add(time.Second)
+		watched := create(t, store)
+		_, unsubscribe := watched.Subscribe()
+		defer unsubscribe()
+		*now = now.Add(time.Second)
+
+		newest := create(t, store)
+		if has(store, oldest) || !has(store, watched) || !has(store, newest) {
+			t.Errorf("got oldest %v, watched %v and newest %v", has(store, oldest), has(store, watched), has(store, newest))
+		}
+
+		_, unsubscribe = newest.Subscribe()
+		defer unsubscribe()
+		_, err := store.Create(context.Background(), Options{BoardSize: 3, Mode: model.GameModeHuman})
+		assertError(t, err, ErrTooManyGames)
+	})
+}
+
+func assertNoError(t testing.TB, err error) {
+	t.Helper()
+	if err != nil {
+		t.Errorf("got error %v when no error was expected", err)
+	}
+}
+
+func assertError(t testing.TB, got error, want error) {
+	t.Helper()
+	if !errors.Is(got, want) {
+		t.Errorf("got error %v want %v", got, want)
+	}
+}
@@ -0,0 +1,418 @@
+// Package websocket is a minimal RFC 6455 implementation built on the
+// standard library. It supports the subset the API needs: the opening
+// handshake, text/binary messages, fragmentation, ping/pong and close frames,
+// plus a small client used by tests and Go clients.
+package websocket
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	ContinuationMessage = 0
+	TextMessage         = 1
+	BinaryMessage       = 2
+	CloseMessage        = 8
+	PingMessage         = 9
+	PongMessage         = 10
+)
+
+const (
+	CloseNormalClosure    = 1000
+	CloseGoingAway        = 1001
+	CloseProtocolError    = 1002
+	CloseMessageTooBig    = 1009
+	CloseNoStatusReceived = 1005
+)
+
+// MaxMessageSize is the largest message a Conn accepts from a peer.
+const MaxMessageSize = 64 << 10
+
+const (
+	handshakeGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+	writeWait     = 10 * time.Second
+)
+
+var (
+	ErrBadHandshake    = errors.New("websocket: bad handshake")
+	ErrMessageTooBig   = errors.New("websocket: message too big")
+	ErrProtocol        = errors.New("websocket: protocol error")
+	ErrConnectionClose = errors.New("websocket: connection closed")
+	ErrBadOrigin       = errors.New("websocket: origin not allowed")
+)
+
+// CloseError is returned by ReadMessage when the peer sends a close frame.
+type CloseError struct {
+	Code int
+	Text string
+}
+
+func (e *CloseError) Error() string {
+	return "websocket: close " + strconv.Itoa(e.Code) + " " + e.Text
+}
+
+type Conn struct {
+	conn    net.Conn
+	br      *bufio.Reader
+	client  bool
+	writeMu sync.Mutex
+	closed  bool
+
+	// readMu guards the read deadlines. readTimeout is set by KeepAlive and
+	// readDeadline by SetReadDeadline, which caps the timeout.
+	readMu       sync.Mutex
+	readTimeout  time.Duration
+	readDeadline time.Time
+}
+
+// Upgrade performs the server side of the opening handshake and hijacks the
+// underlying connection. On failure an HTTP error has already been written.
+// Browsers may only connect from the origin of the server, or from one that
+// the CORS headers already set on w allow.
+func Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "WebSocket upgrade requires GET", http.StatusMethodNotAllowed)
+		return nil, ErrBadHandshake
+	}
+	if !headerContains(r.Header, "Connection", "upgrade") || !headerContains(r.Header, "Upgrade", "websocket") {
+		http.Error(w, "Expected WebSocket upgrade request", http.StatusBadRequest)
+		return nil, ErrBadHandshake
+	}
+	if r.Header.Get("Sec-Websocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "Unsupported WebSocket version", http.StatusUpgradeRequired)
+		return nil, ErrBadHandshake
+	}
+	key := r.Header.Get("Sec-Websocket-Key")
+	if key == "" {
+		http.Error(w, "Missing Sec-WebSocket-Key", http.StatusBadRequest)
+		return nil, ErrBadHandshake
+	}
+	if !allowedOrigin(w, r) {
+		http.Error(w, "WebSocket origin not allowed", http.StatusForbidden)
+		return nil, ErrBadOrigin
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "WebSocket not supported", http.StatusInternalServerError)
+		return nil, ErrBadHandshake
+	}
+	netConn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + AcceptKey(key) + "\r\n\r\n"
+	netConn.SetWriteDeadline(time.Now().Add(writeWait))
+	if _, err := netConn.Write([]byte(response)); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+	netConn.SetDeadline(time.Time{})
+
+	return &Conn{conn: netConn, br: rw.Reader}, nil
+}
+
+// AcceptKey computes the Sec-WebSocket-Accept value for a client key.
+func AcceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + handshakeGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// allowedOrigin reports whether the page that opens the connection may do so.
+// Clients other than browsers send no Origin.
+func allowedOrigin(w http.ResponseWriter, r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if allow := w.Header().Get("Access-Control-Allow-Origin"); allow == "*" || allow == origin {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+func headerContains(header http.Header, name string, value string) bool {
+	for _, v := range header.Values(name) {
+		for _, token := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ReadMessage returns the next text or binary message. Control frames are
+// handled transparently: pings are answered and a close frame is echoed and
+// reported as a *CloseError.
+func (c *Conn) ReadMessage() (int, []byte, error) {
+	var messageType int
+	var message []byte
+
+	for {
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch opcode {
+		case PingMessage:
+			if err := c.writeFrame(PongMessage, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case PongMessage:
+			continue
+		case CloseMessage:
+			closeErr := &CloseError{Code: CloseNoStatusReceived}
+			if len(payload) >= 2 {
+				closeErr.Code = int(binary.BigEndian.Uint16(payload))
+				closeErr.Text = string(payload[2:])
+			}
+			c.WriteClose(CloseNormalClosure, "")
+			return 0, nil, closeErr
+		case TextMessage, BinaryMessage:
+			if messageType != 0 {
+				return 0, nil, ErrProtocol
+			}
+			messageType = opcode
+		case ContinuationMessage:
+			if messageType == 0 {
+				return 0, nil, ErrProtocol
+			}
+		default:
+			return 0, nil, ErrProtocol
+		}
+
+		if len(message)+len(payload) > MaxMessageSize {
+			c.WriteClose(CloseMessageTooBig, "")
+			return 0, nil, ErrMessageTooBig
+		}
+		message = append(message, payload...)
+
+		if fin {
+			return messageType, message, nil
+		}
+	}
+}
+
+func (c *Conn) readFrame() (bool, int, []byte, error) {
+	c.extendReadDeadline()
+
+	var header [2]byte
+	if _, err := io.ReadFull(c.br, header[:]); err != nil {
+		return false, 0, nil, err
+	}
+
+	fin := header[0]&0x80 != 0
+	opcode := int(header[0] & 0x0f)
+	masked := header[1]&0x80 != 0
+	length := uint64(header[1] & 0x7f)
+
+	// Clients must mask every frame they send and servers must not.
+	if masked == c.client || header[0]&0x70 != 0 {
+		c.WriteClose(CloseProtocolError, "")
+		return false, 0, nil, ErrProtocol
+	}
+
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+
+	if opcode >= CloseMessage && (length > 125 || !fin) {
+		c.WriteClose(CloseProtocolError, "")
+		return false, 0, nil, ErrProtocol
+	}
+	if length > MaxMessageSize {
+		c.WriteClose(CloseMessageTooBig, "")
+		return false, 0, nil, ErrMessageTooBig
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+			return false, 0, nil, err
+		}
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		return false, 0, nil, err
+	}
+	if masked {
+		maskBytes(mask, payload)
+	}
+
+	return fin, opcode, payload, nil
+}
+
+// WriteMessage sends a single unfragmented message. It is safe to call from
+// multiple goroutines.
+func (c *Conn) WriteMessage(messageType int, data []byte) error {
+	return c.writeFrame(messageType, data)
+}
+
+func (c *Conn) writeFrame(opcode int, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if c.closed {
+		return ErrConnectionClose
+	}
+
+	var maskBit byte
+	if c.client {
+		maskBit = 0x80
+	}
+
+	frame := make([]byte, 0, len(payload)+14)
+	frame = append(frame, 0x80|byte(opcode))
+	switch {
+	case len(payload) < 126:
+		frame = append(frame, maskBit|byte(len(payload)))
+	case len(payload) <= 0xffff:
+		frame = append(frame, maskBit|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(len(payload)))
+	default:
+		frame = append(frame, maskBit|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(len(payload)))
+	}
+
+	if c.client {
+		var mask [4]byte
+		if _, err := rand.Read(mask[:]); err != nil {
+			return err
+		}
+		frame = append(frame, mask[:]...)
+		start := len(frame)
+		frame = append(frame, payload...)
+		maskBytes(mask, frame[start:])
+	} else {
+		frame = append(frame, payload...)
+	}
+
+	c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+	_, err := c.conn.Write(frame)
+	if opcode == CloseMessage {
+		c.closed = true
+	}
+	return err
+}
+
+// WriteClose sends a close frame with the given status code. Further writes fail.
+func (c *Conn) WriteClose(code int, text string) error {
+	payload := binary.BigEndian.AppendUint16(nil, uint16(code))
+	payload = append(payload, text...)
+	return c.writeFrame(CloseMessage, payload)
+}
+
+func maskBytes(mask [4]byte, b []byte) {
+	for i := range b {
+		b[i] ^= mask[i%4]
+	}
+}
+
+func (c *Conn) ReadJSON(v interface{}) error {
+	_, data, err := c.ReadMessage()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func (c *Conn) WriteJSON(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.WriteMessage(TextMessage, data)
+}
+
+// SetReadDeadline makes reads fail after t, even when KeepAlive would allow
+// the peer longer. A zero t removes the deadline.
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	c.readMu.Lock()
+	defer c.readMu.Unlock()
+	c.readDeadline = t
+	return c.conn.SetReadDeadline(t)
+}
+
+// KeepAlive pings the peer every pingPeriod until stop is called, and makes
+// reads fail once the peer has sent nothing, not even a pong, for pongWait.
+// Pongs are only read while the connection is being read, so a server
+// should keep reading; pongWait should be longer than pingPeriod.
+func (c *Conn) KeepAlive(pingPeriod time.Duration, pongWait time.Duration) (stop func()) {
+	c.readMu.Lock()
+	c.readTimeout = pongWait
+	c.readMu.Unlock()
+	c.extendReadDeadline()
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(pingPeriod)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := c.writeFrame(PingMessage, nil); err != nil {
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
+
+// extendReadDeadline gives the peer readTimeout from now to send its next
+// frame, within the deadline set with SetReadDeadline.
+func (c *Conn) extendReadDeadline() {
+	c.readMu.Lock()
+	defer c.readMu.Unlock()
+	if c.readTimeout == 0 {
+		return
+	}
+	deadline := time.Now().Add(c.readTimeout)
+	if !c.readDeadline.IsZero() && c.readDeadline.Before(deadline) {
+		deadline = c.readDeadline
+	}
+	c.conn.SetReadDeadline(deadline)
+}
+
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,188 @@
+package websocket
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAcceptKey(t *testing.T) {
+	// Example from RFC 6455 section 1.3.
+	got := AcceptKey("dGhlIHNhbXBsZSBub25jZQ==")
+	want := "s3pPLMBiTxaQ9kYGzzhZRbK+xOo="
+
+	if got != want {
+		t.Errorf("got %q want %q", got, want)
+	}
+}
+
+func TestEcho(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		for {
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			conn.WriteMessage(messageType, data)
+		}
+	}))
+	defer s.Close()
+
+	conn, _, err := Dial("ws"+strings.TrimPrefix(s.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("got error %v when no error was expected", err)
+	}
+	defer conn.Close()
+
+	t.Run("short text message", func(t *testing.T) {
+		assertEcho(t, conn, TextMessage, "hello")
+	})
+
+	t.Run("message with extended length", func(t *testing.T) {
+		assertEcho(t, conn, BinaryMessage, strings.Repeat("x", 40000))
+	})
+
+	t.Run("ping is answered transparently", func(t *testing.T) {
+		if err := conn.WriteMessage(PingMessage, []byte("ping")); err != nil {
+			t.Fatalf("got error %v when no error was expected", err)
+		}
+		// The client sees the pong before the echoed message but skips it.
+		assertEcho(t, conn, TextMessage, "after ping")
+	})
+
+	t.Run("close is echoed", func(t *testing.T) {
+		if err := conn.WriteClose(CloseNormalClosure, "bye"); err != nil {
+			t.Fatalf("got error %v when no error was expected", err)
+		}
+		_, _, err := conn.ReadMessage()
+		var closeErr *CloseError
+		if !errors.As(err, &closeErr) || closeErr.Code != CloseNormalClosure {
+			t.Errorf("got %v want close error with code %d", err, CloseNormalClosure)
+		}
+	})
+}
+
+func TestUpgradeRejectsPlainRequests(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/ws", nil)
+	recorder := httptest.NewRecorder()
+
+	_, err := Upgrade(recorder, req)
+
+	if !errors.Is(err, ErrBadHandshake) {
+		t.Errorf("got %v want %v", err, ErrBadHandshake)
+	}
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("got %v want %v", recorder.Code, http.StatusBadRequest)
+	}
+}
+
+func TestUpgradeChecksOrigin(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Origin") == "https://app.example.com" {
+			w.Header().Set("Access-Control-Allow-Origin", "https://app.example.com")
+		}
+		if conn, err := Upgrade(w, r); err == nil {
+			conn.Close()
+		}
+	}))
+	defer s.Close()
+
+	for _, tc := range []struct {
+		origin string
+		wantOK bool
+	}{
+		{"", true},
+		{s.URL, true},
+		{"https://app.example.com", true},
+		{"https://evil.example.com", false},
+	} {
+		t.Run(tc.origin, func(t *testing.T) {
+			header := http.Header{}
+			if tc.origin != "" {
+				header.Set("Origin", tc.origin)
+			}
+			conn, resp, err := Dial("ws"+strings.TrimPrefix(s.URL, "http"), header)
+			if tc.wantOK {
+				if err != nil {
+					t.Fatalf("got error %v when no error was expected", err)
+				}
+				conn.Close()
+				return
+			}
+			if !errors.Is(err, ErrBadHandshake) || resp == nil || resp.StatusCode != http.StatusForbidden {
+				t.Errorf("got error %v and response %v want a 403", err, resp)
+			}
+		})
+	}
+}
+
+func TestKeepAlive(t *testing.T) {
+	serverErr := make(chan error, 1)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		stop := conn.KeepAlive(20*time.Millisecond, 100*time.Millisecond)
+		defer stop()
+
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				serverErr <- err
+				return
+			}
+		}
+	}))
+	defer s.Close()
+
+	conn, _, err := Dial("ws"+strings.TrimPrefix(s.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("got error %v when no error was expected", err)
+	}
+	defer conn.Close()
+
+	// Reading answers the pings, which keeps the connection open
+	conn.SetReadDeadline(time.Now().Add(300 * time.Millisecond))
+	conn.ReadMessage()
+	select {
+	case err := <-serverErr:
+		t.Fatalf("got error %v from a peer that answers pings", err)
+	default:
+	}
+
+	// A silent peer is dropped
+	select {
+	case err := <-serverErr:
+		var netErr interface{ Timeout() bool }
+		if !errors.As(err, &netErr) || !netErr.Timeout() {
+			t.Errorf("got error %v want a timeout", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("the silent peer was not dropped")
+	}
+}
+
+func assertEcho(t testing.TB, conn *Conn, messageType int, message string) {
+	t.Helper()
+	if err := conn.WriteMessage(messageType, []byte(message)); err != nil {
+		t.Fatalf("got error %v when no error was expected", err)
+	}
+
+	gotType, got, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("got error %v when no error was expected", err)
+	}
+	if gotType != messageType || string(got) != message {
+		t.Errorf("got type %d with %d bytes want type %d with %d bytes", gotType, len(got), messageType, len(message))
+	}
+}
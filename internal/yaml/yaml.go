@@ -0,0 +1,342 @@
+// Package yaml reads the small subset of YAML used by the OpenAPI document and
+// the config file of the server.
+package yaml
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// The reader covers what an OpenAPI document needs: block mappings and
+// sequences, flow sequences of scalars, literal block scalars (|) and plain,
+// single- and double-quoted scalars. Values decode like encoding/json does
+// into an any: map[string]any, []any, string, float64, bool and nil.
+
+// SyntaxError reports malformed YAML together with the offending line.
+type SyntaxError struct {
+	Line int
+	Msg  string
+}
+
+func (e *SyntaxError) Error() string {
+	return "line " + strconv.Itoa(e.Line) + ": " + e.Msg
+}
+
+type sourceLine struct {
+	number int
+	indent int
+	text   string
+}
+
+type parser struct {
+	raw   []string
+	lines []sourceLine
+	pos   int
+}
+
+// Unmarshal decodes a YAML document.
+func Unmarshal(data []byte) (any, error) {
+	p := &parser{raw: strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")}
+	for i, raw := range p.raw {
+		text := strings.TrimLeft(raw, " ")
+		if strings.HasPrefix(text, "\t") {
+			return nil, &SyntaxError{i + 1, "tabs are not allowed in indentation"}
+		}
+		p.lines = append(p.lines, sourceLine{number: i + 1, indent: len(raw) - len(text), text: strings.TrimRight(text, " ")})
+	}
+
+	p.skipBlank()
+	if p.pos == len(p.lines) {
+		return nil, nil
+	}
+	value, err := p.parseBlock(p.lines[p.pos].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.skipBlank(); p.pos < len(p.lines) {
+		return nil, &SyntaxError{p.lines[p.pos].number, "unexpected indentation"}
+	}
+	return value, nil
+}
+
+// skipBlank moves past empty and comment lines.
+func (p *parser) skipBlank() {
+	for p.pos < len(p.lines) && (p.lines[p.pos].text == "" || strings.HasPrefix(p.lines[p.pos].text, "#")) {
+		p.pos++
+	}
+}
+
+// parseBlock parses the mapping or sequence starting at the next line, which
+// is indented by indent.
+func (p *parser) parseBlock(indent int) (any, error) {
+	if isSequenceItem(p.lines[p.pos].text) {
+		return p.parseSequence(indent)
+	}
+	return p.parseMapping(indent)
+}
+
+func (p *parser) parseMapping(indent int) (map[string]any, error) {
+	mapping := map[string]any{}
+	for p.skipBlank(); p.pos < len(p.lines); p.skipBlank() {
+		line := p.lines[p.pos]
+		if line.indent < indent || (line.indent == indent && isSequenceItem(line.text)) {
+			break
+		}
+		if line.indent > indent {
+			return nil, &SyntaxError{line.number, "unexpected indentation"}
+		}
+
+		key, value, err := splitKey(line)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := mapping[key]; ok {
+			return nil, &SyntaxError{line.number, "duplicate key " + strconv.Quote(key)}
+		}
+		p.pos++
+
+		mapping[key], err = p.parseValue(line, indent, value)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return mapping, nil
+}
+
+func (p *parser) parseSequence(indent int) ([]any, error) {
+	sequence := []any{}
+	for p.skipBlank(); p.pos < len(p.lines); p.skipBlank() {
+		line := p.lines[p.pos]
+		if line.indent < indent || (line.indent == indent && !isSequenceItem(line.text)) {
+			break
+		}
+		if line.indent > indent {
+			return nil, &SyntaxError{line.number, "unexpected indentation"}
+		}
+
+		item := strings.TrimLeft(strings.TrimPrefix(line.text, "-"), " ")
+		if _, _, err := splitKey(sourceLine{line.number, 0, item}); err == nil && !strings.HasPrefix(item, "[") {
+			// "- key: value" starts a mapping indented past the dash
+			p.lines[p.pos].indent += len(line.text) - len(item)
+			p.lines[p.pos].text = item
+			value, err := p.parseMapping(p.lines[p.pos].indent)
+			if err != nil {
+				return nil, err
+			}
+			sequence = append(sequence, value)
+			continue
+		}
+		p.pos++
+
+		value, err := p.parseValue(line, indent, item)
+		if err != nil {
+			return nil, err
+		}
+		sequence = append(sequence, value)
+	}
+	return sequence, nil
+}
+
+// parseValue parses the value after a key or a dash on line: a scalar on the
+// same line, a literal block, or a nested block on the following lines.
+func (p *parser) parseValue(line sourceLine, indent int, value string) (any, error) {
+	switch {
+	case value == "|" || value == "|-":
+		return p.parseLiteral(indent, value == "|-"), nil
+	case value != "":
+		return parseScalar(line.number, value)
+	}
+
+	p.skipBlank()
+	if p.pos == len(p.lines) {
+		return nil, nil
+	}
+	next := p.lines[p.pos]
+	if next.indent > indent || (next.indent == indent && isSequenceItem(next.text) && !isSequenceItem(line.text)) {
+		return p.parseBlock(next.indent)
+	}
+	return nil, nil
+}
+
+// parseLiteral reads the lines of a literal block scalar indented past
+// indent. The trailing line break is kept unless strip is set.
+func (p *parser) parseLiteral(indent int, strip bool) string {
+	var lines []string
+	blockIndent := -1
+	for ; p.pos < len(p.lines); p.pos++ {
+		line := p.lines[p.pos]
+		if line.text == "" {
+			lines = append(lines, "")
+			continue
+		}
+		if line.indent <= indent {
+			break
+		}
+		if blockIndent == -1 {
+			blockIndent = line.indent
+		}
+		lines = append(lines, strings.TrimRight(p.raw[line.number-1][minInt(blockIndent, line.indent):], " "))
+	}
+
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	text := strings.Join(lines, "\n")
+	if !strip && text != "" {
+		text += "\n"
+	}
+	return text
+}
+
+func isSequenceItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// splitKey splits "key: value" into the key and the rest of the line.
+func splitKey(line sourceLine) (string, string, error) {
+	text := line.text
+	if strings.HasPrefix(text, `"`) || strings.HasPrefix(text, "'") {
+		end := quoteEnd(text)
+		if end == -1 {
+			return "", "", &SyntaxError{line.number, "unterminated quoted key"}
+		}
+		key, err := parseScalar(line.number, text[:end+1])
+		if err != nil {
+			return "", "", err
+		}
+		rest := text[end+1:]
+		if rest != ":" && !strings.HasPrefix(rest, ": ") {
+			return "", "", &SyntaxError{line.number, "expected ':' after the key"}
+		}
+		return key.(string), stripComment(strings.TrimSpace(rest[1:])), nil
+	}
+
+	for i := 0; i < len(text); i++ {
+		if text[i] == ':' && (i == len(text)-1 || text[i+1] == ' ') {
+			return text[:i], stripComment(strings.TrimSpace(text[i+1:])), nil
+		}
+	}
+	return "", "", &SyntaxError{line.number, "expected 'key: value'"}
+}
+
+// quoteEnd returns the index of the quote closing the scalar that starts
+// text, or -1.
+func quoteEnd(text string) int {
+	quote := text[0]
+	for i := 1; i < len(text); i++ {
+		switch {
+		case quote == '"' && text[i] == '\\':
+			i++
+		case text[i] == quote && quote == '\'' && i+1 < len(text) && text[i+1] == '\'':
+			i++
+		case text[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
+
+// stripComment removes a trailing " # comment" that is not inside quotes.
+func stripComment(value string) string {
+	if strings.HasPrefix(value, "#") {
+		return ""
+	}
+	inQuote := byte(0)
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; {
+		case inQuote == 0 && (c == '"' || c == '\''):
+			inQuote = c
+		case inQuote == '"' && c == '\\':
+			i++
+		case inQuote != 0 && c == inQuote:
+			inQuote = 0
+		case inQuote == 0 && c == '#' && value[i-1] == ' ':
+			return strings.TrimRight(value[:i], " ")
+		}
+	}
+	return value
+}
+
+var numberPattern = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?([eE][-+]?[0-9]+)?$`)
+
+func parseScalar(number int, value string) (any, error) {
+	switch {
+	case strings.HasPrefix(value, "["):
+		return parseFlowSequence(number, value)
+	case value == "{}":
+		return map[string]any{}, nil
+	case strings.HasPrefix(value, `"`):
+		var s string
+		if quoteEnd(value) != len(value)-1 || json.Unmarshal([]byte(value), &s) != nil {
+			return nil, &SyntaxError{number, "invalid double-quoted scalar " + value}
+		}
+		return s, nil
+	case strings.HasPrefix(value, "'"):
+		if quoteEnd(value) != len(value)-1 {
+			return nil, &SyntaxError{number, "invalid single-quoted scalar " + value}
+		}
+		return strings.ReplaceAll(value[1:len(value)-1], "''", "'"), nil
+	case strings.HasPrefix(value, "{") || strings.HasPrefix(value, "&") || strings.HasPrefix(value, "*") || strings.HasPrefix(value, ">"):
+		return nil, &SyntaxError{number, "unsupported YAML: " + value}
+	}
+
+	switch value {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null", "~":
+		return nil, nil
+	}
+	if numberPattern.MatchString(value) {
+		f, err := strconv.ParseFloat(value, 64)
+		if err == nil {
+			return f, nil
+		}
+	}
+	return value, nil
+}
+
+func parseFlowSequence(number int, value string) ([]any, error) {
+	if !strings.HasSuffix(value, "]") {
+		return nil, &SyntaxError{number, "unterminated flow sequence " + value}
+	}
+	inner := strings.TrimSpace(value[1 : len(value)-1])
+	sequence := []any{}
+	for inner != "" {
+		end := len(inner)
+		if inner[0] == '"' || inner[0] == '\'' {
+			if end = quoteEnd(inner) + 1; end == 0 {
+				return nil, &SyntaxError{number, "unterminated quoted scalar in " + value}
+			}
+		} else if comma := strings.IndexByte(inner, ','); comma != -1 {
+			end = comma
+		}
+
+		item, err := parseScalar(number, strings.TrimSpace(inner[:end]))
+		if err != nil {
+			return nil, err
+		}
+		if _, nested := item.([]any); nested {
+			return nil, &SyntaxError{number, "nested flow sequences are not supported"}
+		}
+		sequence = append(sequence, item)
+
+		inner = strings.TrimSpace(inner[end:])
+		if inner != "" && !strings.HasPrefix(inner, ",") {
+			return nil, &SyntaxError{number, "expected ',' in " + value}
+		}
+		inner = strings.TrimSpace(strings.TrimPrefix(inner, ","))
+	}
+	return sequence, nil
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
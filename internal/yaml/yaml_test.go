@@ -0,0 +1,77 @@
+package yaml
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUnmarshal(t *testing.T) {
+	data := `# a comment
+openapi: 3.0.0
+servers:
+  - url: https://example.com/v1
+    description: The server # trailing comment
+paths:
+  /games/{id}:
+    get:
+      tags: [games, "a, b", 'it''s']
+      summary: |
+        First line.
+
+        Third line.
+      responses:
+        '200':
+          description: "Quoted \"text\"\n"
+list:
+- one
+- two: 2
+  three: true
+empty:
+nothing: null
+`
+	got, err := Unmarshal([]byte(data))
+	assertNoError(t, err)
+
+	want := map[string]any{
+		"openapi": "3.0.0",
+		"servers": []any{map[string]any{"url": "https://example.com/v1", "description": "The server"}},
+		"paths": map[string]any{
+			"/games/{id}": map[string]any{
+				"get": map[string]any{
+					"tags":    []any{"games", "a, b", "it's"},
+					"summary": "First line.\n\nThird line.\n",
+					"responses": map[string]any{
+						"200": map[string]any{"description": "Quoted \"text\"\n"},
+					},
+				},
+			},
+		},
+		"list":    []any{"one", map[string]any{"two": 2.0, "three": true}},
+		"empty":   nil,
+		"nothing": nil,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v want %#v", got, want)
+	}
+
+	for _, invalid := range []string{
+		"a: 1\na: 2\n",
+		"a: 1\n  b: 2\n",
+		"a: [1, 2\n",
+		"a: \"unterminated\n",
+		"a: {b: 1}\n",
+		"\ta: 1\n",
+		"just text\n",
+	} {
+		if _, err := Unmarshal([]byte(invalid)); err == nil {
+			t.Errorf("got no error for %q", invalid)
+		}
+	}
+}
+
+func assertNoError(t testing.TB, err error) {
+	t.Helper()
+	if err != nil {
+		t.Errorf("got error %v when no error was expected", err)
+	}
+}
@@ -0,0 +1,12 @@
+// Package tictactoeapi holds the files of the repository that the server
+// embeds.
+package tictactoeapi
+
+import "embed"
+
+// WellKnown holds the ChatGPT plugin manifest, the OpenAPI document and the
+// logo in .well-known. The manifest and the document are text/template
+// templates of the public URL of the server, see cmd/server.
+//
+//go:embed .well-known
+var WellKnown embed.FS